@@ -0,0 +1,121 @@
+// Package bpmn übersetzt Prozessdefinitionen in BPMN 2.0 (mit Diagram
+// Interchange), damit sie in Standard-BPMN-Werkzeugen angezeigt werden können.
+//
+// Die Abbildung der Aktivitäten:
+//
+//	EVENT START              startEvent
+//	EVENT END                endEvent
+//	übrige EVENTs            intermediateThrowEvent
+//	IMPLEMENTATION TASK      serviceTask (implementation = implementation-ref-id)
+//	IMPLEMENTATION SUB_FLOW  callActivity (calledElement = implementation-ref-id)
+//	übrige Aktivitäten       task
+//
+// Transitionen werden zu sequenceFlows, Bedingungen zu conditionExpressions,
+// die node-graphics-info zu den Bounds der BPMNShapes.
+package bpmn
+
+import "encoding/xml"
+
+// Die Namespaces von BPMN 2.0
+const (
+	NS_MODEL = "http://www.omg.org/spec/BPMN/20100524/MODEL"
+	NS_DI    = "http://www.omg.org/spec/BPMN/20100524/DI"
+	NS_DC    = "http://www.omg.org/spec/DD/20100524/DC"
+	NS_DD_DI = "http://www.omg.org/spec/DD/20100524/DI"
+	NS_XSI   = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// Die Werte von implementation-type und event-type, die besonders abgebildet
+// werden
+const (
+	IMPLEMENTATION_TASK     = "TASK"
+	IMPLEMENTATION_SUB_FLOW = "SUB_FLOW"
+	EVENT_START             = "START"
+	EVENT_END               = "END"
+)
+
+// Die Typen der Elemente im BPMN-Modell. Die Namen tragen das Präfix des
+// Namespaces, damit die Ausgabe den üblichen BPMN-Dateien entspricht.
+
+type definitions struct {
+	XMLName         xml.Name `xml:"bpmn:definitions"`
+	Bpmn            string   `xml:"xmlns:bpmn,attr"`
+	BpmnDi          string   `xml:"xmlns:bpmndi,attr"`
+	Dc              string   `xml:"xmlns:dc,attr"`
+	Di              string   `xml:"xmlns:di,attr"`
+	Xsi             string   `xml:"xmlns:xsi,attr"`
+	Id              string   `xml:"id,attr"`
+	TargetNamespace string   `xml:"targetNamespace,attr"`
+	Exporter        string   `xml:"exporter,attr"`
+	Process         process  `xml:"bpmn:process"`
+	Diagram         diagram  `xml:"bpmndi:BPMNDiagram"`
+}
+
+type process struct {
+	Id            string         `xml:"id,attr"`
+	Name          string         `xml:"name,attr,omitempty"`
+	IsExecutable  bool           `xml:"isExecutable,attr"`
+	Documentation string         `xml:"bpmn:documentation,omitempty"`
+	Elements      []flowNode     `xml:",any"`
+	Flows         []sequenceFlow `xml:"bpmn:sequenceFlow"`
+}
+
+// flowNode ist ein Event oder eine Aktivität. Der Elementname (z.B.
+// bpmn:serviceTask) steht in XMLName.
+type flowNode struct {
+	XMLName        xml.Name
+	Id             string   `xml:"id,attr"`
+	Name           string   `xml:"name,attr,omitempty"`
+	Implementation string   `xml:"implementation,attr,omitempty"`
+	CalledElement  string   `xml:"calledElement,attr,omitempty"`
+	Incoming       []string `xml:"bpmn:incoming"`
+	Outgoing       []string `xml:"bpmn:outgoing"`
+}
+
+type sequenceFlow struct {
+	Id        string      `xml:"id,attr"`
+	SourceRef string      `xml:"sourceRef,attr"`
+	TargetRef string      `xml:"targetRef,attr"`
+	Condition *expression `xml:"bpmn:conditionExpression"`
+}
+
+type expression struct {
+	Type  string `xml:"xsi:type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type diagram struct {
+	Id    string `xml:"id,attr"`
+	Plane plane  `xml:"bpmndi:BPMNPlane"`
+}
+
+type plane struct {
+	Id          string  `xml:"id,attr"`
+	BpmnElement string  `xml:"bpmnElement,attr"`
+	Shapes      []shape `xml:"bpmndi:BPMNShape"`
+	Edges       []edge  `xml:"bpmndi:BPMNEdge"`
+}
+
+type shape struct {
+	Id          string `xml:"id,attr"`
+	BpmnElement string `xml:"bpmnElement,attr"`
+	Bounds      bounds `xml:"dc:Bounds"`
+}
+
+type bounds struct {
+	X      int `xml:"x,attr"`
+	Y      int `xml:"y,attr"`
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+}
+
+type edge struct {
+	Id          string     `xml:"id,attr"`
+	BpmnElement string     `xml:"bpmnElement,attr"`
+	Waypoints   []waypoint `xml:"di:waypoint"`
+}
+
+type waypoint struct {
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+}
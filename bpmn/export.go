@@ -0,0 +1,151 @@
+package bpmn
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/frericksm/pride/layout"
+	"github.com/frericksm/pride/processfile"
+)
+
+// elementId macht aus der Id 'id' eines Elements der Prozessdatei eine
+// gültige XML-Id (UUIDs dürfen mit einer Ziffer beginnen, XML-Ids nicht)
+func elementId(id string) string {
+	return "_" + id
+}
+
+// elementName liefert den Namen des BPMN-Elements der Aktivität 'a'
+func elementName(a *processfile.Activity) string {
+	switch {
+	case a.Body.ActivityType == "EVENT" && a.Body.EventType == EVENT_START:
+		return "bpmn:startEvent"
+	case a.Body.ActivityType == "EVENT" && a.Body.EventType == EVENT_END:
+		return "bpmn:endEvent"
+	case a.Body.ActivityType == "EVENT":
+		return "bpmn:intermediateThrowEvent"
+	case a.Body.ImplementationType == IMPLEMENTATION_TASK:
+		return "bpmn:serviceTask"
+	case a.Body.ImplementationType == IMPLEMENTATION_SUB_FLOW:
+		return "bpmn:callActivity"
+	}
+	return "bpmn:task"
+}
+
+// waypoints liefert die Endpunkte einer Kante von 'from' nach 'to': von der
+// Mitte der unteren Kante zur Mitte der oberen Kante, wenn 'to' unterhalb
+// liegt, sonst umgekehrt
+func waypoints(from, to processfile.Rect) []waypoint {
+	fx, tx := from.X+from.Width/2, to.X+to.Width/2
+	if to.Y >= from.Y+from.Height {
+		return []waypoint{{fx, from.Y + from.Height}, {tx, to.Y}}
+	}
+	if to.Y+to.Height <= from.Y {
+		return []waypoint{{fx, from.Y}, {tx, to.Y + to.Height}}
+	}
+	return []waypoint{{fx, from.Y + from.Height/2}, {tx, to.Y + to.Height/2}}
+}
+
+// Export liefert die Prozessdefinition 'p' als BPMN-2.0-Datei. Aktivitäten
+// ohne node-graphics-info werden mit layout.Layout angeordnet, Transitionen
+// zu unbekannten Aktivitäten ausgelassen.
+func Export(p *processfile.Process) ([]byte, error) {
+	d := definitions{
+		Bpmn:            NS_MODEL,
+		BpmnDi:          NS_DI,
+		Dc:              NS_DC,
+		Di:              NS_DD_DI,
+		Xsi:             NS_XSI,
+		Id:              "Definitions_" + p.Id,
+		TargetNamespace: "http://bpmn.io/schema/bpmn",
+		Exporter:        "pride",
+		Process: process{
+			Id:            p.Id,
+			Name:          p.Name,
+			Documentation: strings.TrimSpace(p.Description.Text()),
+		},
+		Diagram: diagram{
+			Id:    "Diagram_" + p.Id,
+			Plane: plane{Id: "Plane_" + p.Id, BpmnElement: p.Id},
+		},
+	}
+
+	var computed map[string]processfile.NodeGraphicsInfo
+	rects := make(map[string]processfile.Rect)
+	nodes := make(map[string]int)
+	var ids []string
+	for i := range p.Activities {
+		a := &p.Activities[i]
+		if _, present := nodes[a.Id]; present {
+			continue
+		}
+		r := a.Body.NodeGraphicsInfo.Rect()
+		if r.Empty() {
+			if computed == nil {
+				computed = layout.Layout(p, layout.DefaultOptions)
+			}
+			r = computed[a.Id].Rect()
+		}
+		rects[a.Id] = r
+
+		n := flowNode{
+			XMLName: xml.Name{Local: elementName(a)},
+			Id:      elementId(a.Id),
+			Name:    a.Name,
+		}
+		switch a.Body.ImplementationType {
+		case IMPLEMENTATION_TASK:
+			n.Implementation = a.Body.ImplementationRefId
+		case IMPLEMENTATION_SUB_FLOW:
+			n.CalledElement = a.Body.ImplementationRefId
+		}
+		nodes[a.Id] = len(d.Process.Elements)
+		d.Process.Elements = append(d.Process.Elements, n)
+		ids = append(ids, a.Id)
+	}
+
+	flow_ids := make(map[string]int)
+	for i := range p.Activities {
+		a := &p.Activities[i]
+		for _, t := range a.Transitions {
+			to, present := nodes[t.To]
+			if !present {
+				continue
+			}
+			// Transition-Ids sind nur je Aktivität eindeutig
+			id := elementId(t.Id)
+			if flow_ids[id]++; flow_ids[id] > 1 {
+				id = fmt.Sprintf("%s_%d", id, flow_ids[id])
+			}
+			source, target := &d.Process.Elements[nodes[a.Id]], &d.Process.Elements[to]
+			f := sequenceFlow{Id: id, SourceRef: source.Id, TargetRef: target.Id}
+			if condition := strings.TrimSpace(t.Condition.Text()); condition != "" {
+				f.Condition = &expression{Type: "bpmn:tFormalExpression", Value: condition}
+			}
+			d.Process.Flows = append(d.Process.Flows, f)
+			source.Outgoing = append(source.Outgoing, id)
+			target.Incoming = append(target.Incoming, id)
+
+			d.Diagram.Plane.Edges = append(d.Diagram.Plane.Edges, edge{
+				Id:          id + "_di",
+				BpmnElement: id,
+				Waypoints:   waypoints(rects[a.Id], rects[t.To]),
+			})
+		}
+	}
+
+	for i, n := range d.Process.Elements {
+		r := rects[ids[i]]
+		d.Diagram.Plane.Shapes = append(d.Diagram.Plane.Shapes, shape{
+			Id:          n.Id + "_di",
+			BpmnElement: n.Id,
+			Bounds:      bounds{r.X, r.Y, r.Width, r.Height},
+		})
+	}
+
+	content, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
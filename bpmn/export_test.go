@@ -0,0 +1,62 @@
+package bpmn_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/frericksm/pride/bpmn"
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestExport(t *testing.T) {
+	p := processfile.FromBytes(processfile.FileContent("../processfile/testdata/A1.process"))
+	p.Activities[1].Transitions[0].Condition = processfile.NewCondition(`(= kategorie "FEHLER")`)
+
+	content, err := bpmn.Export(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(content, new(struct{})); err != nil {
+		t.Fatalf("Expected well-formed XML, but was %s", err)
+	}
+
+	s := string(content)
+	for _, expected := range []string{
+		`<bpmn:process id="de.michael.A1" name="A1" isExecutable="false">`,
+		`<bpmn:startEvent id="_9118f203-b2de-4f4a-80eb-def19c1b2996" name="&lt;Start&gt;">`,
+		`<bpmn:serviceTask id="_a45fcdf6-d7d6-4d8f-8e96-fe7e6cf108e5" name="Protokoll" implementation="de.fi.prosupport.task.ProtokollEintragSchreiben">`,
+		`<bpmn:callActivity id="_fe90d47d-4eb5-40a4-aa1e-126d1e0b239d" name="A1" calledElement="version400.schufa026201504162opdvversion.haupt_schufa_026.Haupt_Schufa_026">`,
+		`<bpmn:endEvent id="_8944fcd4-f497-4c7a-9699-9315fb980d4d" name="&lt;Ende&gt;">`,
+		`<bpmn:conditionExpression xsi:type="bpmn:tFormalExpression">(= kategorie &#34;FEHLER&#34;)</bpmn:conditionExpression>`,
+		`<bpmndi:BPMNShape id="_a45fcdf6-d7d6-4d8f-8e96-fe7e6cf108e5_di" bpmnElement="_a45fcdf6-d7d6-4d8f-8e96-fe7e6cf108e5">`,
+		`<dc:Bounds x="347" y="94" width="125" height="30"></dc:Bounds>`,
+		`<di:waypoint x="408" y="51"></di:waypoint>`,
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("Expected %s in\n%s", expected, s)
+		}
+	}
+
+	// Die beiden Transitionen mit der Id "1" ergeben verschiedene Sequence-Flows
+	if !strings.Contains(s, `<bpmn:sequenceFlow id="_1" `) || !strings.Contains(s, `<bpmn:sequenceFlow id="_1_2" `) {
+		t.Errorf("Expected unique sequence flow ids in\n%s", s)
+	}
+	if n := strings.Count(s, "<bpmndi:BPMNEdge "); n != 4 {
+		t.Errorf("Expected 4 edges, but was %d", n)
+	}
+}
+
+func TestExportWithoutLayout(t *testing.T) {
+	p := processfile.FromBytes(processfile.FileContent("../processfile/testdata/A1.process"))
+	for i := range p.Activities {
+		p.Activities[i].Body.NodeGraphicsInfo = processfile.NodeGraphicsInfo{}
+	}
+	content, err := bpmn.Export(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), `width="0"`) {
+		t.Errorf("Expected computed layout, but was\n%s", content)
+	}
+}
@@ -0,0 +1,310 @@
+package bpmn
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/frericksm/pride/layout"
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/scaffold"
+)
+
+// Mapping legt fest, welche implementation-ref-id die TASK-Aktivitäten aus
+// den Tasks der BPMN-Datei bekommen, z.B. in mapping.yaml:
+//
+//	tasks:
+//	  Protokoll schreiben: de.fi.prosupport.task.ProtokollEintragSchreiben
+//
+// Gesucht wird nach dem Attribut 'implementation' des Tasks, seinem Namen und
+// seiner Id. Ohne Eintrag wird 'implementation' übernommen, sofern es keine
+// der Vorgaben von BPMN (##WebService, ##unspecified) ist.
+type Mapping struct {
+	Tasks map[string]string `json:"tasks" yaml:"tasks"`
+}
+
+// LoadMapping liest ein Mapping im JSON- oder YAML-Format (nach Endung der Datei)
+func LoadMapping(path string) (*Mapping, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Mapping
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(content, &m)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &m)
+	default:
+		err = errors.New(fmt.Sprintf("unsupported mapping format '%s'", filepath.Ext(path)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// task liefert die implementation-ref-id des Tasks 'e'
+func (m *Mapping) task(e *element) string {
+	if m != nil {
+		for _, key := range []string{e.Implementation, e.Name, e.Id} {
+			if ref, present := m.Tasks[key]; present && key != "" {
+				return ref
+			}
+		}
+	}
+	if strings.HasPrefix(e.Implementation, "##") {
+		return ""
+	}
+	return e.Implementation
+}
+
+// Die Typen zum Lesen einer BPMN-Datei. Anders als beim Schreiben sind die
+// Namen mit dem Namespace qualifiziert, damit jedes Präfix gelesen werden kann.
+
+type inputDefinitions struct {
+	Processes []inputProcess `xml:"http://www.omg.org/spec/BPMN/20100524/MODEL process"`
+	Shapes    []inputShape   `xml:"http://www.omg.org/spec/BPMN/20100524/DI BPMNDiagram>BPMNPlane>BPMNShape"`
+}
+
+type inputProcess struct {
+	Id            string    `xml:"id,attr"`
+	Name          string    `xml:"name,attr"`
+	Documentation string    `xml:"http://www.omg.org/spec/BPMN/20100524/MODEL documentation"`
+	Elements      []element `xml:",any"`
+}
+
+// element ist ein Flow-Element des Prozesses: Event, Aktivität, Gateway oder
+// Sequence-Flow
+type element struct {
+	XMLName        xml.Name
+	Id             string `xml:"id,attr"`
+	Name           string `xml:"name,attr"`
+	Implementation string `xml:"implementation,attr"`
+	CalledElement  string `xml:"calledElement,attr"`
+	SourceRef      string `xml:"sourceRef,attr"`
+	TargetRef      string `xml:"targetRef,attr"`
+	Condition      string `xml:"http://www.omg.org/spec/BPMN/20100524/MODEL conditionExpression"`
+}
+
+type inputShape struct {
+	BpmnElement string `xml:"bpmnElement,attr"`
+	Bounds      struct {
+		X      float64 `xml:"x,attr"`
+		Y      float64 `xml:"y,attr"`
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+	} `xml:"http://www.omg.org/spec/DD/20100524/DC Bounds"`
+}
+
+// kind ordnet ein Element der BPMN-Datei ein
+type kind int
+
+const (
+	KIND_IGNORED kind = iota
+	KIND_START
+	KIND_END
+	KIND_TASK
+	KIND_SUB_FLOW
+	// Gateways und Zwischen-Events, die Prozessdatei kennt sie nicht. Die
+	// Sequence-Flows werden durch sie hindurch verbunden.
+	KIND_PASS
+	KIND_FLOW
+	KIND_UNSUPPORTED
+)
+
+func kindOf(e *element) kind {
+	if e.XMLName.Space != NS_MODEL {
+		return KIND_IGNORED
+	}
+	name := e.XMLName.Local
+	switch {
+	case name == "startEvent":
+		return KIND_START
+	case name == "endEvent":
+		return KIND_END
+	case name == "callActivity":
+		return KIND_SUB_FLOW
+	case name == "sequenceFlow":
+		return KIND_FLOW
+	case name == "task" || strings.HasSuffix(name, "Task"):
+		return KIND_TASK
+	case strings.HasSuffix(name, "Gateway") || strings.HasPrefix(name, "intermediate"):
+		return KIND_PASS
+	case name == "subProcess" || name == "adHocSubProcess" || name == "transaction" || name == "boundaryEvent":
+		return KIND_UNSUPPORTED
+	}
+	return KIND_IGNORED
+}
+
+// name vereinfacht den Namen eines Elements: Zeilenumbrüche, wie sie
+// BPMN-Werkzeuge einfügen, werden zu Leerzeichen
+func name(e *element) string {
+	return strings.Join(strings.Fields(e.Name), " ")
+}
+
+// and verknüpft zwei Bedingungen
+func and(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return fmt.Sprintf("(and %s %s)", a, b)
+}
+
+// Import liest eine BPMN-2.0-Datei und liefert den ersten Prozess darin als
+// Prozessdefinition mit neu erzeugten UUIDs. Start- und End-Events werden zu
+// START- und END-Events, Call-Activities zu SUB_FLOW- und alle Tasks zu
+// TASK-Aktivitäten (siehe Mapping). Gateways und Zwischen-Events entfallen:
+// ihre Sequence-Flows werden zu Transitionen zwischen den Aktivitäten davor
+// und danach, deren Bedingungen mit 'and' verknüpft. Die Bounds aus BPMN DI
+// werden zur node-graphics-info, Aktivitäten ohne Bounds werden mit
+// layout.Layout angeordnet. Unterprozesse und angeheftete Events werden nicht
+// unterstützt.
+func Import(content []byte, mapping *Mapping) (*processfile.Process, error) {
+	var d inputDefinitions
+	if err := xml.Unmarshal(content, &d); err != nil {
+		return nil, err
+	}
+	var bp *inputProcess
+	for i := range d.Processes {
+		if len(d.Processes[i].Elements) > 0 {
+			bp = &d.Processes[i]
+			break
+		}
+	}
+	if bp == nil {
+		return nil, errors.New("no process found")
+	}
+
+	p := &processfile.Process{
+		Id:   bp.Id,
+		Name: bp.Name,
+	}
+	if p.Name == "" {
+		p.Name = bp.Id[strings.LastIndex(bp.Id, ".")+1:]
+	}
+	var doc bytes.Buffer
+	xml.EscapeText(&doc, []byte(strings.TrimSpace(bp.Documentation)))
+	p.Description.Value = doc.Bytes()
+
+	bounds := make(map[string]processfile.NodeGraphicsInfo)
+	for _, s := range d.Shapes {
+		bounds[s.BpmnElement] = processfile.NodeGraphicsInfo{
+			CoordinateX: int(math.Round(s.Bounds.X)),
+			CoordinateY: int(math.Round(s.Bounds.Y)),
+			Width:       int(math.Round(s.Bounds.Width)),
+			Height:      int(math.Round(s.Bounds.Height)),
+		}
+	}
+
+	// Die Aktivitäten, je Id des BPMN-Elements
+	activities := make(map[string]int)
+	kinds := make(map[string]kind)
+	outgoing := make(map[string][]*element)
+	for i := range bp.Elements {
+		e := &bp.Elements[i]
+		k := kindOf(e)
+		kinds[e.Id] = k
+		a := processfile.Activity{Id: scaffold.NewUUID(), Name: name(e)}
+		switch k {
+		case KIND_UNSUPPORTED:
+			return nil, errors.New(fmt.Sprintf("%s %s: unsupported element", e.XMLName.Local, e.Id))
+		case KIND_FLOW:
+			outgoing[e.SourceRef] = append(outgoing[e.SourceRef], e)
+			continue
+		case KIND_START, KIND_END:
+			a.Body.ActivityType = "EVENT"
+			a.Body.EventType = EVENT_START
+			if k == KIND_END {
+				a.Body.EventType = EVENT_END
+			}
+		case KIND_TASK:
+			a.Body.ActivityType = "IMPLEMENTATION"
+			a.Body.ImplementationType = IMPLEMENTATION_TASK
+			a.Body.ImplementationRefId = mapping.task(e)
+		case KIND_SUB_FLOW:
+			a.Body.ActivityType = "IMPLEMENTATION"
+			a.Body.ImplementationType = IMPLEMENTATION_SUB_FLOW
+			a.Body.ImplementationRefId = e.CalledElement
+		default:
+			continue
+		}
+		if a.Name == "" && a.Body.ActivityType == "EVENT" {
+			a.Name = map[string]string{EVENT_START: "<Start>", EVENT_END: "<Ende>"}[a.Body.EventType]
+		}
+		if a.Name == "" {
+			a.Name = e.Id
+		}
+		a.Body.NodeGraphicsInfo = bounds[e.Id]
+		activities[e.Id] = len(p.Activities)
+		p.Activities = append(p.Activities, a)
+	}
+
+	// Die Ziele der Sequence-Flows ab 'source', durch Gateways hindurch, mit
+	// den verknüpften Bedingungen
+	type target struct{ id, condition string }
+	var follow func(source string, condition string, visited map[string]bool) []target
+	follow = func(source string, condition string, visited map[string]bool) []target {
+		var targets []target
+		for _, f := range outgoing[source] {
+			c := and(condition, strings.TrimSpace(f.Condition))
+			switch kinds[f.TargetRef] {
+			case KIND_PASS:
+				if !visited[f.TargetRef] {
+					visited[f.TargetRef] = true
+					targets = append(targets, follow(f.TargetRef, c, visited)...)
+				}
+			default:
+				if _, present := activities[f.TargetRef]; present {
+					targets = append(targets, target{f.TargetRef, c})
+				}
+			}
+		}
+		return targets
+	}
+	for i := range bp.Elements {
+		e := &bp.Elements[i]
+		index, present := activities[e.Id]
+		if !present {
+			continue
+		}
+		seen := make(map[target]bool)
+		for _, t := range follow(e.Id, "", map[string]bool{}) {
+			if seen[t] {
+				continue
+			}
+			seen[t] = true
+			p.Activities[index].Transitions = append(p.Activities[index].Transitions, processfile.Transition{
+				Id:        scaffold.NewUUID(),
+				To:        p.Activities[activities[t.id]].Id,
+				Condition: processfile.NewCondition(t.condition),
+			})
+		}
+	}
+
+	var computed map[string]processfile.NodeGraphicsInfo
+	for i := range p.Activities {
+		a := &p.Activities[i]
+		if !a.Body.NodeGraphicsInfo.Rect().Empty() {
+			continue
+		}
+		if computed == nil {
+			computed = layout.Layout(p, layout.DefaultOptions)
+		}
+		a.Body.NodeGraphicsInfo = computed[a.Id]
+	}
+	processfile.Canonicalize(p)
+	return p, nil
+}
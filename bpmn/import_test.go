@@ -0,0 +1,97 @@
+package bpmn_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/frericksm/pride/bpmn"
+	"github.com/frericksm/pride/processfile"
+)
+
+func activity(p *processfile.Process, name string) *processfile.Activity {
+	for i := range p.Activities {
+		if p.Activities[i].Name == name {
+			return &p.Activities[i]
+		}
+	}
+	return nil
+}
+
+func TestImport(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/order.bpmn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := &bpmn.Mapping{Tasks: map[string]string{"Bestellung\nprüfen": "de.michael.task.Pruefen"}}
+	p, err := bpmn.Import(content, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Id != "de.michael.Bestellung" || p.Name != "Bestellung" || p.Description.Text() != "Prüft eine Bestellung" {
+		t.Errorf("Expected process de.michael.Bestellung, but was %s %s %q", p.Id, p.Name, p.Description.Text())
+	}
+	if len(p.Activities) != 5 {
+		t.Fatalf("Expected 5 activities, but was %d", len(p.Activities))
+	}
+	if problems := processfile.Validate(p); len(problems) != 1 {
+		t.Errorf("Expected only the missing implementation of Buchen, but was %v", problems)
+	}
+
+	pruefen := activity(p, "Bestellung prüfen")
+	if pruefen == nil || pruefen.Body.ImplementationType != "TASK" || pruefen.Body.ImplementationRefId != "de.michael.task.Pruefen" {
+		t.Fatalf("Expected mapped task, but was %v", pruefen)
+	}
+	if g := pruefen.Body.NodeGraphicsInfo; g != (processfile.NodeGraphicsInfo{CoordinateX: 240, CoordinateY: 60, Width: 100, Height: 80}) {
+		t.Errorf("Expected bounds from BPMN DI, but was %v", g)
+	}
+	if g := activity(p, "<Start>").Body.NodeGraphicsInfo; g.CoordinateX != 152 {
+		t.Errorf("Expected rounded coordinate, but was %v", g)
+	}
+	if ablehnen := activity(p, "Ablehnen"); ablehnen.Body.ImplementationType != "SUB_FLOW" || ablehnen.Body.ImplementationRefId != "de.michael.Ablehnung" {
+		t.Errorf("Expected SUB_FLOW de.michael.Ablehnung, but was %v", ablehnen.Body)
+	}
+	if buchen := activity(p, "Buchen"); buchen.Body.ImplementationRefId != "" || buchen.Body.NodeGraphicsInfo.Height == 0 {
+		t.Errorf("Expected unmapped task with computed layout, but was %v", buchen.Body)
+	}
+
+	// Das Gateway entfällt, seine Bedingungen stehen an den Transitionen
+	conditions := make(map[string]string)
+	for _, tr := range pruefen.Transitions {
+		for _, a := range p.Activities {
+			if a.Id == tr.To {
+				conditions[a.Name] = tr.Condition.Text()
+			}
+		}
+	}
+	if len(conditions) != 2 || conditions["Buchen"] != `(= status "OK")` || conditions["Ablehnen"] != `(not= status "OK")` {
+		t.Errorf("Expected conditional transitions to Buchen and Ablehnen, but was %v", conditions)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	a := processfile.FromBytes(processfile.FileContent("../processfile/testdata/A1.process"))
+	content, err := bpmn.Export(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bpmn.Import(content, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a.Activities) != len(b.Activities) {
+		t.Fatalf("Expected %d activities, but was %d", len(a.Activities), len(b.Activities))
+	}
+	for i := range a.Activities {
+		x, y := a.Activities[i], b.Activities[i]
+		if x.Id == y.Id {
+			t.Errorf("Expected new id for %s", x.Name)
+		}
+		if x.Name != y.Name || x.Body.ActivityType != y.Body.ActivityType || x.Body.EventType != y.Body.EventType ||
+			x.Body.ImplementationType != y.Body.ImplementationType || x.Body.ImplementationRefId != y.Body.ImplementationRefId ||
+			x.Body.NodeGraphicsInfo != y.Body.NodeGraphicsInfo || len(x.Transitions) != len(y.Transitions) {
+			t.Errorf("Expected %v, but was %v", x, y)
+		}
+	}
+}
@@ -7,13 +7,13 @@ import (
 	"fmt"
 	"strings"
 	"errors"
-	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	//graphql "github.com/neelance/graphql-go"
 
 	pcontext "github.com/frericksm/pride/context"	
+	"github.com/frericksm/pride/scaffold"
+	"github.com/frericksm/pride/storage"
 	"github.com/frericksm/pride/utils"	
 )
 
@@ -31,6 +31,26 @@ var Schema = `
                 allBundles(): [Bundle]!
                 # Queries a single bundle
                 bundle(bundle_symbolic_name: String!): Bundle
+                # Compares two process files semantically. Layout changes are reported only if 'layout' is true
+                processDiff(bundle_symbolic_name: String!, path: String!, other_bundle_symbolic_name: String, other_path: String!, layout: Boolean): [ProcessChange]!
+                # Queries the git commits that changed a file or directory of a bundle, newest first
+                history(bundle_symbolic_name: String!, path: String!, limit: Int): [Commit]!
+                # Queries the content of a file at a git revision (commit hash, branch or tag)
+                fileAtRevision(bundle_symbolic_name: String!, path: String!, revision: String!): String!
+                # Searches file names, process ids and names, activity names, implementation refs, formal parameters and data mappings. Every word of 'query' has to match (case-insensitive, also inside longer words). 'kinds' restricts the matches to FILE_NAME, PROCESS_ID, PROCESS_NAME, ACTIVITY_NAME, IMPLEMENTATION_REF, FORMAL_PARAMETER or DATA_MAPPING
+                search(query: String!, bundles: [String!], kinds: [String!]): [SearchResult]!
+                # Queries the activities calling the task 'taskId' (an implementation-ref-id of type TASK), or, without taskId, all tasks
+                taskUsages(taskId: String): [TaskUsage]!
+                # Queries the tasks of the task catalogs (META-INF/tasks.json or tasks.yaml of the bundles) whose id or class name starts with 'prefix'
+                taskDefinitions(prefix: String): [TaskDefinition]!
+                # Checks the data mappings of the TASK activities of a process file against the task catalogs
+                taskProblems(bundle_symbolic_name: String!, path: String!): [Problem]!
+                # Checks the data mappings of the SUB_FLOW activities of a process file against the formal parameters of the called processes
+                subFlowProblems(bundle_symbolic_name: String!, path: String!): [Problem]!
+                # Queries the files, optionally of a single bundle, that could not be read or parsed by the indexer
+                indexErrors(bundle_symbolic_name: String): [IndexError]!
+                # Queries the deleted bundles, directories and files, optionally of a single bundle, newest first
+                trash(bundle_symbolic_name: String): [TrashItem]!
 	}
 
 	# The mutation type, represents all updates we can make to our data
@@ -38,19 +58,22 @@ var Schema = `
                 # Create bundle
 		createBundle(bundle_symbolic_name: String!): Bundle
 
-                # Create file
+                # Create file. Process files (*.process) are created with a START and an END event
 		createFile(bundle_symbolic_name: String!, path: String!, name: String!): File
 
+                # Create a process file with a START and an END event for the process id 'id' (e.g. de.michael.A1), including the directories of its package
+		createProcess(bundle_symbolic_name: String!, id: String!): File
+
                 # Create dir
 		createDir(bundle_symbolic_name: String!, path: String!, name: String!): Directory
 
-                # Delete bundle
+                # Delete bundle (moves it to the trash)
 		deleteBundle(bundle_symbolic_name: String!): Boolean!
 
-                # Delete file
+                # Delete file (moves it to the trash)
 		deleteFile(bundle_symbolic_name: String!, path: String!): Boolean!
 
-                # Delete dir
+                # Delete dir (moves it to the trash)
 		deleteDir(bundle_symbolic_name: String!, path: String!): Boolean!
 
                 # Copy filenode from path 'source' to a filenode at path 'destination' 
@@ -58,6 +81,18 @@ var Schema = `
 
                 # Move filenode from path 'source' to a filenode at path 'destination' 
 		move(bundle_symbolic_name: String!, source: String!, destination: String!): Boolean!
+
+                # Arrange the activities of a process file in layers along the transitions. Only the node-graphics-info of the activities is rewritten
+		layout(bundle_symbolic_name: String!, path: String!): File
+
+                # Restore a deleted bundle, directory or file from the trash to its original path
+		restore(id: String!): Boolean!
+
+                # Delete an item (or, without id, all items) from the trash permanently. Returns the number of purged items
+		purge(id: String): Int!
+
+                # Commit the changes of the given paths of a bundle to git. Without author and email the git identity of the server is the author
+		commit(bundle_symbolic_name: String!, message: String!, paths: [String!]!, author: String, email: String): Commit
 	}
 
 	# Represents a bundle
@@ -109,6 +144,142 @@ var Schema = `
                 children: [FileNode]             
 	}
 
+	# Represents a file found by a search
+	type SearchResult {
+		# The name of the bundle
+                bundle: String!
+		# The file
+                file: File!
+		# The matches inside the file
+                matches: [SearchMatch]!
+	}
+
+	# Represents a match of a search
+	type SearchMatch {
+		# The kind of the match, e.g. ACTIVITY_NAME or DATA_MAPPING
+                kind: String!
+		# The enclosing element, e.g. the activity and formal parameter of a data mapping
+                context: String!
+		# The matched text
+                text: String!
+		# The part of the text around the match
+                snippet: String!
+	}
+
+	# Represents an activity of a process calling a task
+	type TaskUsage {
+		# The implementation-ref-id of the task
+                task: String!
+		# The name of the bundle
+                bundle: String!
+		# The process definition id
+                process: String!
+		# The id of the activity
+                activityId: String!
+		# The name of the activity
+                activity: String!
+		# The formal parameters passed to the task by data mappings
+                parameters: [String!]!
+	}
+
+	# Represents a task of a task catalog
+	type TaskDefinition {
+		# The implementation-ref-id of the task
+                id: String!
+		# The description of the task
+                description: String!
+		# The formal parameters of the task
+                parameters: [TaskParameter]!
+	}
+
+	# Represents a formal parameter of a task
+	type TaskParameter {
+		# The name of the parameter
+                name: String!
+		# IN, OUT or INOUT
+                direction: String!
+		# Flag indicating if the parameter has to be mapped
+                required: Boolean!
+		# string, number, boolean, list, map, any or empty
+                type: String!
+		# The description of the parameter
+                description: String!
+	}
+
+	# Represents a problem in a process definition
+	type Problem {
+		# The id of the activity, empty if the process is affected as a whole
+                activityId: String!
+		# The name of the activity
+                activity: String!
+		# The kind of the element, e.g. data-mapping
+                element: String!
+		# The id of the element (the formal parameter name for data mappings)
+                id: String!
+		# The line inside an expression, 0 if unknown
+                line: Int!
+		# The column inside an expression, 0 if unknown
+                column: Int!
+		# The description of the problem
+                message: String!
+	}
+
+	# Represents a file that could not be read or parsed by the indexer
+	type IndexError {
+		# The name of the bundle
+                bundle: String!
+		# The path of the file inside the bundle
+                path: String!
+		# The read or parse error
+                message: String!
+	}
+
+	# Represents a deleted bundle, directory or file in the trash
+	type TrashItem {
+		# The id used to restore or purge the item
+                id: String!
+		# The name of the bundle
+                bundle: String!
+		# The path inside the bundle, '/' for a deleted bundle
+                path: String!
+                # Flag indicating if the item is a directory
+                isDir: Boolean!
+		# The time of deletion in RFC 3339 format
+                deleted: String!
+	}
+
+	# Represents a git commit
+	type Commit {
+		# The commit hash
+                hash: String!
+		# The name of the author
+                author: String!
+		# The email address of the author
+                email: String!
+		# The author date in RFC 3339 format
+                date: String!
+		# The first line of the commit message
+                message: String!
+	}
+
+	# Represents a semantic difference between two process definitions
+	type ProcessChange {
+		# ADDED, REMOVED or CHANGED
+                kind: String!
+		# The kind of the element, e.g. activity, transition, data-mapping
+                element: String!
+		# The id of the element (the formal parameter name for data mappings)
+                id: String!
+		# The name of the owning element, e.g. the activity of a transition
+                context: String!
+		# The changed attribute (for kind CHANGED)
+                field: String!
+		# The old value
+                old: String!
+		# The new value
+                new: String!
+	}
+
 `
 
 func checkBundleName(name string) error {
@@ -117,7 +288,8 @@ func checkBundleName(name string) error {
 	} else if filepath.Base(name) !=  name {
 		return errors.New("A bundle name cannot be a path. Has to be a simple name")
 	}
-	return nil	
+	// Versteckte Verzeichnisse wie der Papierkorb sind keine Bundles
+	return checkHidden(name)
 }
 
 func checkHidden(name string) error {
@@ -146,6 +318,7 @@ func checkPath(path string) error {
 }
 
 type bundle struct {
+	fs        storage.Storage
 	BundleDir string
 	Name      string
 }
@@ -156,8 +329,9 @@ func (r *Resolver) AllBundles(ctx context.Context) []*bundleResolver {
 	var l []*bundleResolver
 	
 	bundle_root_dir := pcontext.BundleRootDir(ctx)
+	fs := pcontext.Storage(ctx)
 	
-	fileinfos, err := ioutil.ReadDir(bundle_root_dir)
+	fileinfos, err := fs.ReadDir(bundle_root_dir)
 	utils.Check(err)
 	
 	for _, file := range fileinfos {
@@ -172,6 +346,7 @@ func (r *Resolver) AllBundles(ctx context.Context) []*bundleResolver {
 		
 		l = append(l, &bundleResolver{
 			&bundle{
+				fs:        fs,
 				BundleDir: path,
 				Name:      name,
 			}})
@@ -188,8 +363,9 @@ func (r *Resolver) Bundle(ctx context.Context, args struct{ BundleSymbolicName s
 		return nil, error2
 	}
 
+	fs := pcontext.Storage(ctx)
 	path := filepath.Join(bundle_root_dir, args.BundleSymbolicName)
-	_, err := os.Stat(path)
+	_, err := fs.Stat(path)
 	if os.IsNotExist(err) {
 		return nil, err
 	}
@@ -197,6 +373,7 @@ func (r *Resolver) Bundle(ctx context.Context, args struct{ BundleSymbolicName s
 
 	return &bundleResolver{
 		&bundle{
+			fs:        fs,
 			BundleDir: path,
 			Name:      args.BundleSymbolicName,
 		}, 
@@ -214,6 +391,7 @@ func (r *bundleResolver) Name() string {
 func (r *bundleResolver) Root() *directoryResolver {
 	return &directoryResolver{
 		&file{
+			fs:         r.b.fs,
 			BundlePath: r.b.BundleDir,
 			Name:      "",
 			Path:      "/",
@@ -221,20 +399,6 @@ func (r *bundleResolver) Root() *directoryResolver {
 	}
 }
 
-func createManifest(bundle_dir, Bundle_symbolic_name string) {
-	err1 := os.Mkdir(filepath.Join(bundle_dir ,"/META-INF"), 0755)
-	utils.Check(err1)
-
-	f, err2 := os.Create(filepath.Join(bundle_dir ,"/META-INF/MANIFEST.MF"))
-	utils.Check(err2)
-	defer f.Close()
-
-	_, err3 := f.WriteString(fmt.Sprintf("Bundle-SymbolicName: %s", Bundle_symbolic_name))
-	utils.Check(err3)
-	
-	f.Sync()
-}
-
 func (r *Resolver) CreateBundle(ctx context.Context, args *struct {Bundle_symbolic_name string}) (*bundleResolver, error) {
 
 	error := checkBundleName(args.Bundle_symbolic_name)
@@ -242,20 +406,16 @@ func (r *Resolver) CreateBundle(ctx context.Context, args *struct {Bundle_symbol
 		return nil, error
 	}
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
-	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
+	fs := pcontext.Storage(ctx)
 
-	if error := os.Mkdir(bundle_dir, 0755); os.IsExist(error) {
-		return nil, errors.New(fmt.Sprintf("Bundle '%s' already exists" , args.Bundle_symbolic_name))
-	} else {		
-		utils.Check(error)
+	//Create the bundle with META-INF/MANIFEST.MF
+	bundle_dir, error := scaffold.CreateBundle(fs, bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name), pcontext.Templates(ctx))
+	if error != nil {
+		return nil, error
 	}
 
-	//Create META-INF/MANIFEST.MF
-        createManifest(bundle_dir, args.Bundle_symbolic_name)
-
-	
-	
 	new_bundle := &bundle{
+		fs:        fs,
 		BundleDir: bundle_dir,
 		Name:      args.Bundle_symbolic_name,
 	}
@@ -270,13 +430,14 @@ func (r *Resolver) DeleteBundle(ctx context.Context, args *struct {Bundle_symbol
 	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
 	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
 
-	if _, error := os.Stat(bundle_dir); os.IsNotExist(error) {
+	if _, error := fs.Stat(bundle_dir); os.IsNotExist(error) {
 		return false, errors.New(fmt.Sprintf("Bundle '%s' does not exist" , args.Bundle_symbolic_name))
 	} 
 
-	if error := os.RemoveAll(bundle_dir); error != nil {
+	if error := moveToTrash(fs, bundle_root_dir, args.Bundle_symbolic_name, "/", true); error != nil {
 		return false, errors.New(fmt.Sprintf("Bundle '%s' cannot be deleted" , args.Bundle_symbolic_name))
 	} 
 
@@ -302,22 +463,33 @@ func (r *Resolver) CreateFile(ctx context.Context, args *struct {Bundle_symbolic
 	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
 	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
 	rel_file_path := filepath.ToSlash(filepath.Join(args.Path, args.Name))
 	filepath := filepath.Join(bundle_dir , rel_file_path)
 
-	if _, error := os.Stat(filepath); error == nil {
+	if _, error := fs.Stat(filepath); error == nil {
 		return nil, errors.New(fmt.Sprintf("A file '%s' already exists" , args.Name))
 	} 
 
-	f, error := os.Create(filepath)
-	if error != nil {
+	// Prozessdateien werden mit START- und END-Event angelegt. Ist der Name
+	// keine gültige Prozess-Id, wird wie bisher eine leere Datei angelegt.
+	var content []byte
+	id := strings.Replace(strings.TrimSuffix(strings.TrimPrefix(rel_file_path, "/"), ".process"), "/", ".", -1)
+	if strings.HasSuffix(args.Name, ".process") && scaffold.CheckProcessId(id) == nil {
+		c, error := scaffold.ProcessContent(fs, pcontext.Templates(ctx), scaffold.ProcessData(args.Bundle_symbolic_name, id))
+		if error != nil {
+			return nil, error
+		}
+		content = c
+	}
+
+	if error := fs.WriteFile(filepath, content, 0644); error != nil {
 		return nil, errors.New(fmt.Sprintf("File '%s' cannot be created" , args.Name))
 	} 
 
-	defer f.Close()
-
 	new_file := &file{
+		fs:         fs,
 		BundlePath: bundle_dir,
 		Path:       rel_file_path,
 		Name:       args.Name,
@@ -325,6 +497,33 @@ func (r *Resolver) CreateFile(ctx context.Context, args *struct {Bundle_symbolic
 	return &fileResolver{new_file}, nil
 }
 
+// CreateProcess legt eine Prozessdatei mit START- und END-Event an. Die
+// Verzeichnisse des Packages der Id werden dabei angelegt.
+func (r *Resolver) CreateProcess(ctx context.Context, args *struct {Bundle_symbolic_name string; Id string}) (*fileResolver, error) {
+
+	if error := checkBundleName(args.Bundle_symbolic_name); error != nil {
+		return nil, error
+	}
+
+	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
+	bundle_dir := filepath.Join(bundle_root_dir, args.Bundle_symbolic_name)
+
+	path, error := scaffold.CreateProcess(fs, bundle_root_dir, args.Bundle_symbolic_name, args.Id, pcontext.Templates(ctx))
+	if error != nil {
+		return nil, error
+	}
+
+	rel_file_path, _ := filepath.Rel(bundle_dir, path)
+	new_file := &file{
+		fs:         fs,
+		BundlePath: bundle_dir,
+		Path:       filepath.ToSlash(rel_file_path),
+		Name:       filepath.Base(path),
+	}
+	return &fileResolver{new_file}, nil
+}
+
 func (r *Resolver) DeleteFile(ctx context.Context, args *struct {Bundle_symbolic_name string; Path string}) (bool, error) {
 
 	error1 := checkBundleName(args.Bundle_symbolic_name)
@@ -338,10 +537,11 @@ func (r *Resolver) DeleteFile(ctx context.Context, args *struct {Bundle_symbolic
 	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
 	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
 	filepath := filepath.Join(bundle_dir , args.Path)
 
-	fi, error := os.Stat(filepath)
+	fi, error := fs.Stat(filepath)
 
 	if  os.IsNotExist(error) {
 		return false, errors.New(fmt.Sprintf("File '%s' does not exist" , args.Path))
@@ -351,7 +551,7 @@ func (r *Resolver) DeleteFile(ctx context.Context, args *struct {Bundle_symbolic
 		return false, errors.New(fmt.Sprintf("File '%s' is a directory. Use mutation 'deleteDir'" , args.Path))
 	} 
 
-	if error := os.Remove(filepath); error != nil {
+	if error := moveToTrash(fs, bundle_root_dir, args.Bundle_symbolic_name, args.Path, false); error != nil {
 		return false, errors.New(fmt.Sprintf("File '%s' cannot be deleted" , args.Path))
 	} 
 
@@ -376,15 +576,17 @@ func (r *Resolver) CreateDir(ctx context.Context, args *struct {Bundle_symbolic_
 	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
 	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
 	rel_file_path := filepath.ToSlash(filepath.Join(args.Path, args.Name))
 	filepath := filepath.Join(bundle_dir , rel_file_path)
 
-	if error := os.Mkdir(filepath, 0755); os.IsExist(error) {
+	if error := fs.Mkdir(filepath, 0755); os.IsExist(error) {
 		return nil, errors.New(fmt.Sprintf("Directory '%s' already exists" , args.Name))
 	} 
 
 	new_dir := &file{
+		fs:         fs,
 		BundlePath: bundle_dir,
 		Path:       rel_file_path,
 		Name:       args.Name,
@@ -405,11 +607,12 @@ func (r *Resolver) DeleteDir(ctx context.Context, args *struct {Bundle_symbolic_
 	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
 	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
 	filepath := filepath.Join(bundle_dir , args.Path)
 
 
-	fi, error := os.Stat(filepath)
+	fi, error := fs.Stat(filepath)
 
 	if  os.IsNotExist(error) {
 		return false, errors.New(fmt.Sprintf("File '%s' does not exist" , args.Path))
@@ -420,7 +623,7 @@ func (r *Resolver) DeleteDir(ctx context.Context, args *struct {Bundle_symbolic_
 	} 
 
 
-	if error := os.RemoveAll(filepath); error != nil {
+	if error := moveToTrash(fs, bundle_root_dir, args.Bundle_symbolic_name, args.Path, true); error != nil {
 		return false, errors.New(fmt.Sprintf("Directory '%s' cannot be deleted" , args.Path))
 	} 
 
@@ -445,13 +648,14 @@ func (r *Resolver) Move(ctx context.Context, args *struct {Bundle_symbolic_name
 	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
 	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
 	
 	oldpath := filepath.Join(bundle_dir , args.Source)
 	//newpath := filepath.Join(bundle_dir , args.Destination, filepath.Base(args.Source))
 	newpath := filepath.Join(bundle_dir , args.Destination)
 
-	if error := os.Rename(oldpath, newpath); error != nil {
+	if error := fs.Rename(oldpath, newpath); error != nil {
 		return false, error
 	} 
 
@@ -462,25 +666,33 @@ func (r *Resolver) Copy(ctx context.Context, args *struct {Bundle_symbolic_name
 
 
 	error1 := checkBundleName(args.Bundle_symbolic_name)
-	utils.Check(error1)
+	if error1 != nil {
+		return false, error1
+	}
 
 	error2 := checkPath(args.Source)
-	utils.Check(error2)
+	if error2 != nil {
+		return false, error2
+	}
 
 	error3 := checkPath(args.Destination)
-	utils.Check(error3)
+	if error3 != nil {
+		return false, error3
+	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)	
+	fs := pcontext.Storage(ctx)
 	bundle_dir := filepath.Join(bundle_root_dir, filepath.Clean(args.Bundle_symbolic_name))
 	
 	srcpath := filepath.Join(bundle_dir , args.Source)
 
-	_, err1 := os.Stat(srcpath)
-	utils.Check(err1)
+	if _, err1 := fs.Stat(srcpath); err1 != nil {
+		return false, errors.New(fmt.Sprintf("Source '%s' does not exist" , args.Source))
+	}
 
 	destpath := filepath.Join(bundle_dir , args.Destination)
 
-	_, err2 := os.Stat(destpath)
+	_, err2 := fs.Stat(destpath)
 
 
 	if err2 == nil {
@@ -490,42 +702,41 @@ func (r *Resolver) Copy(ctx context.Context, args *struct {Bundle_symbolic_name
 		return false, err2
 	}
 
-	error := filepath.Walk(srcpath, copyWalkTreeFunction(srcpath, destpath))
-	utils.Check(error)
+	// Schlägt das Kopieren fehl, wird die unvollständige Kopie entfernt
+	if error := storage.Walk(fs, srcpath, copyWalkTreeFunction(fs, srcpath, destpath)); error != nil {
+		fs.RemoveAll(destpath)
+		return false, error
+	}
 
 	return true, nil
 }
 
-func copyWalkTreeFunction(srcpath, destpath string) func(path string, info os.FileInfo, err error) error {
+func copyWalkTreeFunction(fs storage.Storage, srcpath, destpath string) func(path string, info os.FileInfo, err error) error {
 	return func(path string, info os.FileInfo, err error) error {
 	
+		if err != nil {
+			return err
+		}
 		rel , e1 := filepath.Rel(srcpath, path)
 		if e1 != nil {
 			return e1
 		}
 		var dest = filepath.Join(destpath, rel)
 		if info.IsDir() {
-			err1 := os.Mkdir(dest, 0755)
+			err1 := fs.Mkdir(dest, 0755)
 			if err1 != nil {
 				return err1
 			}
 		} else {
-			fs, err1 := os.Open(path)
+			content, err1 := fs.ReadFile(path)
 			if err1 != nil {
 				return err1
 			}
-			defer fs.Close()
-			
 
-			fd, err2 := os.Create(dest)
+			err2 := fs.WriteFile(dest, content, info.Mode())
 			if err2 != nil {
 				return err2
 			}
-			defer fd.Close()
-			
-			_, err = io.Copy(fd, fs)
-			utils.Check(err)
-			fd.Sync()
 		}
 		return nil;
 	}
@@ -553,6 +764,7 @@ func (r *fileNodeResolver) ToDirectory() (*directoryResolver, bool) {
 }
 
 type file struct {
+	fs         storage.Storage
 	BundlePath string
         Path       string
 	Name       string
@@ -572,8 +784,9 @@ func (r *Resolver) Filenode(ctx context.Context, args struct{ BundleSymbolicName
 	}
 
 	bundle_root_dir := pcontext.BundleRootDir(ctx)
+	fs := pcontext.Storage(ctx)
 	bundle_path := filepath.Join(bundle_root_dir, args.BundleSymbolicName)
-	_, err := os.Stat(bundle_path)
+	_, err := fs.Stat(bundle_path)
 
 	if os.IsNotExist(err) {
 		return nil, errors.New("Unknown bundle")
@@ -581,7 +794,7 @@ func (r *Resolver) Filenode(ctx context.Context, args struct{ BundleSymbolicName
 	utils.Check(err)
 
 	file_path := filepath.Join(bundle_path, args.Path)
-	fileinfo, err := os.Stat(file_path)
+	fileinfo, err := fs.Stat(file_path)
 
 	if os.IsNotExist(err) {
 		return nil, errors.New("Unknown file")
@@ -592,6 +805,7 @@ func (r *Resolver) Filenode(ctx context.Context, args struct{ BundleSymbolicName
 		return &fileNodeResolver{
 			&directoryResolver{
 				&file{
+					fs:         fs,
 					BundlePath: bundle_path,
 					Name:      filepath.Base(args.Path),
 					Path:      filepath.ToSlash(args.Path),
@@ -600,6 +814,7 @@ func (r *Resolver) Filenode(ctx context.Context, args struct{ BundleSymbolicName
 		return &fileNodeResolver{
 			&fileResolver{
 				&file{
+					fs:         fs,
 					BundlePath: bundle_path,
 					Name:      filepath.Base(args.Path),
 					Path:      filepath.ToSlash(args.Path),
@@ -624,13 +839,13 @@ func (r *directoryResolver) Path() string {
 }
 
 func (r *directoryResolver) IsDir() bool {
-	fileInfo, error := os.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
+	fileInfo, error := r.f.fs.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
 	utils.CheckNotExists(error)
 	return fileInfo.IsDir()
 }
 
 func (r *directoryResolver) LastModified() int32 {
-	fileInfo, error := os.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
+	fileInfo, error := r.f.fs.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
 	utils.CheckNotExists(error)
 	return int32(fileInfo.ModTime().Unix())
 }
@@ -638,14 +853,14 @@ func (r *directoryResolver) LastModified() int32 {
 
 func (r *directoryResolver) Children() (*[]*fileNodeResolver, error) {
 	fp := filepath.Join(r.f.BundlePath, r.f.Path)
-	fileInfo, error := os.Stat(fp)
+	fileInfo, error := r.f.fs.Stat(fp)
 	utils.CheckNotExists(error)
 	
 	if !fileInfo.IsDir() {
 		return nil, errors.New("Path does not exist")
 	}
 	
-	fileinfos, err := ioutil.ReadDir(fp)
+	fileinfos, err := r.f.fs.ReadDir(fp)
 	utils.Check(err)
 
 	l := make([]*fileNodeResolver, 0)
@@ -658,6 +873,7 @@ func (r *directoryResolver) Children() (*[]*fileNodeResolver, error) {
 				l = append(l, &fileNodeResolver{
 					&directoryResolver{
 						&file{
+							fs:         r.f.fs,
 							BundlePath: r.f.BundlePath,
 							Path:      filepath.ToSlash(path),
 						Name:      name,
@@ -666,6 +882,7 @@ func (r *directoryResolver) Children() (*[]*fileNodeResolver, error) {
 				l = append(l, &fileNodeResolver{
 					&fileResolver{
 						&file{
+							fs:         r.f.fs,
 							BundlePath: r.f.BundlePath,
 							Path:      filepath.ToSlash(path),
 						Name:      name,
@@ -691,20 +908,20 @@ func (r *fileResolver) Path() string {
 }
 
 func (r *fileResolver) IsDir() bool {
-	fileInfo, error := os.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
+	fileInfo, error := r.f.fs.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
 	utils.CheckNotExists(error)
 	return fileInfo.IsDir()
 }
 
 func (r *fileResolver) LastModified() int32 {
-	fileInfo, error := os.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
+	fileInfo, error := r.f.fs.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
 	utils.CheckNotExists(error)
 	return int32(fileInfo.ModTime().Unix())
 }
 
 
 func (r *fileResolver) Resource_uri() string {
-	fileInfo, error := os.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
+	fileInfo, error := r.f.fs.Stat(filepath.Join(r.f.BundlePath, r.f.Path))
 	utils.Check(error)
 	if !fileInfo.IsDir() {
 	  return filepath.ToSlash(filepath.Join("/bundles" , filepath.Base(r.f.BundlePath), "resources" , r.f.Path))
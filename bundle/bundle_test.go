@@ -0,0 +1,135 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestResolverMemoryStorage(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles", 0755)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+	r := &Resolver{}
+
+	if _, err := r.CreateBundle(ctx, &struct{ Bundle_symbolic_name string }{"b1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateDir(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+		Name                 string
+	}{"b1", "/", "de"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateFile(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+		Name                 string
+	}{"b1", "de", "A1.process"}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := fs.ReadFile("/bundles/b1/de/A1.process"); !strings.Contains(string(content), `id="de.A1"`) {
+		t.Errorf("Expected process de.A1, but was %s", content)
+	}
+
+	// Namen, die keine gültige Prozess-Id sind, ergeben leere Dateien
+	for _, name := range []string{"A-1.process", "1A.process"} {
+		if _, err := r.CreateFile(ctx, &struct {
+			Bundle_symbolic_name string
+			Path                 string
+			Name                 string
+		}{"b1", "de", name}); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if content, err := fs.ReadFile("/bundles/b1/de/" + name); err != nil || len(content) != 0 {
+			t.Errorf("Expected empty file %s, but was %q %v", name, content, err)
+		}
+	}
+
+	if _, err := r.Copy(ctx, &struct {
+		Bundle_symbolic_name string
+		Source               string
+		Destination          string
+	}{"b1", "de", "en"}); err != nil {
+		t.Fatal(err)
+	}
+
+	bundles := r.AllBundles(ctx)
+	if len(bundles) != 1 || bundles[0].Name() != "b1" {
+		t.Fatalf("Expected bundle b1, but was %v", bundles)
+	}
+	children, err := bundles[0].Root().Children()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range *children {
+		names = append(names, c.Name())
+	}
+	if len(names) != 3 || names[0] != "META-INF" || names[1] != "de" || names[2] != "en" {
+		t.Errorf("Expected META-INF, de and en, but was %v", names)
+	}
+
+	node, err := r.Filenode(ctx, struct{ BundleSymbolicName, Path string }{"b1", "en/A1.process"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := node.ToFile(); !ok || f.Resource_uri() != "/bundles/b1/resources/en/A1.process" {
+		t.Errorf("Expected file en/A1.process, but was %v", node)
+	}
+
+	if _, err := r.DeleteFile(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+	}{"b1", "en"}); err == nil {
+		t.Errorf("Expected error deleting a directory with deleteFile")
+	}
+}
+
+// failingStorage schlägt beim Schreiben der Datei 'fail' fehl
+type failingStorage struct {
+	*storage.Memory
+	fail string
+}
+
+func (s *failingStorage) WriteFile(name string, content []byte, perm os.FileMode) error {
+	if filepath.Base(name) == s.fail {
+		return errors.New("disk full")
+	}
+	return s.Memory.WriteFile(name, content, perm)
+}
+
+func TestCopyCleanup(t *testing.T) {
+	fs := &failingStorage{storage.NewMemory(), "A2.process"}
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	fs.Memory.WriteFile("/bundles/b1/de/michael/A1.process", nil, 0644)
+	fs.Memory.WriteFile("/bundles/b1/de/michael/A2.process", nil, 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+
+	if _, err := (&Resolver{}).Copy(ctx, &struct {
+		Bundle_symbolic_name string
+		Source               string
+		Destination          string
+	}{"b1", "de", "en"}); err == nil {
+		t.Fatalf("Expected copy to fail")
+	}
+	if _, err := fs.Stat("/bundles/b1/en"); !os.IsNotExist(err) {
+		t.Errorf("Expected partial copy to be removed, but was %v", err)
+	}
+
+	// Eine fehlende Quelle ist ein Fehler, keine Panic
+	if _, err := (&Resolver{}).Copy(ctx, &struct {
+		Bundle_symbolic_name string
+		Source               string
+		Destination          string
+	}{"b1", "fehlt", "en"}); err == nil {
+		t.Errorf("Expected error for missing source")
+	}
+}
@@ -0,0 +1,145 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/frericksm/pride/storage"
+)
+
+// Name der Datei im Bundle-Root-Verzeichnis, in der der Index zwischen zwei
+// Starts von 'serve' gespeichert wird. Als versteckte Datei wird sie selbst
+// nicht indiziert.
+const INDEX_CACHE_FILE = ".pride-index.json"
+
+const index_cache_version = 2
+
+// Zeit ohne weitere Änderungen am Index, nach der StartWatching den Cache
+// speichert
+const INDEX_CACHE_DELAY = time.Second
+
+// cachedFile ist der gespeicherte Indexeintrag einer Datei
+type cachedFile struct {
+	ModTime int64       `json:"mtime"`
+	Size    int64       `json:"size"`
+	Hash    string      `json:"hash"`
+	Refs    []string    `json:"refs,omitempty"`
+	Tasks   []TaskUsage `json:"tasks,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func (f *cachedFile) contentHash() ([32]byte, bool) {
+	var hash [32]byte
+	b, err := hex.DecodeString(f.Hash)
+	if err != nil || len(b) != len(hash) {
+		return hash, false
+	}
+	copy(hash[:], b)
+	return hash, true
+}
+
+// bundleCache enthält die gespeicherten Einträge eines Bundles, je Pfad relativ
+// zum Bundle-Verzeichnis
+type bundleCache struct {
+	Files map[string]*cachedFile `json:"files"`
+}
+
+func (c *bundleCache) lookup(bundle_dir string, path string) *cachedFile {
+	if c == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(bundle_dir, path)
+	if err != nil {
+		return nil
+	}
+	return c.Files[filepath.ToSlash(rel)]
+}
+
+type indexCache struct {
+	Version int                     `json:"version"`
+	Bundles map[string]*bundleCache `json:"bundles"`
+}
+
+func (c *indexCache) bundle(name string) *bundleCache {
+	if c == nil {
+		return nil
+	}
+	return c.Bundles[name]
+}
+
+// loadIndexCache liest den gespeicherten Index. Fehlt die Datei oder ist sie
+// unlesbar, ist das Ergebnis nil und alle Dateien werden neu indiziert.
+func loadIndexCache(fs storage.Storage, bundle_root_dir string) *indexCache {
+	content, err := fs.ReadFile(filepath.Join(bundle_root_dir, INDEX_CACHE_FILE))
+	if err != nil {
+		return nil
+	}
+	var cache indexCache
+	if err := json.Unmarshal(content, &cache); err != nil || cache.Version != index_cache_version {
+		log.Println("loadIndexCache: ignoring index cache", err)
+		return nil
+	}
+	return &cache
+}
+
+func toIndexCache(index *Index) *indexCache {
+	cache := &indexCache{
+		Version: index_cache_version,
+		Bundles: make(map[string]*bundleCache),
+	}
+	for name, bundle_index := range index.bundle_name_2_bundle_index {
+		files := make(map[string]*cachedFile)
+		for path, state := range *bundle_index.path_filestate {
+			rel, err := filepath.Rel(bundle_index.bundle_dir, path)
+			if err != nil {
+				continue
+			}
+			// Ohne Hash (nicht lesbar) wird die Datei beim nächsten Start neu gelesen
+			f := &cachedFile{
+				ModTime: state.mod_time,
+				Size:    state.size,
+				Error:   (*bundle_index.path_error)[path],
+			}
+			if hash, ok := (*bundle_index.path_contenthash)[path]; ok {
+				f.Hash = hex.EncodeToString(hash[:])
+			}
+			if isProcessFile(path) {
+				id := process_definition_id(bundle_index.bundle_dir, path)
+				for ref := range (*bundle_index.uses_processes)[id] {
+					f.Refs = append(f.Refs, ref)
+				}
+				sort.Strings(f.Refs)
+				f.Tasks = (*bundle_index.uses_tasks)[id]
+			}
+			files[filepath.ToSlash(rel)] = f
+		}
+		cache.Bundles[name] = &bundleCache{Files: files}
+	}
+	return cache
+}
+
+// saveIndexCache speichert 'index' im Bundle-Root-Verzeichnis. Storage.OS
+// schreibt die Datei atomar, so dass ein Abbruch keinen halben Cache
+// hinterlässt. Ist der gespeicherte Cache unverändert, wird er nicht neu
+// geschrieben.
+func saveIndexCache(index *Index) {
+	content, err := json.Marshal(toIndexCache(index))
+	if err != nil {
+		log.Println("saveIndexCache: ", err)
+		return
+	}
+
+	path := filepath.Join(index.bundle_root_dir, INDEX_CACHE_FILE)
+	if saved, err := index.fs.ReadFile(path); err == nil && bytes.Equal(saved, content) {
+		return
+	}
+	if err := index.fs.WriteFile(path, content, 0644); err != nil {
+		log.Println("saveIndexCache: ", err)
+	}
+}
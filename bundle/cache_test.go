@@ -0,0 +1,118 @@
+package bundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/frericksm/pride/storage"
+)
+
+func TestIndexCache(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/A1.process", content, 0644)
+
+	createIndex(fs, "/bundles")
+	cache := loadIndexCache(fs, "/bundles")
+	f := cache.bundle("b1").lookup("/bundles/b1", "/bundles/b1/A1.process")
+	if f == nil || f.Hash == "" || f.Size != int64(len(content)) || len(f.Refs) == 0 {
+		t.Fatalf("Expected cached entry of A1.process, but was %v", f)
+	}
+
+	// Bei unveränderter Änderungszeit und Größe wird der Eintrag aus dem
+	// Cache übernommen und die Datei nicht gelesen
+	f.Error = "aus dem Cache"
+	saved, _ := json.Marshal(cache)
+	fs.WriteFile("/bundles/"+INDEX_CACHE_FILE, saved, 0644)
+	index := createIndex(fs, "/bundles")
+	if errors := index.errors(); len(errors) != 1 || errors[0].Message != "aus dem Cache" {
+		t.Errorf("Expected error from index cache, but was %v", errors)
+	}
+	if _, found := index.ProcessFile("A1"); !found {
+		t.Errorf("Expected process A1 from index cache")
+	}
+
+	// Ein unveränderter Cache wird nicht neu geschrieben
+	before, _ := fs.Stat("/bundles/" + INDEX_CACHE_FILE)
+	createIndex(fs, "/bundles")
+	if after, _ := fs.Stat("/bundles/" + INDEX_CACHE_FILE); !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("Expected unchanged index cache not to be written")
+	}
+
+	// Nach einer Änderung wird die Datei neu gelesen
+	fs.WriteFile("/bundles/b1/A1.process", content, 0644)
+	if errors := createIndex(fs, "/bundles").errors(); len(errors) != 0 {
+		t.Errorf("Expected changed file to be read, but was %v", errors)
+	}
+	if f := loadIndexCache(fs, "/bundles").bundle("b1").lookup("/bundles/b1", "/bundles/b1/A1.process"); f == nil || f.Error != "" {
+		t.Errorf("Expected revalidated cache entry, but was %v", f)
+	}
+}
+
+func TestIndexCacheDelay(t *testing.T) {
+	// Eigenes Root-Verzeichnis, da StartWatching den Index veröffentlicht
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/delay/b1", 0755)
+	watcher, err := StartWatching(fs, "/delay", WatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	before, _ := fs.Stat("/delay/" + INDEX_CACHE_FILE)
+
+	// Ein Event ändert den Index, der Cache wird erst nach INDEX_CACHE_DELAY
+	// gespeichert
+	content, _ := ioutil.ReadFile("../processfile/testdata/A1.process")
+	fs.WriteFile("/delay/b1/A1.process", content, 0644)
+	time.Sleep(INDEX_CACHE_DELAY / 2)
+	if fi, _ := fs.Stat("/delay/" + INDEX_CACHE_FILE); !fi.ModTime().Equal(before.ModTime()) {
+		t.Errorf("Expected index cache not to be saved before INDEX_CACHE_DELAY")
+	}
+	timeout := time.After(5 * time.Second)
+	for loadIndexCache(fs, "/delay").bundle("b1").lookup("/delay/b1", "/delay/b1/A1.process") == nil {
+		select {
+		case <-timeout:
+			t.Fatal("Timeout waiting for the index cache")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestUpdateIndexRereadsNamedFile(t *testing.T) {
+	root, cleanup := tempRoot(t)
+	defer cleanup()
+	path := filepath.Join(root, "b1", "A1.process")
+	writeProcess(t, path)
+	index := createIndex(storage.OS{}, root)
+	cache, _ := ioutil.ReadFile(filepath.Join(root, INDEX_CACHE_FILE))
+
+	// Gleiche Größe und (bei grober Auflösung) gleiche Änderungszeit
+	fi, _ := os.Stat(path)
+	content, _ := ioutil.ReadFile(path)
+	changed := bytes.Replace(content, []byte(`name="A1"`), []byte(`name="B1"`), 1)
+	if err := ioutil.WriteFile(path, changed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	new_index := updateIndex(path, index)
+	hash := (*new_index.bundle_name_2_bundle_index["b1"].path_contenthash)[path]
+	if hash != sha256.Sum256(changed) {
+		t.Errorf("Expected file named by the event to be read again")
+	}
+	if saved, _ := ioutil.ReadFile(filepath.Join(root, INDEX_CACHE_FILE)); !bytes.Equal(saved, cache) {
+		t.Errorf("Expected updateIndex not to save the index cache")
+	}
+}
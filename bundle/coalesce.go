@@ -0,0 +1,103 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"expvar"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/frericksm/pride/storage"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Metriken der Event-Verarbeitung, abrufbar unter /debug/vars
+var watcherMetrics = expvar.NewMap("watcher")
+
+// tickEvent wird in regelmäßigen Abständen durch die Adapter-Kette gereicht,
+// damit Coalesce fällige Batches auch ohne neue Events abarbeiten kann.
+// Es hat keine Op und keinen Namen.
+var tickEvent = fsnotify.Event{}
+
+func isTick(event *fsnotify.Event) bool {
+	return event.Op == 0 && event.Name == ""
+}
+
+// batch sammelt die Events eines Bundles
+type batch struct {
+	paths  map[string]struct{}
+	events int64
+	last   time.Time
+}
+
+// Coalesce sammelt alle Events je Bundle, bis für die Dauer 'quiet' kein
+// weiteres Event für das Bundle eingetroffen ist. Dann wird der Index einmal
+// für das gemeinsame Oberverzeichnis aller Pfade des Batches aktualisiert.
+// Die Pfade der Events werden dabei immer neu gelesen, Verschiebungen
+// innerhalb des Batches erkennt correctErrors.
+// Die gesammelten Events werden nicht weitergereicht, Events außerhalb der
+// Bundles sofort.
+func Coalesce(quiet time.Duration) Adapter {
+	pending := make(map[string]*batch)
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
+			if isTick(event) {
+				new_index := index
+				now := time.Now()
+				for name, b := range pending {
+					if now.Sub(b.last) < quiet {
+						continue
+					}
+					delete(pending, name)
+					watcherMetrics.Add("batches", 1)
+					watcherMetrics.Add("events_coalesced", b.events-1)
+					new_index = updateIndexBelow(commonDir(b.paths), b.paths, new_index)
+				}
+				if new_index != index {
+					correctErrors(index, new_index)
+				}
+				return h.ServeWatcherEvent(watcher, event, new_index)
+			}
+
+			watcherMetrics.Add("events_received", 1)
+			name, ok := bundleName(index.bundle_root_dir, event.Name)
+			if !ok {
+				return h.ServeWatcherEvent(watcher, event, index)
+			}
+
+			b := pending[name]
+			if b == nil {
+				b = &batch{paths: make(map[string]struct{})}
+				pending[name] = b
+			}
+			b.paths[filepath.Clean(event.Name)] = e
+			b.events++
+			b.last = time.Now()
+			return index
+		})
+	}
+}
+
+// commonDir liefert den längsten gemeinsamen Pfad von 'paths'. Bei nur einem
+// Pfad ist das der Pfad selbst.
+func commonDir(paths map[string]struct{}) string {
+	var common []string
+	first := true
+	for path := range paths {
+		segments := strings.Split(path, string(filepath.Separator))
+		if first {
+			common, first = segments, false
+			continue
+		}
+		i := 0
+		for i < len(common) && i < len(segments) && common[i] == segments[i] {
+			i++
+		}
+		common = common[:i]
+	}
+	if len(common) == 1 && common[0] == "" {
+		return string(filepath.Separator)
+	}
+	return strings.Join(common, string(filepath.Separator))
+}
@@ -0,0 +1,103 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/processfile"
+)
+
+// readProcess liest die Prozessdefinition 'path' aus dem Bundle 'bundle_symbolic_name'
+func readProcess(ctx context.Context, bundle_symbolic_name string, path string) (*processfile.Process, error) {
+
+	if err := checkBundleName(bundle_symbolic_name); err != nil {
+		return nil, err
+	}
+	if err := checkPath(path); err != nil {
+		return nil, err
+	}
+
+	bundle_root_dir := pcontext.BundleRootDir(ctx)
+	file_path := filepath.Join(bundle_root_dir, bundle_symbolic_name, path)
+
+	content, err := pcontext.Storage(ctx).ReadFile(file_path)
+	if os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("File '%s' does not exist", path))
+	} else if err != nil {
+		return nil, err
+	}
+
+	p, err := processfile.Parse(content)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", path, err))
+	}
+	return p, nil
+}
+
+func (r *Resolver) ProcessDiff(ctx context.Context, args *struct {
+	Bundle_symbolic_name       string
+	Path                       string
+	Other_bundle_symbolic_name *string
+	Other_path                 string
+	Layout                     *bool
+}) ([]*processChangeResolver, error) {
+
+	other_bundle_symbolic_name := args.Bundle_symbolic_name
+	if args.Other_bundle_symbolic_name != nil {
+		other_bundle_symbolic_name = *args.Other_bundle_symbolic_name
+	}
+
+	a, err := readProcess(ctx, args.Bundle_symbolic_name, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readProcess(ctx, other_bundle_symbolic_name, args.Other_path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := processfile.DiffOptions{Layout: args.Layout != nil && *args.Layout}
+
+	l := make([]*processChangeResolver, 0)
+	for _, c := range processfile.Diff(a, b, opts) {
+		l = append(l, &processChangeResolver{c})
+	}
+	return l, nil
+}
+
+type processChangeResolver struct {
+	c processfile.Change
+}
+
+func (r *processChangeResolver) Kind() string {
+	return r.c.Kind
+}
+
+func (r *processChangeResolver) Element() string {
+	return r.c.Element
+}
+
+func (r *processChangeResolver) Id() string {
+	return r.c.Id
+}
+
+func (r *processChangeResolver) Context() string {
+	return r.c.Context
+}
+
+func (r *processChangeResolver) Field() string {
+	return r.c.Field
+}
+
+func (r *processChangeResolver) Old() string {
+	return r.c.Old
+}
+
+func (r *processChangeResolver) New() string {
+	return r.c.New
+}
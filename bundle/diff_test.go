@@ -0,0 +1,39 @@
+package bundle
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestProcessDiff(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/A1.process", content, 0644)
+	fs.WriteFile("/bundles/b1/broken.process", []byte(`<process id="A1"><activities>`), 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+	diff := func(other string) ([]*processChangeResolver, error) {
+		return (&Resolver{}).ProcessDiff(ctx, &struct {
+			Bundle_symbolic_name       string
+			Path                       string
+			Other_bundle_symbolic_name *string
+			Other_path                 string
+			Layout                     *bool
+		}{Bundle_symbolic_name: "b1", Path: "A1.process", Other_path: other})
+	}
+
+	if changes, err := diff("A1.process"); err != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes, but was %v %v", changes, err)
+	}
+	// Eine fehlerhafte Datei ist ein Fehler, keine Panic
+	if _, err := diff("broken.process"); err == nil {
+		t.Errorf("Expected error for malformed process file")
+	}
+}
@@ -0,0 +1,51 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+	"github.com/fsnotify/fsnotify"
+)
+
+// FormatOnSave schreibt geänderte Prozessdateien in kanonischer Form zurück.
+// Das Zurückschreiben löst ein weiteres Write-Event aus, das dann keine
+// Änderung mehr bewirkt. Dateien, die (noch) nicht geparst werden können,
+// bleiben unverändert.
+func FormatOnSave() Adapter {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
+			if event.Op&fsnotify.Write == fsnotify.Write && filepath.Ext(event.Name) == ".process" {
+				formatFile(index.fs, event.Name)
+			}
+			return h.ServeWatcherEvent(watcher, event, index)
+		})
+	}
+}
+
+func formatFile(fs storage.Storage, path string) {
+	fi, err := fs.Stat(path)
+	if err != nil {
+		return
+	}
+	content, err := fs.ReadFile(path)
+	if err != nil {
+		return
+	}
+	formatted, err := processfile.Format(content)
+	if err != nil {
+		log.Println("FormatOnSave: ", path, err)
+		return
+	}
+	if bytes.Equal(content, formatted) {
+		return
+	}
+	if err := fs.WriteFile(path, formatted, fi.Mode()); err != nil {
+		log.Println("FormatOnSave: ", path, err)
+		return
+	}
+	log.Println("FormatOnSave: formatted ", path)
+}
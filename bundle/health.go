@@ -0,0 +1,138 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	pcontext "github.com/frericksm/pride/context"
+)
+
+// Der jeweils aktuelle Index je Bundle-Root-Verzeichnis, wie ihn
+// StartWatching nach jedem Event oder StartRefreshing veröffentlicht, und ob
+// er beobachtet wird
+var published = struct {
+	sync.RWMutex
+	indexes  map[string]*Index
+	watching map[string]bool
+}{indexes: make(map[string]*Index), watching: make(map[string]bool)}
+
+func publishIndex(index *Index, watching bool) {
+	published.Lock()
+	defer published.Unlock()
+	published.indexes[index.bundle_root_dir] = index
+	published.watching[index.bundle_root_dir] = watching
+}
+
+// currentIndex liefert den veröffentlichten Index zum Bundle-Root-Verzeichnis
+// von 'ctx'. Ohne veröffentlichten Index (außerhalb von serve) wird der Index
+// neu gebaut. Das Ergebnis gibt an, ob der Index beobachtet wird.
+func currentIndex(ctx context.Context) (*Index, bool) {
+	bundle_root_dir := pcontext.BundleRootDir(ctx)
+	published.RLock()
+	index := published.indexes[bundle_root_dir]
+	watching := published.watching[bundle_root_dir]
+	published.RUnlock()
+	if index != nil {
+		return index, watching
+	}
+	return createIndex(pcontext.Storage(ctx), bundle_root_dir), false
+}
+
+// indexError ist der Fehler beim Indizieren einer Datei
+type indexError struct {
+	Bundle  string `json:"bundle"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// errors liefert die Fehler aller Bundles des Index, sortiert nach Bundle und Pfad
+func (index *Index) errors() []*indexError {
+	l := make([]*indexError, 0)
+	for name, bundle_index := range index.bundle_name_2_bundle_index {
+		for path, message := range *bundle_index.path_error {
+			rel, err := filepath.Rel(bundle_index.bundle_dir, path)
+			if err != nil {
+				rel = path
+			}
+			l = append(l, &indexError{Bundle: name, Path: filepath.ToSlash(rel), Message: message})
+		}
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].Bundle != l[j].Bundle {
+			return l[i].Bundle < l[j].Bundle
+		}
+		return l[i].Path < l[j].Path
+	})
+	return l
+}
+
+func (r *Resolver) IndexErrors(ctx context.Context, args *struct{ Bundle_symbolic_name *string }) []*indexErrorResolver {
+	index, _ := currentIndex(ctx)
+	l := make([]*indexErrorResolver, 0)
+	for _, e := range index.errors() {
+		if args.Bundle_symbolic_name != nil && *args.Bundle_symbolic_name != e.Bundle {
+			continue
+		}
+		l = append(l, &indexErrorResolver{e})
+	}
+	return l
+}
+
+type indexErrorResolver struct {
+	e *indexError
+}
+
+func (r *indexErrorResolver) Bundle() string {
+	return r.e.Bundle
+}
+
+func (r *indexErrorResolver) Path() string {
+	return r.e.Path
+}
+
+func (r *indexErrorResolver) Message() string {
+	return r.e.Message
+}
+
+// health ist die Antwort des HealthHandler. Status ist "ok" oder, wenn
+// Dateien nicht indiziert werden konnten, "degraded".
+type health struct {
+	Status   string        `json:"status"`
+	Watching bool          `json:"watching"`
+	Bundles  int           `json:"bundles"`
+	Files    int           `json:"files"`
+	Errors   []*indexError `json:"errors"`
+}
+
+// HealthHandler liefert den Zustand des Index als JSON
+type HealthHandler struct{}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	index, watching := currentIndex(r.Context())
+
+	result := &health{
+		Status:   "ok",
+		Watching: watching,
+		Bundles:  len(index.bundle_name_2_bundle_index),
+		Errors:   index.errors(),
+	}
+	for _, bundle_index := range index.bundle_name_2_bundle_index {
+		result.Files += len(*bundle_index.path_filestate)
+	}
+	if len(result.Errors) > 0 {
+		result.Status = "degraded"
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(content)
+}
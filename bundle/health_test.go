@@ -0,0 +1,95 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestIndexErrors(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	valid, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", valid, 0644)
+	fs.WriteFile("/bundles/b1/de/michael/A2.process", []byte(`<process id="A2"><activities>`), 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+
+	errors := (&Resolver{}).IndexErrors(ctx, &struct{ Bundle_symbolic_name *string }{})
+	if len(errors) != 1 || errors[0].Bundle() != "b1" || errors[0].Path() != "de/michael/A2.process" {
+		t.Fatalf("Expected error for de/michael/A2.process, but was %v", errors)
+	}
+	if errors[0].Message() == "" {
+		t.Errorf("Expected error message")
+	}
+
+	w := httptest.NewRecorder()
+	h := &pcontext.Handler{BundleRootDir: "/bundles", Storage: fs, Handler: &HealthHandler{}}
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var result health
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "degraded" || result.Bundles != 1 || len(result.Errors) != 1 {
+		t.Errorf("Expected degraded health with 1 error, but was %s", w.Body)
+	}
+
+	fs.WriteFile("/bundles/b1/de/michael/A2.process", valid, 0644)
+	index := createIndex(fs, "/bundles")
+	if _, present := index.ProcessFile("de.michael.A1"); !present {
+		t.Errorf("Expected process de.michael.A1 in index")
+	}
+	if errors := index.errors(); len(errors) != 0 {
+		t.Errorf("Expected no errors, but was %v", errors)
+	}
+}
+
+func TestRefreshIndex(t *testing.T) {
+	// Eigenes Root-Verzeichnis, da StartRefreshing den Index veröffentlicht
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/refresh/b1", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/refresh/b1/A1.process", content, 0644)
+	if err := StartRefreshing(fs, "/missing", 0); err == nil {
+		t.Errorf("Expected error for missing bundle root dir")
+	}
+	if err := StartRefreshing(fs, "/refresh", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// Der Index wird nicht je Anfrage gebaut
+	ctx := pcontext.WithStorage(context.Background(), "/refresh", fs)
+	index, watching := currentIndex(ctx)
+	if again, _ := currentIndex(ctx); again != index || watching || index.search == nil {
+		t.Fatalf("Expected the published index without watching")
+	}
+	expectProcess(t, index, "A1", true)
+
+	// Nach dem Intervall ist eine neue Datei im Index
+	fs.WriteFile("/refresh/b1/A2.process", content, 0644)
+	timeout := time.After(5 * time.Second)
+	for {
+		if index, _ := currentIndex(ctx); index.search != nil {
+			if _, present := index.ProcessFile("A2"); present {
+				break
+			}
+		}
+		select {
+		case <-timeout:
+			t.Fatal("Timeout waiting for the refreshed index")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+// Die Bundle-Verzeichnisse (oder das Bundle-Root-Verzeichnis) sind
+// Git-Checkouts. Die Historie wird über das Kommando 'git' gelesen und
+// geschrieben. Aufrufe von 'git' werden serialisiert, damit sich Commits nicht
+// um den Index des Repositorys streiten.
+var gitMu sync.Mutex
+
+// Trennzeichen im Format von 'git log': Unit Separator zwischen den Feldern,
+// Record Separator zwischen den Commits
+const git_log_format = "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e"
+
+type commit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    string
+	Message string
+}
+
+// git führt 'git' im Verzeichnis 'dir' aus und liefert die Ausgabe. Im
+// Fehlerfall enthält der Fehler die Ausgabe von git auf stderr.
+func git(dir string, args ...string) ([]byte, error) {
+	gitMu.Lock()
+	defer gitMu.Unlock()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, errors.New(fmt.Sprintf("git %s: %s", args[0], msg))
+	}
+	return stdout.Bytes(), nil
+}
+
+func parseLog(out []byte) []*commit {
+	l := make([]*commit, 0)
+	for _, record := range strings.Split(string(out), "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		l = append(l, &commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    fields[3],
+			Message: fields[4],
+		})
+	}
+	return l
+}
+
+// gitBundleDir prüft Bundle und Pfad und liefert das Bundle-Verzeichnis und
+// den Pfad relativ dazu im Format von git
+func gitBundleDir(ctx context.Context, bundle_symbolic_name string, path string) (string, string, error) {
+	if err := checkBundleName(bundle_symbolic_name); err != nil {
+		return "", "", err
+	}
+	if err := checkPath(path); err != nil {
+		return "", "", err
+	}
+	if _, ok := pcontext.Storage(ctx).(storage.OS); !ok {
+		return "", "", errors.New("The git history is only available for bundles in the file system")
+	}
+
+	bundle_dir := filepath.Join(pcontext.BundleRootDir(ctx), bundle_symbolic_name)
+	if _, err := os.Stat(bundle_dir); os.IsNotExist(err) {
+		return "", "", errors.New(fmt.Sprintf("Bundle '%s' does not exist", bundle_symbolic_name))
+	}
+
+	rel := strings.TrimPrefix(filepath.ToSlash(path), "/")
+	if rel == "" {
+		rel = "."
+	}
+	return bundle_dir, rel, nil
+}
+
+func (r *Resolver) History(ctx context.Context, args *struct {
+	Bundle_symbolic_name string
+	Path                 string
+	Limit                *int32
+}) ([]*commitResolver, error) {
+
+	bundle_dir, rel, err := gitBundleDir(ctx, args.Bundle_symbolic_name, args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	git_args := []string{"log", git_log_format}
+	if args.Limit != nil {
+		git_args = append(git_args, fmt.Sprintf("--max-count=%d", *args.Limit))
+	}
+	out, err := git(bundle_dir, append(git_args, "--", rel)...)
+	if err != nil {
+		return nil, err
+	}
+
+	l := make([]*commitResolver, 0)
+	for _, c := range parseLog(out) {
+		l = append(l, &commitResolver{c})
+	}
+	return l, nil
+}
+
+func (r *Resolver) FileAtRevision(ctx context.Context, args *struct {
+	Bundle_symbolic_name string
+	Path                 string
+	Revision             string
+}) (string, error) {
+
+	bundle_dir, rel, err := gitBundleDir(ctx, args.Bundle_symbolic_name, args.Path)
+	if err != nil {
+		return "", err
+	}
+	if args.Revision == "" || strings.HasPrefix(args.Revision, "-") || strings.Contains(args.Revision, ":") {
+		return "", errors.New(fmt.Sprintf("Invalid revision '%s'", args.Revision))
+	}
+
+	// './' macht den Pfad relativ zum Bundle-Verzeichnis statt zur Wurzel
+	// des Repositorys
+	out, err := git(bundle_dir, "show", args.Revision+":./"+rel)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (r *Resolver) Commit(ctx context.Context, args *struct {
+	Bundle_symbolic_name string
+	Message              string
+	Paths                []string
+	Author               *string
+	Email                *string
+}) (*commitResolver, error) {
+
+	if strings.TrimSpace(args.Message) == "" {
+		return nil, errors.New("A commit message cannot be empty")
+	}
+	if len(args.Paths) == 0 {
+		return nil, errors.New("No paths to commit")
+	}
+	author, err := commitAuthor(args.Author, args.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle_dir string
+	rels := make([]string, 0, len(args.Paths))
+	for _, path := range args.Paths {
+		dir, rel, err := gitBundleDir(ctx, args.Bundle_symbolic_name, path)
+		if err != nil {
+			return nil, err
+		}
+		bundle_dir = dir
+		rels = append(rels, rel)
+	}
+
+	// 'add -A' nimmt auch gelöschte Dateien auf. Der Commit enthält nur die
+	// angegebenen Pfade, auch wenn andere Änderungen im Index stehen.
+	if _, err := git(bundle_dir, append([]string{"add", "-A", "--"}, rels...)...); err != nil {
+		return nil, err
+	}
+	commit_args := []string{"commit", "-m", args.Message}
+	if author != "" {
+		commit_args = append(commit_args, "--author", author)
+	}
+	if _, err := git(bundle_dir, append(append(commit_args, "--"), rels...)...); err != nil {
+		return nil, err
+	}
+
+	out, err := git(bundle_dir, "log", git_log_format, "--max-count=1")
+	if err != nil {
+		return nil, err
+	}
+	commits := parseLog(out)
+	if len(commits) == 0 {
+		return nil, errors.New("Commit not found")
+	}
+	return &commitResolver{commits[0]}, nil
+}
+
+// commitAuthor liefert den Autor eines Commits im Format von 'git commit
+// --author'. Ohne Name und E-Mail-Adresse ist das Ergebnis leer, git nimmt
+// dann die eigene Identität.
+func commitAuthor(name *string, email *string) (string, error) {
+	if name == nil && email == nil {
+		return "", nil
+	}
+	if name == nil || email == nil || strings.TrimSpace(*name) == "" || strings.TrimSpace(*email) == "" {
+		return "", errors.New("A commit author needs a name and an email address")
+	}
+	if strings.ContainsAny(*name+*email, "<>\n") {
+		return "", errors.New(fmt.Sprintf("Invalid commit author '%s <%s>'", *name, *email))
+	}
+	return fmt.Sprintf("%s <%s>", strings.TrimSpace(*name), strings.TrimSpace(*email)), nil
+}
+
+type commitResolver struct {
+	c *commit
+}
+
+func (r *commitResolver) Hash() string {
+	return r.c.Hash
+}
+
+func (r *commitResolver) Author() string {
+	return r.c.Author
+}
+
+func (r *commitResolver) Email() string {
+	return r.c.Email
+}
+
+func (r *commitResolver) Date() string {
+	return r.c.Date
+}
+
+func (r *commitResolver) Message() string {
+	return r.c.Message
+}
@@ -0,0 +1,105 @@
+package bundle
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root, cleanup := tempRoot(t)
+	defer cleanup()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Michael"},
+		{"config", "user.email", "michael@example.com"},
+	} {
+		if _, err := git(root, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(root, "b1", "de", "michael", "A1.process")
+	writeProcess(t, path)
+	ctx := pcontext.WithStorage(context.Background(), root, storage.OS{})
+	r := &Resolver{}
+
+	commitAs := func(author, email *string, message string, paths ...string) (*commitResolver, error) {
+		return r.Commit(ctx, &struct {
+			Bundle_symbolic_name string
+			Message              string
+			Paths                []string
+			Author               *string
+			Email                *string
+		}{"b1", message, paths, author, email})
+	}
+	commit := func(message string, paths ...string) (*commitResolver, error) {
+		return commitAs(nil, nil, message, paths...)
+	}
+	first, err := commit("A1 angelegt", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("<process/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commit("A1 geleert", "de/michael/A1.process"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := r.History(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+		Limit                *int32
+	}{"b1", "de/michael/A1.process", nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Message() != "A1 geleert" || history[1].Hash() != first.Hash() {
+		t.Errorf("Unexpected history %v", history)
+	}
+	if history[1].Author() != "Michael" || history[1].Email() != "michael@example.com" {
+		t.Errorf("Unexpected author %s <%s>", history[1].Author(), history[1].Email())
+	}
+
+	content, err := r.FileAtRevision(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+		Revision             string
+	}{"b1", "de/michael/A1.process", first.Hash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, _ := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if content != string(original) {
+		t.Errorf("Expected the first version of A1.process")
+	}
+
+	if _, err := commit("nichts", "de"); err == nil {
+		t.Errorf("Expected error committing without changes")
+	}
+
+	// Commit im Namen eines Benutzers
+	name, email := "Anna", "anna@example.com"
+	if err := ioutil.WriteFile(path, []byte("<process id=\"A1\"/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commitAs(&name, nil, "ohne E-Mail", "de"); err == nil {
+		t.Errorf("Expected error for author without email")
+	}
+	c, err := commitAs(&name, &email, "A1 von Anna", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Author() != "Anna" || c.Email() != "anna@example.com" {
+		t.Errorf("Expected author Anna <anna@example.com>, but was %s <%s>", c.Author(), c.Email())
+	}
+}
@@ -2,12 +2,12 @@
 package bundle
 
 import (
+	"fmt"
 	"log"
-//	"fmt"
 	"os"
-	"io/ioutil"
 	"github.com/frericksm/pride/utils"	
 	"github.com/frericksm/pride/processfile"	
+	"github.com/frericksm/pride/storage"
 	"strings"
 	"path/filepath"
 	"github.com/fsnotify/fsnotify"
@@ -16,18 +16,34 @@ import (
 
 var e struct{}
 
+// fileState ist der Zustand einer Datei zum Zeitpunkt ihrer Indizierung
+type fileState struct {
+	mod_time int64;
+	size int64
+}
+
 type BundleIndex struct {
 	bundle_dir string;
 	bundle_name string;
 	uses_processes *map[string]map[string]struct{};
 	usedby_processes *map[string]map[string]struct{};
 	path_contenthash *map[string][32]byte;
-	contenthash_path *map[[32]byte]map[string]struct{}
+	contenthash_path *map[[32]byte]map[string]struct{};
+	path_filestate *map[string]fileState;
+	// Fehler beim Lesen oder Parsen je Datei
+	path_error *map[string]string;
+	// Aufrufe von Tasks je Prozess
+	uses_tasks *map[string][]TaskUsage;
+	// Task-Kataloge je Pfad (siehe processfile.TaskCatalogFiles)
+	path_catalog *map[string]*processfile.TaskCatalog
 }
 
 type Index struct {
+	fs storage.Storage;
 	bundle_root_dir string;
-	bundle_name_2_bundle_index map[string]*BundleIndex
+	bundle_name_2_bundle_index map[string]*BundleIndex;
+	// Der invertierte Index für die Suche, nil solange er nicht gebaut ist
+	search *searchIndex
 }
 
 func process_definition_id(bundle_dir string, path string) string {
@@ -45,38 +61,142 @@ func file_path(bundle_dir string, process_definition_id string) string {
 	return filepath.Join(bundle_dir, strings.Replace(process_definition_id, "." ,"/", -1) + ".process")
 }
 
-func walk_files(bundle_dir string, uses_processes_map *map[string]map[string]struct{}, path_contenthash_map *map[string][32]byte) filepath.WalkFunc {
-	return func(path string, info os.FileInfo, err error) error {
-		
-		fi , _ := os.Stat(path)
-		if fi.IsDir() {
-			return nil
+func stateOf(fi os.FileInfo) fileState {
+	return fileState{mod_time: fi.ModTime().UnixNano(), size: fi.Size()}
+}
+
+func isProcessFile(path string) bool {
+	return strings.Contains(filepath.Base(path), ".process")
+}
+
+// index_file trägt Hash und (bei Prozessdateien) die Referenzen der Datei
+// 'path' in 'bundle_index' ein. Stimmen Änderungszeit und Größe mit dem
+// Eintrag 'cached' überein, wird die Datei nicht gelesen.
+func index_file(fs storage.Storage, bundle_index *BundleIndex, path string, fi os.FileInfo, cached *cachedFile) {
+
+	p0 := filepath.Clean(path)
+	state := stateOf(fi)
+	(*bundle_index.path_filestate)[p0] = state
+	delete(*bundle_index.path_error, p0)
+
+	// Task-Kataloge werden immer gelesen, sie stehen nicht im Cache
+	catalog := isTaskCatalog(bundle_index.bundle_dir, path)
+	if catalog {
+		delete(*bundle_index.path_catalog, p0)
+	}
+
+	if cached != nil && !catalog && cached.ModTime == state.mod_time && cached.Size == state.size {
+		if hash, ok := cached.contentHash(); ok {
+			(*bundle_index.path_contenthash)[p0] = hash
+			if cached.Error != "" {
+				(*bundle_index.path_error)[p0] = cached.Error
+			}
+			if isProcessFile(path) {
+				refs := make(map[string]struct{})
+				for _, r := range cached.Refs {
+					refs[r] = e
+				}
+				id := process_definition_id(bundle_index.bundle_dir, path)
+				(*bundle_index.uses_processes)[id] = refs
+				setTaskUsages(bundle_index, id, cached.Tasks)
+			}
+			return
 		}
-		
-		content := processfile.FileContent(path)
+	}
 
-	        //log.Println(fmt.Sprintf("walkFile: %s", filepath.Clean(path)))
+	// Eine nicht lesbare (oder inzwischen gelöschte) Datei wird ohne Hash
+	// vermerkt und beim nächsten Event erneut gelesen
+	content, err := fs.ReadFile(path)
+	if err != nil {
+		log.Println(fmt.Sprintf("index_file: %s", err))
+		delete(*bundle_index.path_contenthash, p0)
+		(*bundle_index.path_error)[p0] = err.Error()
+		return
+	}
 
-		// Calc SHA256 for all files
-		p0 := filepath.Clean(path)
-		(*path_contenthash_map)[p0] = sha256.Sum256(content)
-
-		// Calc refs for all process files
-		if strings.Contains(filepath.Base(path), ".process") {
-			refs := make(map[string]struct{})
-	//		refs := make([]string, 0)
-			if len(content) != 0 {
-				p := processfile.FromBytes(content)
-				for _, act := range p.Activities {
-					if act.Body.ImplementationType == "SUB_FLOW" {
-						refs[act.Body.ImplementationRefId] = e
-						//refs = append(refs, act.Body.ImplementationRefId)
-					}
+        //log.Println(fmt.Sprintf("walkFile: %s", filepath.Clean(path)))
+
+	// Calc SHA256 for all files
+	(*bundle_index.path_contenthash)[p0] = sha256.Sum256(content)
+
+	// Calc refs for all process files
+	if isProcessFile(path) {
+		id := process_definition_id(bundle_index.bundle_dir, path)
+		refs := make(map[string]struct{})
+		var tasks []TaskUsage
+//		refs := make([]string, 0)
+		if len(content) != 0 {
+			p, err := processfile.Parse(content)
+			if err != nil {
+				log.Println(fmt.Sprintf("index_file: %s: %s", path, err))
+				(*bundle_index.path_error)[p0] = err.Error()
+				p = &processfile.Process{}
+			}
+			for _, act := range p.Activities {
+				if act.Body.ImplementationType == "SUB_FLOW" {
+					refs[act.Body.ImplementationRefId] = e
+					//refs = append(refs, act.Body.ImplementationRefId)
+				} else if act.Body.ImplementationType == "TASK" {
+					tasks = append(tasks, taskUsage(act))
 				}
 			}
-			(*uses_processes_map)[process_definition_id(bundle_dir, path)] =  refs
+		}
+		(*bundle_index.uses_processes)[id] =  refs
+		setTaskUsages(bundle_index, id, tasks)
+	}
+
+	if catalog {
+		c, err := processfile.ParseTaskCatalog(path, content)
+		if err != nil {
+			log.Println(fmt.Sprintf("index_file: %s: %s", path, err))
+			(*bundle_index.path_error)[p0] = err.Error()
+		} else {
+			(*bundle_index.path_catalog)[p0] = c
+		}
+	}
+}
+
+// remove_file entfernt die Datei 'path' aus 'bundle_index'
+func remove_file(bundle_index *BundleIndex, path string) {
+	p0 := filepath.Clean(path)
+	delete(*bundle_index.path_contenthash, p0)
+	delete(*bundle_index.path_filestate, p0)
+	delete(*bundle_index.path_error, p0)
+	delete(*bundle_index.path_catalog, p0)
+	if isProcessFile(path) {
+		id := process_definition_id(bundle_index.bundle_dir, path)
+		delete(*bundle_index.uses_processes, id)
+		delete(*bundle_index.uses_tasks, id)
+	}
+}
+
+// skipHidden prüft, ob 'path' unterhalb von 'bundle_dir' versteckt ist (wie
+// die temporären Dateien beim Schreiben). Versteckte Verzeichnisse werden beim
+// Indizieren ganz übersprungen.
+func skipHidden(bundle_dir string, path string, info os.FileInfo) (bool, error) {
+	if filepath.Clean(path) == filepath.Clean(bundle_dir) || !strings.HasPrefix(info.Name(), ".") {
+		return false, nil
+	}
+	if info.IsDir() {
+		return true, filepath.SkipDir
+	}
+	return true, nil
+}
+
+func walk_files(fs storage.Storage, bundle_index *BundleIndex, cache *bundleCache) filepath.WalkFunc {
+	return func(path string, info os.FileInfo, err error) error {
+		
+		if err != nil {
+			return nil
+		}
+		if skip, err := skipHidden(bundle_index.bundle_dir, path, info); skip {
+			return err
+		}
+		if info.IsDir() {
+			return nil
 		}
 
+		index_file(fs, bundle_index, path, info, cache.lookup(bundle_index.bundle_dir, path))
 		return nil;
 	}
 }
@@ -114,61 +234,127 @@ func reverse_path_contenthash_map(path_contenthash_map *map[string][32]byte) *ma
 }
  
 
-func createBundleIndex(bundle_dir string, bundle_name string) *BundleIndex {
-
+func newBundleIndex(bundle_dir string, bundle_name string) *BundleIndex {
 	uses_processes_map := make(map[string]map[string]struct{})
 	path_contenthash_map := make(map[string][32]byte)
+	path_filestate_map := make(map[string]fileState)
+	path_error_map := make(map[string]string)
+	uses_tasks_map := make(map[string][]TaskUsage)
+	path_catalog_map := make(map[string]*processfile.TaskCatalog)
 
-	filepath.Walk(bundle_dir, walk_files(bundle_dir, &uses_processes_map, &path_contenthash_map))
-	
 	return &BundleIndex{
 		bundle_dir: bundle_dir,
 		bundle_name: bundle_name,
 		uses_processes: &uses_processes_map,
-		usedby_processes: reverse_uses_processes_map(&uses_processes_map),
 		path_contenthash: &path_contenthash_map,
-		contenthash_path: reverse_path_contenthash_map(&path_contenthash_map),
-	}	
+		path_filestate: &path_filestate_map,
+		path_error: &path_error_map,
+		uses_tasks: &uses_tasks_map,
+		path_catalog: &path_catalog_map,
+	}
 }
 
-func updateBundleIndex(bundle_dir string, bundle_name string, bundle_index *BundleIndex) *BundleIndex {
-	return createBundleIndex(bundle_dir, bundle_name)
+// copyBundleIndex kopiert die Maps von 'bundle_index', damit der alte Index
+// beim Aktualisieren unverändert bleibt
+func copyBundleIndex(bundle_index *BundleIndex) *BundleIndex {
+	c := newBundleIndex(bundle_index.bundle_dir, bundle_index.bundle_name)
+	for k, v := range *bundle_index.uses_processes {
+		(*c.uses_processes)[k] = v
+	}
+	for k, v := range *bundle_index.path_contenthash {
+		(*c.path_contenthash)[k] = v
+	}
+	for k, v := range *bundle_index.path_filestate {
+		(*c.path_filestate)[k] = v
+	}
+	for k, v := range *bundle_index.path_error {
+		(*c.path_error)[k] = v
+	}
+	for k, v := range *bundle_index.uses_tasks {
+		(*c.uses_tasks)[k] = v
+	}
+	for k, v := range *bundle_index.path_catalog {
+		(*c.path_catalog)[k] = v
+	}
+	return c
 }
 
-func createIndex(bundle_root_dir string) *Index {
+// reverse berechnet die umgekehrten Maps von 'bundle_index'
+func (bundle_index *BundleIndex) reverse() *BundleIndex {
+	bundle_index.usedby_processes = reverse_uses_processes_map(bundle_index.uses_processes)
+	bundle_index.contenthash_path = reverse_path_contenthash_map(bundle_index.path_contenthash)
+	return bundle_index
+}
 
-	m2bi := make(map[string]*BundleIndex)
+func createBundleIndex(fs storage.Storage, bundle_dir string, bundle_name string, cache *bundleCache) *BundleIndex {
 
-	fileinfos, err := ioutil.ReadDir(bundle_root_dir)
-	utils.Check(err)
+	bundle_index := newBundleIndex(bundle_dir, bundle_name)
+
+	storage.Walk(fs, bundle_dir, walk_files(fs, bundle_index, cache))
 	
-	for _, file := range fileinfos {
-		if !file.IsDir() {
-			continue
+	return bundle_index.reverse()
+}
+
+// updateBundleIndex baut einen neuen BundleIndex, in dem nur die Dateien
+// unterhalb von 'modified_path' neu indiziert sind. Dateien, deren Änderungszeit
+// und Größe sich nicht geändert haben, werden dabei nicht gelesen. Die Dateien
+// in 'named' (die Pfade der Events) werden immer gelesen: bei grober
+// Auflösung der Änderungszeit (NFS, SMB) bleiben Änderungszeit und Größe
+// einer geänderten Datei sonst eventuell gleich.
+func updateBundleIndex(fs storage.Storage, modified_path string, named map[string]struct{}, bundle_index *BundleIndex) *BundleIndex {
+
+	new_bundle_index := copyBundleIndex(bundle_index)
+	modified_path = filepath.Clean(modified_path)
+
+	// Alle bekannten Dateien unterhalb von modified_path merken ...
+	known := make(map[string]fileState)
+	for path, state := range *bundle_index.path_filestate {
+		if isBelow(path, modified_path) {
+			known[path] = state
 		}
-		name := file.Name()
-		if strings.HasPrefix(name , ".") {
-			continue
+	}
+
+	// ... und die aktuellen Dateien neu indizieren
+	storage.Walk(fs, modified_path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
 		}
-		path := filepath.Join(bundle_root_dir, file.Name())
+		if skip, err := skipHidden(bundle_index.bundle_dir, path, info); skip {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		p0 := filepath.Clean(path)
+		state, present := known[p0]
+		delete(known, p0)
+		if _, force := named[p0]; present && state == stateOf(info) && !force {
+			return nil
+		}
+		index_file(fs, new_bundle_index, p0, info, nil)
+		return nil
+	})
 
-		m2bi[name] = createBundleIndex(path, name)
-		//log.Println(fmt.Sprintf("bundle: %s, index: %s", path, m2bi[name]))
+	// Nicht mehr vorhandene Dateien entfernen
+	for path := range known {
+		remove_file(new_bundle_index, path)
 	}
 
-	return &Index{
-		bundle_root_dir: bundle_root_dir,
-		bundle_name_2_bundle_index: m2bi,
-	}	
+	return new_bundle_index.reverse()
 }
 
-// Baut einen neuen Index, der nur den BundleIndex, den Pfad 'modified_dir enthält,  neu berechnet
-func updateIndex(modified_dir string, index *Index) *Index {
+// isBelow prüft, ob 'path' gleich 'dir' ist oder unterhalb von 'dir' liegt
+func isBelow(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir + string(filepath.Separator))
+}
+
+func createIndex(fs storage.Storage, bundle_root_dir string) *Index {
 
-        //log.Println(fmt.Sprintf("updateIndex: modified_dir %s", modified_dir))
 	m2bi := make(map[string]*BundleIndex)
 
-	fileinfos, err := ioutil.ReadDir(index.bundle_root_dir)
+	cache := loadIndexCache(fs, bundle_root_dir)
+
+	fileinfos, err := fs.ReadDir(bundle_root_dir)
 	utils.Check(err)
 	
 	for _, file := range fileinfos {
@@ -179,55 +365,150 @@ func updateIndex(modified_dir string, index *Index) *Index {
 		if strings.HasPrefix(name , ".") {
 			continue
 		}
-		path := filepath.Join(index.bundle_root_dir, file.Name())
+		path := filepath.Join(bundle_root_dir, file.Name())
 
-		if strings.HasPrefix(filepath.Clean(modified_dir), filepath.Clean(path)) {
-			//log.Println(fmt.Sprintf("updateIndex: %s", filepath.Clean(path)))
-			m2bi[name] = updateBundleIndex(path, name, index.bundle_name_2_bundle_index[name])
-		} else {
-			m2bi[name] = index.bundle_name_2_bundle_index[name]
-		}
+		m2bi[name] = createBundleIndex(fs, path, name, cache.bundle(name))
 		//log.Println(fmt.Sprintf("bundle: %s, index: %s", path, m2bi[name]))
 	}
 
+	index := &Index{
+		fs: fs,
+		bundle_root_dir: bundle_root_dir,
+		bundle_name_2_bundle_index: m2bi,
+	}
+	saveIndexCache(index)
+	return index
+}
+
+// CreateIndex baut den Index aller Bundles unterhalb von 'bundle_root_dir'
+// im Storage 'fs'
+func CreateIndex(fs storage.Storage, bundle_root_dir string) *Index {
+	return createIndex(fs, bundle_root_dir)
+}
+
+// ProcessFile liefert den Pfad der Datei, die die Prozessdefinition mit der
+// Id 'process_definition_id' enthält
+func (index *Index) ProcessFile(process_definition_id string) (string, bool) {
+	for _, bundle_index := range index.bundle_name_2_bundle_index {
+		if _, present := (*bundle_index.uses_processes)[process_definition_id]; present {
+			return file_path(bundle_index.bundle_dir, process_definition_id), true
+		}
+	}
+	return "", false
+}
+
+// Process liest die Prozessdefinition mit der Id 'process_definition_id'.
+// Nicht indizierte oder nicht lesbare Prozesse werden nicht gefunden.
+func (index *Index) Process(process_definition_id string) (*processfile.Process, bool) {
+	path, found := index.ProcessFile(process_definition_id)
+	if !found {
+		return nil, false
+	}
+	content, err := index.fs.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	p, err := processfile.Parse(content)
+	if err != nil {
+		return nil, false
+	}
+	return p, true
+}
+
+// bundleName liefert den Namen des Bundles, in dem 'path' liegt. Pfade
+// außerhalb der Bundles und in versteckten Verzeichnissen gehören zu keinem
+// Bundle.
+func bundleName(bundle_root_dir string, path string) (string, bool) {
+	rel, err := filepath.Rel(bundle_root_dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	name := strings.Split(filepath.ToSlash(rel), "/")[0]
+	if strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	return name, true
+}
+
+// Baut einen neuen Index, der nur den BundleIndex, in dem der Pfad 'modified_path' liegt, aktualisiert
+func updateIndex(modified_path string, index *Index) *Index {
+	return updateIndexBelow(modified_path, map[string]struct{}{filepath.Clean(modified_path): e}, index)
+}
+
+// updateIndexBelow aktualisiert wie updateIndex die Dateien unterhalb von
+// 'modified_path'. Die Dateien in 'named' werden dabei immer gelesen (siehe
+// updateBundleIndex).
+func updateIndexBelow(modified_path string, named map[string]struct{}, index *Index) *Index {
+
+        //log.Println(fmt.Sprintf("updateIndex: modified_path %s", modified_path))
+	name, ok := bundleName(index.bundle_root_dir, modified_path)
+	if !ok {
+		return index
+	}
+
+	m2bi := make(map[string]*BundleIndex)
+	for n, bundle_index := range index.bundle_name_2_bundle_index {
+		m2bi[n] = bundle_index
+	}
+
+	bundle_dir := filepath.Join(index.bundle_root_dir, name)
+	if fi, err := index.fs.Stat(bundle_dir); err != nil || !fi.IsDir() {
+		delete(m2bi, name)
+	} else if bundle_index, present := m2bi[name]; present {
+		m2bi[name] = updateBundleIndex(index.fs, modified_path, named, bundle_index)
+	} else {
+		m2bi[name] = createBundleIndex(index.fs, bundle_dir, name, nil)
+	}
+
 	return &Index{
+		fs: index.fs,
 		bundle_root_dir: index.bundle_root_dir,
 		bundle_name_2_bundle_index: m2bi,
-	}	
+	}
 }
 
+// UpdateIndexForNewDir indiziert neu angelegte Dateien, Verzeichnisse und Bundles
 func UpdateIndexForNewDir() Adapter {
 	return func(h Handler) Handler {
-		return HandlerFunc(func(watcher *fsnotify.Watcher, event *fsnotify.Event, index *Index) *Index {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
+			new_index := index
 			if event.Op&fsnotify.Create == fsnotify.Create {
-				if fi, _ := os.Stat(event.Name); fi.IsDir() {
-					log.Println("UpdateIndexForNewDir: ", event.Name)
-					//filepath.Walk(event.Name, create_walkTreeFunction(watcher))
+				if _, err := index.fs.Stat(event.Name); err == nil {
+					//log.Println("UpdateIndexForNewDir: ", event.Name)
+					new_index = updateIndex(event.Name, index)
+					correctErrors(index, new_index)
 				}
 			}
-			return h.ServeWatcherEvent(watcher, event, index)  
+			return h.ServeWatcherEvent(watcher, event, new_index)  
 		})
 	}
 }
 
+// UpdateIndexForRemovedDir entfernt gelöschte oder umbenannte Dateien,
+// Verzeichnisse und Bundles aus dem Index
 func UpdateIndexForRemovedDir() Adapter {
 	return func(h Handler) Handler {
-		return HandlerFunc(func(watcher *fsnotify.Watcher, event *fsnotify.Event, index *Index) *Index {
-			if event.Op&fsnotify.Remove == fsnotify.Remove {
-				log.Println("UpdateIndexForRemovedDir: ", event.Name)
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
+			new_index := index
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				if _, err := index.fs.Stat(event.Name); os.IsNotExist(err) {
+					//log.Println("UpdateIndexForRemovedDir: ", event.Name)
+					new_index = updateIndex(event.Name, index)
+					correctErrors(index, new_index)
+				}
 			}
-			return h.ServeWatcherEvent(watcher, event, index)  
+			return h.ServeWatcherEvent(watcher, event, new_index)  
 		})
 	}
 }
 
 func UpdateIndexForModifiedDir() Adapter {
 	return func(h Handler) Handler {
-		return HandlerFunc(func(watcher *fsnotify.Watcher, event *fsnotify.Event, index *Index) *Index {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
 			new_index := index
 			if event.Op&fsnotify.Write == fsnotify.Write {
 				// fi, er := os.Stat(event.Name)
-				_, er := os.Stat(event.Name)
+				_, er := index.fs.Stat(event.Name)
 				if os.IsNotExist(er) {
 					//nothing to do
 				//} else if fi.IsDir() {
@@ -0,0 +1,50 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"context"
+	"path/filepath"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/layout"
+)
+
+// Layout ordnet die Aktivitäten einer Prozessdatei neu an. Nur die
+// node-graphics-info der Aktivitäten wird geändert.
+func (r *Resolver) Layout(ctx context.Context, args *struct {
+	Bundle_symbolic_name string
+	Path                 string
+}) (*fileResolver, error) {
+	if err := checkBundleName(args.Bundle_symbolic_name); err != nil {
+		return nil, err
+	}
+	if err := checkPath(args.Path); err != nil {
+		return nil, err
+	}
+
+	fs := pcontext.Storage(ctx)
+	bundle_dir := filepath.Join(pcontext.BundleRootDir(ctx), args.Bundle_symbolic_name)
+	path := filepath.Join(bundle_dir, args.Path)
+	fi, err := fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := layout.LayoutContent(content, layout.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.WriteFile(path, result, fi.Mode()); err != nil {
+		return nil, err
+	}
+
+	return &fileResolver{&file{
+		fs:         fs,
+		BundlePath: bundle_dir,
+		Path:       filepath.ToSlash(args.Path),
+		Name:       filepath.Base(args.Path),
+	}}, nil
+}
@@ -0,0 +1,94 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/frericksm/pride/storage"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Zeit, die nach einem Rename-Event auf das zugehörige Create-Event gewartet wird
+const MOVE_TIMEOUT = 250 * time.Millisecond
+
+// CorrelateMoves fasst ein Rename-Event und das unmittelbar folgende
+// Create-Event zu einer Verschiebung zusammen, wenn beide zueinander passen
+// (siehe isMove). Der Index wird dann für Quelle und Ziel in einem Schritt
+// aktualisiert, so dass die Verschiebung als solche erkannt wird (siehe
+// correctErrors). Beide Events werden nicht weitergereicht.
+//
+// Folgt kein passendes Create-Event innerhalb von MOVE_TIMEOUT, etwa weil das
+// Ziel außerhalb der Bundles liegt, wird das Rename-Event weitergereicht und
+// wie ein Löschen behandelt. Ein nicht passendes Create-Event wird danach
+// normal verarbeitet.
+func CorrelateMoves() Adapter {
+	var renamed *fsnotify.Event
+	var renamed_at time.Time
+
+	return func(h Handler) Handler {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
+			if renamed != nil {
+				if event.Op&fsnotify.Create == fsnotify.Create && isMove(index, renamed.Name, event.Name) {
+					from := renamed.Name
+					renamed = nil
+					watcherMetrics.Add("moves", 1)
+					log.Println(fmt.Sprintf("CorrelateMoves: %s -> %s", from, event.Name))
+
+					new_index := updateIndex(event.Name, updateIndex(from, index))
+					correctErrors(index, new_index)
+					return new_index
+				}
+				if !isTick(event) || time.Since(renamed_at) >= MOVE_TIMEOUT {
+					pending := renamed
+					renamed = nil
+					index = h.ServeWatcherEvent(watcher, pending, index)
+				}
+			}
+
+			if event.Op&fsnotify.Rename == fsnotify.Rename {
+				copied := *event
+				renamed, renamed_at = &copied, time.Now()
+				return index
+			}
+			return h.ServeWatcherEvent(watcher, event, index)
+		})
+	}
+}
+
+// isMove prüft, ob 'to' das Ziel einer Verschiebung von 'from' sein kann: der
+// Name ist gleich geblieben oder die Dateien unter 'to' haben denselben Inhalt
+// wie die im Index bekannten Dateien unter 'from'.
+func isMove(index *Index, from string, to string) bool {
+	if filepath.Base(from) == filepath.Base(to) {
+		return true
+	}
+	name, ok := bundleName(index.bundle_root_dir, from)
+	if !ok {
+		return false
+	}
+	bundle_index, present := index.bundle_name_2_bundle_index[name]
+	if !present {
+		return false
+	}
+	from = filepath.Clean(from)
+	found := false
+	for path, hash := range *bundle_index.path_contenthash {
+		if !isBelow(path, from) {
+			continue
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return false
+		}
+		content, err := index.fs.ReadFile(filepath.Join(to, rel))
+		if err != nil || sha256.Sum256(content) != hash {
+			return false
+		}
+		found = true
+	}
+	return found
+}
@@ -0,0 +1,432 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+)
+
+// Die Arten der Fundstellen einer Suche
+const (
+	SEARCH_FILE_NAME          = "FILE_NAME"
+	SEARCH_PROCESS_ID         = "PROCESS_ID"
+	SEARCH_PROCESS_NAME       = "PROCESS_NAME"
+	SEARCH_ACTIVITY_NAME      = "ACTIVITY_NAME"
+	SEARCH_IMPLEMENTATION_REF = "IMPLEMENTATION_REF"
+	SEARCH_FORMAL_PARAMETER   = "FORMAL_PARAMETER"
+	SEARCH_DATA_MAPPING       = "DATA_MAPPING"
+)
+
+var search_kinds = []string{
+	SEARCH_FILE_NAME,
+	SEARCH_PROCESS_ID,
+	SEARCH_PROCESS_NAME,
+	SEARCH_ACTIVITY_NAME,
+	SEARCH_IMPLEMENTATION_REF,
+	SEARCH_FORMAL_PARAMETER,
+	SEARCH_DATA_MAPPING,
+}
+
+// Anzahl der Zeichen vor und nach dem Suchbegriff in einem Snippet
+const snippet_radius = 30
+
+// searchEntry ist ein durchsuchbarer Text einer Datei
+type searchEntry struct {
+	kind string
+	// Das umgebende Element, z.B. die Aktivität eines Data-Mappings
+	context string
+	text    string
+}
+
+// searchIndex ist der invertierte Index über die Texte aller Dateien. Er wird
+// nicht verändert, sondern wie der Index bei jeder Änderung neu (und nur für
+// die geänderten Dateien) aufgebaut.
+type searchIndex struct {
+	// Hash des Inhalts, aus dem die Einträge einer Datei berechnet wurden
+	path_hash    map[string][32]byte
+	path_bundle  map[string]string
+	path_entries map[string][]searchEntry
+	// Kleingeschriebene Wörter je Datei
+	term_paths map[string]map[string]struct{}
+}
+
+// searchTerms zerlegt 'text' in kleingeschriebene Wörter aus Buchstaben und Ziffern
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchEntries liefert die durchsuchbaren Texte der Datei 'path'. Von
+// Prozessdateien, die nicht geparst werden können, wird nur der Name indiziert.
+func searchEntries(bundle_dir string, path string, content []byte) []searchEntry {
+	rel, err := filepath.Rel(bundle_dir, path)
+	if err != nil {
+		rel = path
+	}
+	entries := []searchEntry{{kind: SEARCH_FILE_NAME, context: filepath.ToSlash(rel), text: filepath.Base(path)}}
+	if !isProcessFile(path) {
+		return entries
+	}
+	p, err := processfile.Parse(content)
+	if err != nil {
+		return entries
+	}
+
+	entries = append(entries, searchEntry{kind: SEARCH_PROCESS_ID, text: process_definition_id(bundle_dir, path)})
+	if p.Name != "" {
+		entries = append(entries, searchEntry{kind: SEARCH_PROCESS_NAME, text: p.Name})
+	}
+	for _, f := range p.FormalParameters {
+		entries = append(entries, searchEntry{kind: SEARCH_FORMAL_PARAMETER, text: f.Name})
+	}
+	for _, a := range p.Activities {
+		if a.Name != "" {
+			entries = append(entries, searchEntry{kind: SEARCH_ACTIVITY_NAME, context: a.Id, text: a.Name})
+		}
+		if a.Body.ImplementationRefId != "" {
+			entries = append(entries, searchEntry{kind: SEARCH_IMPLEMENTATION_REF, context: a.Name, text: a.Body.ImplementationRefId})
+		}
+		for _, m := range a.Body.DataMappings {
+			text := strings.Join(strings.Fields(m.ActualParameter.Text()), " ")
+			if text != "" {
+				entries = append(entries, searchEntry{kind: SEARCH_DATA_MAPPING, context: a.Name + "/" + m.FormalParameter, text: text})
+			}
+		}
+	}
+	return entries
+}
+
+// updateSearchIndex baut den searchIndex zu 'index'. Nur die Dateien, deren
+// Inhalt sich gegenüber 'old' geändert hat, werden gelesen. Ist 'old' nil,
+// werden alle Dateien gelesen.
+func updateSearchIndex(fs storage.Storage, old *searchIndex, index *Index) *searchIndex {
+	if old == nil {
+		old = &searchIndex{
+			path_hash:    make(map[string][32]byte),
+			path_bundle:  make(map[string]string),
+			path_entries: make(map[string][]searchEntry),
+			term_paths:   make(map[string]map[string]struct{}),
+		}
+	}
+
+	s := &searchIndex{
+		path_hash:    make(map[string][32]byte),
+		path_bundle:  make(map[string]string),
+		path_entries: make(map[string][]searchEntry),
+		term_paths:   make(map[string]map[string]struct{}),
+	}
+	for term, paths := range old.term_paths {
+		s.term_paths[term] = paths
+	}
+
+	// Die Mengen von 'old' werden vor dem Ändern kopiert
+	copied := make(map[string]struct{})
+	paths_of := func(term string) map[string]struct{} {
+		if _, present := copied[term]; !present {
+			paths := make(map[string]struct{})
+			for path := range s.term_paths[term] {
+				paths[path] = e
+			}
+			s.term_paths[term] = paths
+			copied[term] = e
+		}
+		return s.term_paths[term]
+	}
+	remove := func(path string) {
+		for _, entry := range old.path_entries[path] {
+			for _, term := range searchTerms(entry.text) {
+				delete(paths_of(term), path)
+			}
+		}
+	}
+
+	for name, bundle_index := range index.bundle_name_2_bundle_index {
+		for path, hash := range *bundle_index.path_contenthash {
+			if old_hash, present := old.path_hash[path]; present && old_hash == hash && old.path_bundle[path] == name {
+				s.path_hash[path] = hash
+				s.path_bundle[path] = name
+				s.path_entries[path] = old.path_entries[path]
+				continue
+			}
+			content, err := fs.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			remove(path)
+			entries := searchEntries(bundle_index.bundle_dir, path, content)
+			s.path_hash[path] = hash
+			s.path_bundle[path] = name
+			s.path_entries[path] = entries
+			for _, entry := range entries {
+				for _, term := range searchTerms(entry.text) {
+					paths_of(term)[path] = e
+				}
+			}
+		}
+	}
+
+	// Gelöschte und nicht mehr lesbare Dateien entfernen
+	for path := range old.path_entries {
+		if _, present := s.path_entries[path]; !present {
+			remove(path)
+		}
+	}
+	for term := range copied {
+		if len(s.term_paths[term]) == 0 {
+			delete(s.term_paths, term)
+		}
+	}
+	return s
+}
+
+// UpdateSearchIndex hält den searchIndex der von der Adapter-Kette gelieferten
+// Indizes aktuell. Der Adapter muss daher der erste der Kette sein.
+func UpdateSearchIndex() Adapter {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
+			new_index := h.ServeWatcherEvent(watcher, event, index)
+			if new_index != index && new_index.search == nil {
+				new_index.search = updateSearchIndex(new_index.fs, index.search, new_index)
+			}
+			return new_index
+		})
+	}
+}
+
+// searchMatch ist eine Fundstelle in einer Datei
+type searchMatch struct {
+	entry   searchEntry
+	snippet string
+}
+
+// searchResult sind die Fundstellen einer Datei
+type searchResult struct {
+	bundle  string
+	path    string
+	matches []searchMatch
+}
+
+// snippet liefert den Ausschnitt von 'text' um das erste Vorkommen von 'term'
+func snippet(text string, term string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	start := 0
+	if i := strings.Index(string(lower), term); i > 0 {
+		start = utf8.RuneCountInString(string(lower)[:i])
+	}
+	end := start + utf8.RuneCountInString(term)
+
+	from, to := start-snippet_radius, end+snippet_radius
+	prefix, suffix := "...", "..."
+	if from <= 0 {
+		from, prefix = 0, ""
+	}
+	if to >= len(runes) {
+		to, suffix = len(runes), ""
+	}
+	return prefix + string(runes[from:to]) + suffix
+}
+
+// search sucht die Dateien, deren Texte jedes Wort von 'query' enthalten. Die
+// Wörter werden ohne Beachtung der Groß- und Kleinschreibung auch innerhalb
+// längerer Wörter gefunden, z.B. 'eintrag' in 'ProtokollEintragSchreiben'.
+// Leere 'bundles' oder 'kinds' schränken die Suche nicht ein.
+func (s *searchIndex) search(query string, bundles []string, kinds []string) []*searchResult {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []*searchResult{}
+	}
+	unique := make(map[string]struct{})
+	for _, t := range terms {
+		unique[t] = e
+	}
+	in_bundles := make(map[string]struct{})
+	for _, b := range bundles {
+		in_bundles[b] = e
+	}
+	in_kinds := make(map[string]struct{})
+	for _, k := range kinds {
+		in_kinds[k] = e
+	}
+
+	// Kandidaten über den invertierten Index: Dateien, die jedes Wort enthalten.
+	// Da die Wörter auch innerhalb der Terme gesucht werden, wird dafür je Wort
+	// das ganze Vokabular durchlaufen. Der Aufwand wächst also mit der Zahl
+	// der verschiedenen Terme, nicht mit der Größe der Dateien.
+	var candidates map[string]struct{}
+	for _, t := range terms {
+		found := make(map[string]struct{})
+		for term, paths := range s.term_paths {
+			if !strings.Contains(term, t) {
+				continue
+			}
+			for path := range paths {
+				if candidates == nil {
+					found[path] = e
+				} else if _, present := candidates[path]; present {
+					found[path] = e
+				}
+			}
+		}
+		candidates = found
+	}
+
+	results := make([]*searchResult, 0)
+	for path := range candidates {
+		bundle := s.path_bundle[path]
+		if _, present := in_bundles[bundle]; len(in_bundles) > 0 && !present {
+			continue
+		}
+		// Jedes Wort muss in einer Fundstelle der gewünschten Arten vorkommen
+		result := &searchResult{bundle: bundle, path: path}
+		found := make(map[string]struct{})
+		for _, entry := range s.path_entries[path] {
+			if _, present := in_kinds[entry.kind]; len(in_kinds) > 0 && !present {
+				continue
+			}
+			// Eine Fundstelle kann mehrere Wörter enthalten, der Ausschnitt
+			// zeigt das erste
+			lower := strings.ToLower(entry.text)
+			first := ""
+			for _, t := range terms {
+				if strings.Contains(lower, t) {
+					found[t] = e
+					if first == "" {
+						first = t
+					}
+				}
+			}
+			if first != "" {
+				result.matches = append(result.matches, searchMatch{entry: entry, snippet: snippet(entry.text, first)})
+			}
+		}
+		if len(found) == len(unique) {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].bundle != results[j].bundle {
+			return results[i].bundle < results[j].bundle
+		}
+		return results[i].path < results[j].path
+	})
+	return results
+}
+
+func (r *Resolver) Search(ctx context.Context, args *struct {
+	Query   string
+	Bundles *[]string
+	Kinds   *[]string
+}) ([]*searchResultResolver, error) {
+
+	if len(searchTerms(args.Query)) == 0 {
+		return nil, errors.New("A query cannot be empty")
+	}
+	var bundles, kinds []string
+	if args.Bundles != nil {
+		bundles = *args.Bundles
+	}
+	if args.Kinds != nil {
+		kinds = *args.Kinds
+		for _, k := range kinds {
+			if !searchKind(k) {
+				return nil, errors.New(fmt.Sprintf("Unknown kind '%s'. Expected one of %s", k, strings.Join(search_kinds, ", ")))
+			}
+		}
+	}
+
+	// Ohne veröffentlichten Index (außerhalb von serve) wird der searchIndex je
+	// Anfrage gebaut
+	index, _ := currentIndex(ctx)
+	s := index.search
+	if s == nil {
+		s = updateSearchIndex(index.fs, nil, index)
+	}
+
+	l := make([]*searchResultResolver, 0)
+	for _, result := range s.search(args.Query, bundles, kinds) {
+		bundle_path := filepath.Join(index.bundle_root_dir, result.bundle)
+		rel, err := filepath.Rel(bundle_path, result.path)
+		if err != nil {
+			continue
+		}
+		l = append(l, &searchResultResolver{
+			result: result,
+			file: &fileResolver{
+				&file{
+					fs:         index.fs,
+					BundlePath: bundle_path,
+					Name:       filepath.Base(rel),
+					Path:       filepath.ToSlash(rel),
+				},
+			},
+		})
+	}
+	return l, nil
+}
+
+func searchKind(kind string) bool {
+	for _, k := range search_kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+type searchResultResolver struct {
+	result *searchResult
+	file   *fileResolver
+}
+
+func (r *searchResultResolver) Bundle() string {
+	return r.result.bundle
+}
+
+func (r *searchResultResolver) File() *fileResolver {
+	return r.file
+}
+
+func (r *searchResultResolver) Matches() []*searchMatchResolver {
+	l := make([]*searchMatchResolver, 0)
+	for i := range r.result.matches {
+		l = append(l, &searchMatchResolver{&r.result.matches[i]})
+	}
+	return l
+}
+
+type searchMatchResolver struct {
+	m *searchMatch
+}
+
+func (r *searchMatchResolver) Kind() string {
+	return r.m.entry.kind
+}
+
+func (r *searchMatchResolver) Context() string {
+	return r.m.entry.context
+}
+
+func (r *searchMatchResolver) Text() string {
+	return r.m.entry.text
+}
+
+func (r *searchMatchResolver) Snippet() string {
+	return r.m.snippet
+}
@@ -0,0 +1,99 @@
+package bundle
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestSearch(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	storage.MkdirAll(fs, "/bundles/b2", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", content, 0644)
+	fs.WriteFile("/bundles/b2/readme.txt", nil, 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+	search := func(query string, kinds ...string) []*searchResultResolver {
+		args := &struct {
+			Query   string
+			Bundles *[]string
+			Kinds   *[]string
+		}{Query: query}
+		if len(kinds) > 0 {
+			args.Kinds = &kinds
+		}
+		results, err := (&Resolver{}).Search(ctx, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+
+	results := search("ProtokollEintragSchreiben", SEARCH_IMPLEMENTATION_REF)
+	if len(results) != 1 || results[0].Bundle() != "b1" || results[0].File().Path() != "de/michael/A1.process" {
+		t.Fatalf("Expected de/michael/A1.process, but was %v", results)
+	}
+	matches := results[0].Matches()
+	if len(matches) == 0 || matches[0].Kind() != SEARCH_IMPLEMENTATION_REF || !strings.Contains(matches[0].Snippet(), "ProtokollEintragSchreiben") {
+		t.Errorf("Expected match of the implementation ref, but was %v", matches)
+	}
+
+	if results := search("eintrag meldungstext"); len(results) != 1 {
+		t.Errorf("Expected 1 result for words inside longer words, but was %d", len(results))
+	}
+	// Alle Wörter in derselben Fundstelle
+	if results := search("task ProtokollEintragSchreiben", SEARCH_IMPLEMENTATION_REF); len(results) != 1 || len(results[0].Matches()) != 1 {
+		t.Errorf("Expected 1 result with 1 match for words in the same field, but was %v", results)
+	}
+	if results := search("readme"); len(results) != 1 || results[0].Bundle() != "b2" {
+		t.Errorf("Expected readme.txt, but was %v", results)
+	}
+	if results := search("meldungstext", SEARCH_ACTIVITY_NAME); len(results) != 0 {
+		t.Errorf("Expected no result for kind ACTIVITY_NAME, but was %d", len(results))
+	}
+	if _, err := (&Resolver{}).Search(ctx, &struct {
+		Query   string
+		Bundles *[]string
+		Kinds   *[]string
+	}{Query: "a", Kinds: &[]string{"FOO"}}); err == nil {
+		t.Errorf("Expected error for unknown kind")
+	}
+}
+
+func TestUpdateSearchIndex(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", content, 0644)
+	index := createIndex(fs, "/bundles")
+	index.search = updateSearchIndex(fs, nil, index)
+	old := index.search
+
+	handler := Adapt(&NoopHandler{}, UpdateSearchIndex(), UpdateIndexForModifiedDir())
+	changed := strings.Replace(string(content), "ProtokollEintragSchreiben", "ProtokollLoeschen", -1)
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", []byte(changed), 0644)
+	index = handler.ServeWatcherEvent(nil, &fsnotify.Event{Name: "/bundles/b1/de/michael/A1.process", Op: fsnotify.Write}, index)
+
+	if results := index.search.search("ProtokollLoeschen", nil, nil); len(results) != 1 {
+		t.Errorf("Expected 1 result after update, but was %d", len(results))
+	}
+	if results := index.search.search("ProtokollEintragSchreiben", nil, nil); len(results) != 0 {
+		t.Errorf("Expected no result after update, but was %d", len(results))
+	}
+	if results := old.search("ProtokollEintragSchreiben", nil, nil); len(results) != 1 {
+		t.Errorf("Expected old search index to be unchanged")
+	}
+}
@@ -0,0 +1,300 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+// TaskUsage ist der Aufruf eines Tasks (Activity mit implementation-type
+// TASK) in einem Prozess
+type TaskUsage struct {
+	// Die implementation-ref-id, der Name der Java-Klasse des Tasks
+	Task       string `json:"task"`
+	Bundle     string `json:"bundle"`
+	Process    string `json:"process"`
+	ActivityId string `json:"activityId"`
+	Activity   string `json:"activity"`
+	// Die formalen Parameter der Data-Mappings, in der Reihenfolge der Datei
+	Parameters []string `json:"parameters"`
+}
+
+func taskUsage(act processfile.Activity) TaskUsage {
+	parameters := make([]string, 0, len(act.Body.DataMappings))
+	for _, m := range act.Body.DataMappings {
+		parameters = append(parameters, m.FormalParameter)
+	}
+	return TaskUsage{
+		Task:       act.Body.ImplementationRefId,
+		ActivityId: act.Id,
+		Activity:   act.Name,
+		Parameters: parameters,
+	}
+}
+
+// setTaskUsages trägt die Aufrufe 'tasks' des Prozesses 'process_definition_id'
+// in 'bundle_index' ein
+func setTaskUsages(bundle_index *BundleIndex, process_definition_id string, tasks []TaskUsage) {
+	l := make([]TaskUsage, 0, len(tasks))
+	for _, t := range tasks {
+		t.Bundle = bundle_index.bundle_name
+		t.Process = process_definition_id
+		if t.Parameters == nil {
+			t.Parameters = []string{}
+		}
+		l = append(l, t)
+	}
+	(*bundle_index.uses_tasks)[process_definition_id] = l
+}
+
+// TaskUsages liefert die Aufrufe des Tasks 'task' oder, wenn 'task' leer ist,
+// aller Tasks. Sortiert wird nach Task, Bundle, Prozess und Aktivität.
+func (index *Index) TaskUsages(task string) []TaskUsage {
+	l := make([]TaskUsage, 0)
+	for _, bundle_index := range index.bundle_name_2_bundle_index {
+		for _, tasks := range *bundle_index.uses_tasks {
+			for _, t := range tasks {
+				if task == "" || t.Task == task {
+					l = append(l, t)
+				}
+			}
+		}
+	}
+	sort.Slice(l, func(i, j int) bool {
+		a, b := l[i], l[j]
+		if a.Task != b.Task {
+			return a.Task < b.Task
+		}
+		if a.Bundle != b.Bundle {
+			return a.Bundle < b.Bundle
+		}
+		if a.Process != b.Process {
+			return a.Process < b.Process
+		}
+		return a.ActivityId < b.ActivityId
+	})
+	return l
+}
+
+func (r *Resolver) TaskUsages(ctx context.Context, args *struct{ TaskId *string }) []*taskUsageResolver {
+	task := ""
+	if args.TaskId != nil {
+		task = *args.TaskId
+	}
+	index, _ := currentIndex(ctx)
+	l := make([]*taskUsageResolver, 0)
+	for _, t := range index.TaskUsages(task) {
+		l = append(l, &taskUsageResolver{t})
+	}
+	return l
+}
+
+type taskUsageResolver struct {
+	t TaskUsage
+}
+
+func (r *taskUsageResolver) Task() string {
+	return r.t.Task
+}
+
+func (r *taskUsageResolver) Bundle() string {
+	return r.t.Bundle
+}
+
+func (r *taskUsageResolver) Process() string {
+	return r.t.Process
+}
+
+func (r *taskUsageResolver) ActivityId() string {
+	return r.t.ActivityId
+}
+
+func (r *taskUsageResolver) Activity() string {
+	return r.t.Activity
+}
+
+func (r *taskUsageResolver) Parameters() []string {
+	return r.t.Parameters
+}
+
+// isTaskCatalog prüft, ob 'path' ein Task-Katalog des Bundles 'bundle_dir' ist
+func isTaskCatalog(bundle_dir string, path string) bool {
+	rel, err := filepath.Rel(bundle_dir, path)
+	return err == nil && processfile.IsTaskCatalog(rel)
+}
+
+// TaskCatalog liefert die zusammengefassten Task-Kataloge aller Bundles. Ist
+// ein Task mehrfach definiert, gilt die Definition des Bundles mit dem
+// kleinsten Namen.
+func (index *Index) TaskCatalog() *processfile.TaskCatalog {
+	names := make([]string, 0, len(index.bundle_name_2_bundle_index))
+	for name := range index.bundle_name_2_bundle_index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var catalogs []*processfile.TaskCatalog
+	for _, name := range names {
+		bundle_index := index.bundle_name_2_bundle_index[name]
+		paths := make([]string, 0, len(*bundle_index.path_catalog))
+		for path := range *bundle_index.path_catalog {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			catalogs = append(catalogs, (*bundle_index.path_catalog)[path])
+		}
+	}
+	return processfile.MergeTaskCatalogs(catalogs...)
+}
+
+// TaskDefinitions liefert die Tasks des Katalogs für die Vervollständigung in
+// Editoren: alle Tasks, deren Id oder Klassenname (ohne Package) mit 'prefix'
+// beginnt. Groß- und Kleinschreibung werden nicht unterschieden.
+func (r *Resolver) TaskDefinitions(ctx context.Context, args *struct{ Prefix *string }) []*taskDefinitionResolver {
+	prefix := ""
+	if args.Prefix != nil {
+		prefix = strings.ToLower(*args.Prefix)
+	}
+	index, _ := currentIndex(ctx)
+	l := make([]*taskDefinitionResolver, 0)
+	for _, t := range index.TaskCatalog().Tasks {
+		id := strings.ToLower(t.Id)
+		if strings.HasPrefix(id, prefix) || strings.HasPrefix(id[strings.LastIndex(id, ".")+1:], prefix) {
+			l = append(l, &taskDefinitionResolver{t})
+		}
+	}
+	return l
+}
+
+// readProcessFile liest die Prozessdatei 'path' des Bundles 'bundle_name' aus
+// dem Storage von 'index'
+func readProcessFile(index *Index, bundle_name string, path string) (*processfile.Process, error) {
+	if err := checkBundleName(bundle_name); err != nil {
+		return nil, err
+	}
+	if err := checkPath(path); err != nil {
+		return nil, err
+	}
+	content, err := index.fs.ReadFile(filepath.Join(index.bundle_root_dir, bundle_name, path))
+	if err != nil {
+		return nil, err
+	}
+	return processfile.Parse(content)
+}
+
+func problemResolvers(problems []processfile.Problem) []*problemResolver {
+	l := make([]*problemResolver, 0)
+	for _, problem := range problems {
+		l = append(l, &problemResolver{problem})
+	}
+	return l
+}
+
+// TaskProblems prüft die Data-Mappings der TASK-Aktivitäten einer
+// Prozessdatei gegen den Task-Katalog
+func (r *Resolver) TaskProblems(ctx context.Context, args *struct {
+	Bundle_symbolic_name string
+	Path                 string
+}) ([]*problemResolver, error) {
+	index, _ := currentIndex(ctx)
+	p, err := readProcessFile(index, args.Bundle_symbolic_name, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	return problemResolvers(processfile.ValidateTasks(p, index.TaskCatalog())), nil
+}
+
+// SubFlowProblems prüft die Data-Mappings der SUB_FLOW-Aktivitäten einer
+// Prozessdatei gegen die formalen Parameter der aufgerufenen Prozesse
+func (r *Resolver) SubFlowProblems(ctx context.Context, args *struct {
+	Bundle_symbolic_name string
+	Path                 string
+}) ([]*problemResolver, error) {
+	index, _ := currentIndex(ctx)
+	p, err := readProcessFile(index, args.Bundle_symbolic_name, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	return problemResolvers(processfile.ValidateSubFlows(p, index.Process)), nil
+}
+
+type taskDefinitionResolver struct {
+	t processfile.TaskDefinition
+}
+
+func (r *taskDefinitionResolver) Id() string {
+	return r.t.Id
+}
+
+func (r *taskDefinitionResolver) Description() string {
+	return r.t.Description
+}
+
+func (r *taskDefinitionResolver) Parameters() []*taskParameterResolver {
+	l := make([]*taskParameterResolver, 0)
+	for _, f := range r.t.Parameters {
+		l = append(l, &taskParameterResolver{f})
+	}
+	return l
+}
+
+type taskParameterResolver struct {
+	f processfile.TaskParameter
+}
+
+func (r *taskParameterResolver) Name() string {
+	return r.f.Name
+}
+
+func (r *taskParameterResolver) Direction() string {
+	return r.f.Direction
+}
+
+func (r *taskParameterResolver) Required() bool {
+	return r.f.Required
+}
+
+func (r *taskParameterResolver) Type() string {
+	return r.f.Type
+}
+
+func (r *taskParameterResolver) Description() string {
+	return r.f.Description
+}
+
+type problemResolver struct {
+	p processfile.Problem
+}
+
+func (r *problemResolver) ActivityId() string {
+	return r.p.ActivityId
+}
+
+func (r *problemResolver) Activity() string {
+	return r.p.Activity
+}
+
+func (r *problemResolver) Element() string {
+	return r.p.Element
+}
+
+func (r *problemResolver) Id() string {
+	return r.p.Id
+}
+
+func (r *problemResolver) Line() int32 {
+	return int32(r.p.Line)
+}
+
+func (r *problemResolver) Column() int32 {
+	return int32(r.p.Column)
+}
+
+func (r *problemResolver) Message() string {
+	return r.p.Message
+}
@@ -0,0 +1,115 @@
+package bundle
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestTaskUsages(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", content, 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+
+	task := "de.fi.prosupport.task.ProtokollEintragSchreiben"
+	usages := (&Resolver{}).TaskUsages(ctx, &struct{ TaskId *string }{&task})
+	if len(usages) != 1 {
+		t.Fatalf("Expected 1 usage, but was %d", len(usages))
+	}
+	u := usages[0]
+	if u.Bundle() != "b1" || u.Process() != "de.michael.A1" || u.Activity() != "Protokoll" {
+		t.Errorf("Expected usage by activity Protokoll of de.michael.A1, but was %v", u.t)
+	}
+	if p := u.Parameters(); len(p) != 3 || p[0] != "kategorie" || p[2] != "eintrag" {
+		t.Errorf("Expected parameters kategorie, protokollId and eintrag, but was %v", p)
+	}
+
+	// Aus dem Cache gelesene Aufrufe
+	index := createIndex(fs, "/bundles")
+	if usages := index.TaskUsages(task); len(usages) != 1 || len(usages[0].Parameters) != 3 {
+		t.Errorf("Expected 1 usage from index cache, but was %v", usages)
+	}
+	if usages := index.TaskUsages("unknown"); len(usages) != 0 {
+		t.Errorf("Expected no usage, but was %v", usages)
+	}
+}
+
+func TestTaskCatalog(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	storage.MkdirAll(fs, "/bundles/b1/META-INF", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", content, 0644)
+	fs.WriteFile("/bundles/b1/META-INF/tasks.json", []byte(`{"tasks": [
+		{"id": "de.fi.prosupport.task.ProtokollEintragSchreiben", "parameters": [{"name": "kategorie"}, {"name": "text", "required": true}]},
+		{"id": "de.fi.prosupport.task.MailSenden"}]}`), 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+	r := &Resolver{}
+
+	prefix := "protokoll"
+	tasks := r.TaskDefinitions(ctx, &struct{ Prefix *string }{&prefix})
+	if len(tasks) != 1 || tasks[0].Id() != "de.fi.prosupport.task.ProtokollEintragSchreiben" || len(tasks[0].Parameters()) != 2 {
+		t.Fatalf("Expected task ProtokollEintragSchreiben, but was %v", tasks)
+	}
+	if tasks := r.TaskDefinitions(ctx, &struct{ Prefix *string }{}); len(tasks) != 2 {
+		t.Errorf("Expected 2 tasks, but was %d", len(tasks))
+	}
+
+	problems, err := r.TaskProblems(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+	}{"b1", "de/michael/A1.process"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range problems {
+		ids = append(ids, p.Id())
+	}
+	if len(ids) != 3 || ids[0] != "protokollId" || ids[1] != "eintrag" || ids[2] != "text" {
+		t.Errorf("Expected problems for protokollId, eintrag and text, but was %v", ids)
+	}
+
+	fs.WriteFile("/bundles/b1/META-INF/tasks.json", []byte(`{"tasks": [`), 0644)
+	index := createIndex(fs, "/bundles")
+	if len(index.TaskCatalog().Tasks) != 0 || len(index.errors()) != 1 {
+		t.Errorf("Expected invalid catalog to be reported as index error, but was %v", index.errors())
+	}
+}
+
+func TestSubFlowProblems(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	storage.MkdirAll(fs, "/bundles/b2/version400/schufa026201504162opdvversion/haupt_schufa_026", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", content, 0644)
+	callee := strings.Replace(string(content), `name="Meldungstext" required="false"`, `name="Meldungstext" required="true"`, 1)
+	fs.WriteFile("/bundles/b2/version400/schufa026201504162opdvversion/haupt_schufa_026/Haupt_Schufa_026.process", []byte(callee), 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+
+	problems, err := (&Resolver{}).SubFlowProblems(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+	}{"b1", "de/michael/A1.process"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Id() != "Meldungstext" || problems[0].Activity() != "A1" {
+		t.Errorf("Expected missing parameter Meldungstext, but was %v", problems)
+	}
+}
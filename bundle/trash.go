@@ -0,0 +1,259 @@
+// Package bundle provides a schema and resolver for bundle remote bundle management.
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+// Name des Papierkorbs im Bundle-Root-Verzeichnis. Als verstecktes
+// Verzeichnis ist er weder ein Bundle noch über die Resolver erreichbar.
+//
+// Jedes gelöschte Bundle, Verzeichnis oder jede gelöschte Datei liegt in einem
+// eigenen Unterverzeichnis <id> als 'item' zusammen mit der Beschreibung
+// 'meta.json'.
+const TRASH_DIR = ".trash"
+
+const trash_item_name = "item"
+
+const trash_meta_file = "meta.json"
+
+// trashItem beschreibt einen gelöschten Eintrag. Path ist relativ zum Bundle,
+// für ein gelöschtes Bundle "/".
+type trashItem struct {
+	Id      string    `json:"-"`
+	Bundle  string    `json:"bundle"`
+	Path    string    `json:"path"`
+	IsDir   bool      `json:"isDir"`
+	Deleted time.Time `json:"deleted"`
+}
+
+var trashMu sync.Mutex
+
+var trash_seq int64
+
+func trashDir(bundle_root_dir string) string {
+	return filepath.Join(bundle_root_dir, TRASH_DIR)
+}
+
+// moveToTrash verschiebt 'path' aus dem Bundle 'bundle_name' in den Papierkorb
+func moveToTrash(fs storage.Storage, bundle_root_dir string, bundle_name string, path string, is_dir bool) error {
+	trashMu.Lock()
+	defer trashMu.Unlock()
+
+	now := time.Now().UTC()
+	trash_seq++
+	id := fmt.Sprintf("%s-%d", now.Format("20060102T150405.000000000"), trash_seq)
+	entry := filepath.Join(trashDir(bundle_root_dir), id)
+
+	meta, err := json.Marshal(&trashItem{Bundle: bundle_name, Path: path, IsDir: is_dir, Deleted: now})
+	if err != nil {
+		return err
+	}
+	if err := storage.MkdirAll(fs, entry, 0755); err != nil {
+		return err
+	}
+	if err := fs.WriteFile(filepath.Join(entry, trash_meta_file), meta, 0644); err != nil {
+		fs.RemoveAll(entry)
+		return err
+	}
+	if err := fs.Rename(filepath.Join(bundle_root_dir, bundle_name, path), filepath.Join(entry, trash_item_name)); err != nil {
+		fs.RemoveAll(entry)
+		return err
+	}
+	return nil
+}
+
+func readTrashItem(fs storage.Storage, bundle_root_dir string, id string) (*trashItem, error) {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return nil, errors.New(fmt.Sprintf("Invalid trash id '%s'", id))
+	}
+	content, err := fs.ReadFile(filepath.Join(trashDir(bundle_root_dir), id, trash_meta_file))
+	if os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("Trash item '%s' does not exist", id))
+	} else if err != nil {
+		return nil, err
+	}
+	var item trashItem
+	if err := json.Unmarshal(content, &item); err != nil {
+		return nil, err
+	}
+	item.Id = id
+	return &item, nil
+}
+
+// listTrash liefert die Einträge des Papierkorbs, zuletzt gelöschte zuerst
+func listTrash(fs storage.Storage, bundle_root_dir string) ([]*trashItem, error) {
+	fileinfos, err := fs.ReadDir(trashDir(bundle_root_dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var l []*trashItem
+	for _, fi := range fileinfos {
+		item, err := readTrashItem(fs, bundle_root_dir, fi.Name())
+		if err != nil {
+			log.Println("listTrash: ", err)
+			continue
+		}
+		l = append(l, item)
+	}
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Deleted.After(l[j].Deleted) })
+	return l, nil
+}
+
+// restoreFromTrash verschiebt den Eintrag 'id' an seinen ursprünglichen Ort
+// zurück. Fehlende Verzeichnisse werden angelegt, vorhandene Dateien nicht
+// überschrieben.
+func restoreFromTrash(fs storage.Storage, bundle_root_dir string, id string) error {
+	trashMu.Lock()
+	defer trashMu.Unlock()
+
+	item, err := readTrashItem(fs, bundle_root_dir, id)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(bundle_root_dir, item.Bundle, item.Path)
+	if _, err := fs.Stat(target); err == nil {
+		return errors.New(fmt.Sprintf("Cannot restore '%s': '%s' already exists", id, filepath.ToSlash(filepath.Join(item.Bundle, item.Path))))
+	}
+	if err := storage.MkdirAll(fs, filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	entry := filepath.Join(trashDir(bundle_root_dir), id)
+	if err := fs.Rename(filepath.Join(entry, trash_item_name), target); err != nil {
+		return err
+	}
+	return fs.RemoveAll(entry)
+}
+
+// purgeTrash löscht die Einträge des Papierkorbs, für die 'expired' gilt,
+// endgültig und liefert ihre Anzahl
+func purgeTrash(fs storage.Storage, bundle_root_dir string, expired func(*trashItem) bool) (int, error) {
+	items, err := listTrash(fs, bundle_root_dir)
+	if err != nil {
+		return 0, err
+	}
+
+	trashMu.Lock()
+	defer trashMu.Unlock()
+
+	n := 0
+	for _, item := range items {
+		if !expired(item) {
+			continue
+		}
+		if err := fs.RemoveAll(filepath.Join(trashDir(bundle_root_dir), item.Id)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// PurgeExpiredTrash löscht die Einträge des Papierkorbs endgültig, die vor
+// mehr als 'retention' gelöscht wurden
+func PurgeExpiredTrash(fs storage.Storage, bundle_root_dir string, retention time.Duration) (int, error) {
+	deadline := time.Now().Add(-retention)
+	return purgeTrash(fs, bundle_root_dir, func(item *trashItem) bool {
+		return item.Deleted.Before(deadline)
+	})
+}
+
+// StartTrashRetention leert den Papierkorb stündlich um die Einträge, die
+// älter als 'retention' sind. Bei 0 bleiben die Einträge erhalten, bis sie
+// mit 'purge' gelöscht werden.
+func StartTrashRetention(fs storage.Storage, bundle_root_dir string, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	purge := func() {
+		n, err := PurgeExpiredTrash(fs, bundle_root_dir, retention)
+		if err != nil {
+			log.Println("StartTrashRetention: ", err)
+		} else if n > 0 {
+			log.Println(fmt.Sprintf("StartTrashRetention: %d Einträge endgültig gelöscht", n))
+		}
+	}
+	purge()
+	go func() {
+		for range time.NewTicker(time.Hour).C {
+			purge()
+		}
+	}()
+}
+
+func (r *Resolver) Trash(ctx context.Context, args *struct{ Bundle_symbolic_name *string }) ([]*trashItemResolver, error) {
+	items, err := listTrash(pcontext.Storage(ctx), pcontext.BundleRootDir(ctx))
+	if err != nil {
+		return nil, err
+	}
+	l := make([]*trashItemResolver, 0)
+	for _, item := range items {
+		if args.Bundle_symbolic_name != nil && *args.Bundle_symbolic_name != item.Bundle {
+			continue
+		}
+		l = append(l, &trashItemResolver{item})
+	}
+	return l, nil
+}
+
+func (r *Resolver) Restore(ctx context.Context, args *struct{ Id string }) (bool, error) {
+	if err := restoreFromTrash(pcontext.Storage(ctx), pcontext.BundleRootDir(ctx), args.Id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *Resolver) Purge(ctx context.Context, args *struct{ Id *string }) (int32, error) {
+	fs := pcontext.Storage(ctx)
+	bundle_root_dir := pcontext.BundleRootDir(ctx)
+
+	if args.Id != nil {
+		if _, err := readTrashItem(fs, bundle_root_dir, *args.Id); err != nil {
+			return 0, err
+		}
+	}
+	n, err := purgeTrash(fs, bundle_root_dir, func(item *trashItem) bool {
+		return args.Id == nil || item.Id == *args.Id
+	})
+	return int32(n), err
+}
+
+type trashItemResolver struct {
+	t *trashItem
+}
+
+func (r *trashItemResolver) Id() string {
+	return r.t.Id
+}
+
+func (r *trashItemResolver) Bundle() string {
+	return r.t.Bundle
+}
+
+func (r *trashItemResolver) Path() string {
+	return r.t.Path
+}
+
+func (r *trashItemResolver) IsDir() bool {
+	return r.t.IsDir
+}
+
+func (r *trashItemResolver) Deleted() string {
+	return r.t.Deleted.Format(time.RFC3339)
+}
@@ -0,0 +1,100 @@
+package bundle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestTrash(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", []byte("<process/>"), 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+	r := &Resolver{}
+
+	if _, err := r.DeleteFile(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+	}{"b1", "de/michael/A1.process"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/bundles/b1/de/michael/A1.process"); err == nil {
+		t.Errorf("Expected A1.process to be moved to the trash")
+	}
+	if _, err := r.DeleteBundle(ctx, &struct{ Bundle_symbolic_name string }{"b1"}); err != nil {
+		t.Fatal(err)
+	}
+	if bundles := r.AllBundles(ctx); len(bundles) != 0 {
+		t.Errorf("Expected no bundles, but was %v", bundles)
+	}
+
+	items, err := r.Trash(ctx, &struct{ Bundle_symbolic_name *string }{nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Path() != "/" || items[1].Path() != "de/michael/A1.process" {
+		t.Fatalf("Unexpected trash %v", items)
+	}
+
+	// Erst das Bundle, dann die Datei wiederherstellen
+	for _, item := range items {
+		if _, err := r.Restore(ctx, &struct{ Id string }{item.Id()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err := fs.ReadFile("/bundles/b1/de/michael/A1.process")
+	if err != nil || string(content) != "<process/>" {
+		t.Errorf("Expected restored A1.process, but was %s %v", content, err)
+	}
+	if _, err := r.Restore(ctx, &struct{ Id string }{items[0].Id()}); err == nil {
+		t.Errorf("Expected error restoring a purged item")
+	}
+
+	r.DeleteDir(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+	}{"b1", "de"})
+	if n, _ := PurgeExpiredTrash(fs, "/bundles", time.Hour); n != 0 {
+		t.Errorf("Expected no expired items, but was %d", n)
+	}
+	if n, _ := r.Purge(ctx, &struct{ Id *string }{nil}); n != 1 {
+		t.Errorf("Expected 1 purged item, but was %d", n)
+	}
+	if items, _ := listTrash(fs, "/bundles"); len(items) != 0 {
+		t.Errorf("Expected empty trash, but was %v", items)
+	}
+}
+
+func TestTrashNotReachable(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1", 0755)
+	fs.WriteFile("/bundles/b1/a.txt", nil, 0644)
+	ctx := pcontext.WithStorage(context.Background(), "/bundles", fs)
+	r := &Resolver{}
+	if _, err := r.DeleteFile(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+	}{"b1", "a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.DeleteBundle(ctx, &struct{ Bundle_symbolic_name string }{TRASH_DIR}); err == nil {
+		t.Errorf("Expected error deleting the trash")
+	}
+	if _, err := r.CreateBundle(ctx, &struct{ Bundle_symbolic_name string }{TRASH_DIR}); err == nil {
+		t.Errorf("Expected error creating a bundle named %s", TRASH_DIR)
+	}
+	if _, err := r.DeleteFile(ctx, &struct {
+		Bundle_symbolic_name string
+		Path                 string
+	}{TRASH_DIR, "a.txt"}); err == nil {
+		t.Errorf("Expected error deleting a file in the trash")
+	}
+	if items, _ := listTrash(fs, "/bundles"); len(items) != 1 {
+		t.Errorf("Expected unchanged trash, but was %v", items)
+	}
+}
@@ -2,13 +2,16 @@
 package bundle
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"time"
+	"github.com/frericksm/pride/storage"
 	"github.com/fsnotify/fsnotify"
 )
 
-func watcherWalkTreeFunction(watcher *fsnotify.Watcher) func(path string, info os.FileInfo, err error) error {
+func watcherWalkTreeFunction(watcher storage.Watcher) func(path string, info os.FileInfo, err error) error {
 	return func(path string, info os.FileInfo, err error) error {
 		
 		if err != nil {
@@ -16,8 +19,7 @@ func watcherWalkTreeFunction(watcher *fsnotify.Watcher) func(path string, info o
 		}
 		err = watcher.Add(path)
 		if err != nil {
-			log.Fatal(err)
-			return err
+			log.Println("watcherWalkTreeFunction: ", err)
 		}
 		
 		return nil;
@@ -26,7 +28,7 @@ func watcherWalkTreeFunction(watcher *fsnotify.Watcher) func(path string, info o
 
 
 type Handler interface {
-        ServeWatcherEvent(*fsnotify.Watcher, *fsnotify.Event, *Index) *Index
+        ServeWatcherEvent(storage.Watcher, *fsnotify.Event, *Index) *Index
 }
 
 type Adapter func(Handler) Handler
@@ -39,22 +41,22 @@ func Adapt(h Handler, adapters ...Adapter) Handler {
 	return h
 }
 
-type HandlerFunc func(*fsnotify.Watcher, *fsnotify.Event, *Index) *Index
+type HandlerFunc func(storage.Watcher, *fsnotify.Event, *Index) *Index
 
-func (f HandlerFunc) ServeWatcherEvent(watcher *fsnotify.Watcher, event *fsnotify.Event, index *Index) *Index {
+func (f HandlerFunc) ServeWatcherEvent(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
   	return f(watcher, event, index)
 }
 
 func UpdateWatcher() Adapter {
 	return func(h Handler) Handler {
-		return HandlerFunc(func(watcher *fsnotify.Watcher, event *fsnotify.Event, index *Index) *Index {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
 			if event.Op&fsnotify.Create == fsnotify.Create {
 				log.Println("UpdateWatcher: add ", event.Name)
 
-				fi, err := os.Stat(event.Name)
-				if !os.IsNotExist(err) && fi.IsDir() {
+				fi, err := index.fs.Stat(event.Name)
+				if err == nil && fi.IsDir() {
 					//log.Println("UpdateWatcher: add ", event.Name)
-					filepath.Walk(event.Name, watcherWalkTreeFunction(watcher))
+					storage.Walk(index.fs, event.Name, watcherWalkTreeFunction(watcher))
 				}
 			} else if event.Op&fsnotify.Rename == fsnotify.Rename {
 				//log.Println("UpdateWatcher: remove ", event.Name)
@@ -73,7 +75,7 @@ func UpdateWatcher() Adapter {
 
 func LogEvent() Adapter {
 	return func(h Handler) Handler {
-		return HandlerFunc(func(watcher *fsnotify.Watcher, event *fsnotify.Event, index *Index) *Index {
+		return HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
 			log.Println("Event: ", event)
 			return h.ServeWatcherEvent(watcher, event, index)  
 		})
@@ -83,34 +85,146 @@ func LogEvent() Adapter {
 
 type NoopHandler struct {}
 
-func (h *NoopHandler) ServeWatcherEvent(watcher *fsnotify.Watcher, event *fsnotify.Event, index *Index) *Index {
+func (h *NoopHandler) ServeWatcherEvent(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
 	return index
 }
 
 
-func StartWatching(watcher *fsnotify.Watcher, bundleRootDir string) {
-
-	index := createIndex(bundleRootDir)
-
+// WatchOptions steuert die Verarbeitung der Events in StartWatching
+type WatchOptions struct {
+	// FormatOnSave schreibt gespeicherte Prozessdateien in kanonischer Form zurück
+	FormatOnSave bool
+	// QuietPeriod ist die Zeit ohne weitere Events eines Bundles, nach der
+	// die gesammelten Events des Bundles verarbeitet werden. 0 verarbeitet
+	// jedes Event sofort.
+	QuietPeriod time.Duration
+}
 
-	handler := Adapt(&NoopHandler{},
+// eventHandler liefert die Adapter-Kette für 'options' und das Intervall, in
+// dem tickEvent durch die Kette gereicht wird
+func eventHandler(options WatchOptions) (Handler, time.Duration) {
+	adapters := []Adapter{
 //		LogEvent(),
+		UpdateSearchIndex(),
 		UpdateWatcher(), 
-//		UpdateIndexForNewDir(), 
-//		UpdateIndexForRemovedDir(), 
+	}
+	if options.FormatOnSave {
+		adapters = append(adapters, FormatOnSave())
+	}
+
+	interval := MOVE_TIMEOUT / 2
+	if options.QuietPeriod > 0 {
+		adapters = append(adapters, Coalesce(options.QuietPeriod))
+		if options.QuietPeriod/2 < interval {
+			interval = options.QuietPeriod / 2
+		}
+	}
+	adapters = append(adapters,
+		CorrelateMoves(),
+		UpdateIndexForNewDir(), 
+		UpdateIndexForRemovedDir(), 
 		UpdateIndexForModifiedDir(), )
 
+	return Adapt(&NoopHandler{}, adapters...), interval
+}
+
+// serveEvent reicht 'event' durch die Adapter-Kette. Eine Panic beim
+// Verarbeiten beendet nicht den Server: sie wird protokolliert und der Index
+// bleibt unverändert.
+func serveEvent(handler Handler, watcher storage.Watcher, event *fsnotify.Event, index *Index) (new_index *Index) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("serveEvent: ", event, r)
+			new_index = index
+		}
+	}()
+	return handler.ServeWatcherEvent(watcher, event, index)
+}
+
+// StartWatching baut den Index der Bundles in 'fs' und hält ihn mit den
+// Events eines Watchers von 'fs' aktuell. Der Watcher wird geliefert, damit
+// er geschlossen werden kann.
+func StartWatching(fs storage.Storage, bundleRootDir string, options WatchOptions) (storage.Watcher, error) {
+
+	watcher, err := fs.Watch()
+	if err != nil {
+		return nil, err
+	}
+
+	index := createIndex(fs, bundleRootDir)
+	index.search = updateSearchIndex(fs, nil, index)
+	publishIndex(index, true)
+
+	handler, interval := eventHandler(options)
+	tick := time.NewTicker(interval).C
+
+	// Der Cache wird erst gespeichert, wenn sich der Index für
+	// INDEX_CACHE_DELAY nicht geändert hat, nicht bei jedem Event
 	go func() {
+		var changed_at time.Time
+		unsaved := false
 		for {
+			var new_index *Index
 			select {
-			case event := <-watcher.Events:
+			case event := <-watcher.Events():
 				//log.Println("event:", event)
-				index = handler.ServeWatcherEvent(watcher, &event, index)
-			case err := <-watcher.Errors:
+				new_index = serveEvent(handler, watcher, &event, index)
+			case <-tick:
+				new_index = serveEvent(handler, watcher, &tickEvent, index)
+				if unsaved && time.Since(changed_at) >= INDEX_CACHE_DELAY {
+					saveIndexCache(index)
+					unsaved = false
+				}
+			case err := <-watcher.Errors():
 				log.Println("error:", err)
+				continue
+			}
+			if new_index != index {
+				index = new_index
+				publishIndex(index, true)
+				changed_at, unsaved = time.Now(), true
 			}
 		}
 	}()
 
-	filepath.Walk(bundleRootDir, watcherWalkTreeFunction(watcher))
+	storage.Walk(fs, bundleRootDir, watcherWalkTreeFunction(watcher))
+	return watcher, nil
+}
+
+// buildIndex baut den Index der Bundles in 'fs' samt Suchindex. Eine Panic
+// beim Indizieren wird als Fehler geliefert.
+func buildIndex(fs storage.Storage, bundleRootDir string) (index *Index, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			index, err = nil, errors.New(fmt.Sprintf("Index von '%s' nicht gebaut: %v", bundleRootDir, r))
+		}
+	}()
+	index = createIndex(fs, bundleRootDir)
+	index.search = updateSearchIndex(fs, nil, index)
+	return index, nil
+}
+
+// StartRefreshing baut den Index der Bundles in 'fs' einmal und veröffentlicht
+// ihn, ohne die Bundles zu beobachten (serve --watch=none). Bei 'interval' > 0
+// wird der Index in diesem Abstand neu gebaut und veröffentlicht, bei 0 nie.
+func StartRefreshing(fs storage.Storage, bundleRootDir string, interval time.Duration) error {
+	index, err := buildIndex(fs, bundleRootDir)
+	if err != nil {
+		return err
+	}
+	publishIndex(index, false)
+	if interval <= 0 {
+		return nil
+	}
+	go func() {
+		for range time.NewTicker(interval).C {
+			index, err := buildIndex(fs, bundleRootDir)
+			if err != nil {
+				log.Println("StartRefreshing: ", err)
+				continue
+			}
+			publishIndex(index, false)
+		}
+	}()
+	return nil
 }
@@ -0,0 +1,296 @@
+package bundle
+
+import (
+	"expvar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/frericksm/pride/storage"
+)
+
+func writeProcess(t *testing.T, path string) {
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tempRoot(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "pride-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root, func() { os.RemoveAll(root) }
+}
+
+func newWatcher(t *testing.T) storage.Watcher {
+	watcher, err := storage.OS{}.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return watcher
+}
+
+func expectProcess(t *testing.T, index *Index, id string, expected bool) {
+	if _, present := index.ProcessFile(id); present != expected {
+		t.Errorf("Expected process %s present=%v in index", id, expected)
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	root, cleanup := tempRoot(t)
+	defer cleanup()
+	watcher := newWatcher(t)
+	defer watcher.Close()
+
+	writeProcess(t, filepath.Join(root, "b1", "de", "michael", "A1.process"))
+	index := createIndex(storage.OS{}, root)
+	expectProcess(t, index, "de.michael.A1", true)
+
+	handler, _ := eventHandler(WatchOptions{})
+	serve := func(op fsnotify.Op, path string) {
+		index = handler.ServeWatcherEvent(watcher, &fsnotify.Event{Name: path, Op: op}, index)
+	}
+
+	// Neues Bundle
+	writeProcess(t, filepath.Join(root, "b2", "de", "x", "B.process"))
+	serve(fsnotify.Create, filepath.Join(root, "b2"))
+	if _, present := index.bundle_name_2_bundle_index["b2"]; !present {
+		t.Fatalf("Expected bundle b2 in index")
+	}
+	expectProcess(t, index, "de.x.B", true)
+
+	// Verschobenes Verzeichnis
+	from := filepath.Join(root, "b1", "de", "michael")
+	to := filepath.Join(root, "b1", "de", "moved")
+	if err := os.Rename(from, to); err != nil {
+		t.Fatal(err)
+	}
+	serve(fsnotify.Rename, from)
+	expectProcess(t, index, "de.michael.A1", true)
+	serve(fsnotify.Create, to)
+	expectProcess(t, index, "de.michael.A1", false)
+	expectProcess(t, index, "de.moved.A1", true)
+
+	// Gelöschte Datei
+	if err := os.Remove(filepath.Join(root, "b2", "de", "x", "B.process")); err != nil {
+		t.Fatal(err)
+	}
+	serve(fsnotify.Remove, filepath.Join(root, "b2", "de", "x", "B.process"))
+	expectProcess(t, index, "de.x.B", false)
+
+	// Bundle aus dem Root-Verzeichnis hinaus verschoben: ohne Create-Event
+	// wird das Rename nach MOVE_TIMEOUT wie ein Löschen behandelt
+	outside, cleanup_outside := tempRoot(t)
+	defer cleanup_outside()
+	if err := os.Rename(filepath.Join(root, "b1"), filepath.Join(outside, "b1")); err != nil {
+		t.Fatal(err)
+	}
+	serve(fsnotify.Rename, filepath.Join(root, "b1"))
+	time.Sleep(MOVE_TIMEOUT)
+	index = handler.ServeWatcherEvent(watcher, &tickEvent, index)
+	if _, present := index.bundle_name_2_bundle_index["b1"]; present {
+		t.Errorf("Expected bundle b1 to be removed from index")
+	}
+	expectProcess(t, index, "de.moved.A1", false)
+}
+
+func TestWatchFilesystem(t *testing.T) {
+	testWatchFilesystem(t, newWatcher(t))
+}
+
+func TestWatchFilesystemPolling(t *testing.T) {
+	testWatchFilesystem(t, storage.NewPollWatcher(storage.OS{}, 20*time.Millisecond))
+}
+
+func testWatchFilesystem(t *testing.T, watcher storage.Watcher) {
+	root, cleanup := tempRoot(t)
+	defer cleanup()
+	defer watcher.Close()
+
+	index := createIndex(storage.OS{}, root)
+	storage.Walk(storage.OS{}, root, watcherWalkTreeFunction(watcher))
+	handler, interval := eventHandler(WatchOptions{QuietPeriod: 50 * time.Millisecond})
+
+	await := func(id string, expected bool) {
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		timeout := time.After(5 * time.Second)
+		for {
+			if _, present := index.ProcessFile(id); present == expected {
+				return
+			}
+			select {
+			case event := <-watcher.Events():
+				index = handler.ServeWatcherEvent(watcher, &event, index)
+			case <-tick.C:
+				index = handler.ServeWatcherEvent(watcher, &tickEvent, index)
+			case err := <-watcher.Errors():
+				t.Fatal(err)
+			case <-timeout:
+				t.Fatalf("Timeout waiting for process %s present=%v", id, expected)
+			}
+		}
+	}
+
+	writeProcess(t, filepath.Join(root, "b3", "p", "C.process"))
+	await("p.C", true)
+
+	if err := os.Rename(filepath.Join(root, "b3", "p"), filepath.Join(root, "b3", "q")); err != nil {
+		t.Fatal(err)
+	}
+	await("q.C", true)
+	await("p.C", false)
+
+	if err := os.RemoveAll(filepath.Join(root, "b3")); err != nil {
+		t.Fatal(err)
+	}
+	await("q.C", false)
+}
+
+func TestCoalesce(t *testing.T) {
+	fs := storage.NewMemory()
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/bundles/b1/de/a/A1.process", "/bundles/b1/de/a/X.process"} {
+		storage.MkdirAll(fs, filepath.Dir(path), 0755)
+		fs.WriteFile(path, content, 0644)
+	}
+	index := createIndex(fs, "/bundles")
+	expectProcess(t, index, "de.a.A1", true)
+	expectProcess(t, index, "de.a.X", true)
+
+	quiet := 50 * time.Millisecond
+	var served []fsnotify.Event
+	handler := Coalesce(quiet)(HandlerFunc(func(watcher storage.Watcher, event *fsnotify.Event, index *Index) *Index {
+		if !isTick(event) {
+			served = append(served, *event)
+		}
+		return index
+	}))
+	serve := func(op fsnotify.Op, path string) {
+		index = handler.ServeWatcherEvent(nil, &fsnotify.Event{Name: path, Op: op}, index)
+	}
+	metric := func(name string) int64 {
+		if v, ok := watcherMetrics.Get(name).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	coalesced, batches := metric("events_coalesced"), metric("batches")
+
+	// Events aller Art werden je Bundle gesammelt, Events außerhalb der
+	// Bundles sofort weitergereicht
+	fs.WriteFile("/bundles/b1/de/a/A1.process", content, 0644)
+	serve(fsnotify.Write, "/bundles/b1/de/a/A1.process")
+	storage.MkdirAll(fs, "/bundles/b1/de/b", 0755)
+	serve(fsnotify.Create, "/bundles/b1/de/b")
+	fs.WriteFile("/bundles/b1/de/b/B.process", content, 0644)
+	serve(fsnotify.Create, "/bundles/b1/de/b/B.process")
+	serve(fsnotify.Write, "/bundles/b1/de/b/B.process")
+	fs.Remove("/bundles/b1/de/a/X.process")
+	serve(fsnotify.Remove, "/bundles/b1/de/a/X.process")
+	fs.Rename("/bundles/b1/de/a/A1.process", "/bundles/b1/de/b/A1.process")
+	serve(fsnotify.Rename, "/bundles/b1/de/a/A1.process")
+	serve(fsnotify.Create, "/bundles/b1/de/b/A1.process")
+	storage.MkdirAll(fs, "/bundles/b2/de/c", 0755)
+	fs.WriteFile("/bundles/b2/de/c/C.process", content, 0644)
+	serve(fsnotify.Create, "/bundles/b2")
+	serve(fsnotify.Create, "/elsewhere")
+
+	before := index
+	index = handler.ServeWatcherEvent(nil, &tickEvent, index)
+	if len(served) != 1 || served[0].Name != "/elsewhere" {
+		t.Fatalf("Expected only the event outside the bundles before the quiet period, but was %v", served)
+	}
+	if index != before {
+		t.Fatalf("Expected unchanged index before the quiet period")
+	}
+
+	// Nach der Ruhezeit wird der Index einmal je Bundle aktualisiert
+	time.Sleep(quiet)
+	index = handler.ServeWatcherEvent(nil, &tickEvent, index)
+	expectProcess(t, index, "de.a.A1", false)
+	expectProcess(t, index, "de.a.X", false)
+	expectProcess(t, index, "de.b.A1", true)
+	expectProcess(t, index, "de.b.B", true)
+	expectProcess(t, index, "de.c.C", true)
+	if len(served) != 1 {
+		t.Errorf("Expected coalesced events not to be served, but was %v", served)
+	}
+	if b := metric("batches") - batches; b != 2 {
+		t.Errorf("Expected 2 batches, but was %d", b)
+	}
+	if c := metric("events_coalesced") - coalesced; c != 6 {
+		t.Errorf("Expected 6 coalesced events, but was %d", c)
+	}
+
+	before = index
+	if index = handler.ServeWatcherEvent(nil, &tickEvent, index); index != before {
+		t.Errorf("Expected no further index updates")
+	}
+}
+
+func TestCorrelateMoves(t *testing.T) {
+	fs := storage.NewMemory()
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/bundles/b1/de/a/A1.process", "/bundles/b1/de/a/X.process"} {
+		storage.MkdirAll(fs, filepath.Dir(path), 0755)
+		fs.WriteFile(path, content, 0644)
+	}
+	index := createIndex(fs, "/bundles")
+	watcher, err := fs.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	handler, _ := eventHandler(WatchOptions{})
+	serve := func(op fsnotify.Op, path string) {
+		index = handler.ServeWatcherEvent(watcher, &fsnotify.Event{Name: path, Op: op}, index)
+	}
+	moves := func() int64 {
+		if v, ok := watcherMetrics.Get("moves").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := moves()
+
+	// Umbenannt, aber mit gleichem Inhalt: eine Verschiebung
+	fs.Rename("/bundles/b1/de/a/A1.process", "/bundles/b1/de/a/B1.process")
+	serve(fsnotify.Rename, "/bundles/b1/de/a/A1.process")
+	serve(fsnotify.Create, "/bundles/b1/de/a/B1.process")
+	if m := moves() - before; m != 1 {
+		t.Errorf("Expected 1 correlated move, but was %d", m)
+	}
+	expectProcess(t, index, "de.a.A1", false)
+	expectProcess(t, index, "de.a.B1", true)
+
+	// Anderer Name und anderer Inhalt: Löschen und unabhängiges Anlegen
+	fs.Remove("/bundles/b1/de/a/X.process")
+	serve(fsnotify.Rename, "/bundles/b1/de/a/X.process")
+	fs.WriteFile("/bundles/b1/de/a/Y.process", append(content, '\n'), 0644)
+	serve(fsnotify.Create, "/bundles/b1/de/a/Y.process")
+	if m := moves() - before; m != 1 {
+		t.Errorf("Expected no further correlated move, but was %d", m-1)
+	}
+	expectProcess(t, index, "de.a.X", false)
+	expectProcess(t, index, "de.a.Y", true)
+}
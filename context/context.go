@@ -3,21 +3,38 @@ package context
 import (
 	"context"
 	"net/http"
+
+	"github.com/frericksm/pride/storage"
 )
 
 type Handler struct {
 	BundleRootDir string
+	// Storage der Bundles. Default ist das Dateisystem (storage.OS).
+	Storage storage.Storage
+	// Verzeichnis der Templates für neue Bundles und Prozesse (siehe
+	// Package scaffold). Leer für die eingebauten Vorlagen.
+	Templates string
 	Handler http.Handler
 }
 
 const KEY_BUNDLE_ROOT_DIR = "BUNDLE_ROOT_DIR"
 
+const KEY_STORAGE = "STORAGE"
+
+const KEY_TEMPLATES = "TEMPLATES"
+
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	old_context := r.Context()
 	new_context := context.WithValue(
-		old_context, 
-		KEY_BUNDLE_ROOT_DIR, 
+		old_context,
+		KEY_BUNDLE_ROOT_DIR,
 		h.BundleRootDir)
+	if h.Storage != nil {
+		new_context = context.WithValue(new_context, KEY_STORAGE, h.Storage)
+	}
+	if h.Templates != "" {
+		new_context = context.WithValue(new_context, KEY_TEMPLATES, h.Templates)
+	}
 	r_new := r.WithContext(new_context)
 	h.Handler.ServeHTTP(w , r_new)
 }
@@ -27,7 +44,26 @@ func BundleRootDir(ctx context.Context) string {
 	// ctx.Value returns nil if ctx has no value for the key;
 	// the string type assertion returns ok=false for nil.
 	bundle_dir, _ := ctx.Value(KEY_BUNDLE_ROOT_DIR).(string)
-	return bundle_dir	
+	return bundle_dir
 }
 
+// Storage extracts the storage from ctx. Without one the file system is used.
+func Storage(ctx context.Context) storage.Storage {
+	if fs, ok := ctx.Value(KEY_STORAGE).(storage.Storage); ok {
+		return fs
+	}
+	return storage.OS{}
+}
 
+// WithStorage liefert einen Context mit dem Bundle-Root-Verzeichnis
+// 'bundle_root_dir' im Storage 'fs', z.B. für Tests der Resolver
+func WithStorage(ctx context.Context, bundle_root_dir string, fs storage.Storage) context.Context {
+	ctx = context.WithValue(ctx, KEY_BUNDLE_ROOT_DIR, bundle_root_dir)
+	return context.WithValue(ctx, KEY_STORAGE, fs)
+}
+
+// Templates extracts the template directory from ctx, if present.
+func Templates(ctx context.Context) string {
+	templates, _ := ctx.Value(KEY_TEMPLATES).(string)
+	return templates
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+)
+
+// Die Endungen der Dateien je Format
+var formatExtensions = map[string]string{
+	processfile.FORMAT_XML:  ".process",
+	processfile.FORMAT_JSON: ".json",
+	processfile.FORMAT_YAML: ".yaml",
+}
+
+// Convert wandelt Prozessdefinitionen in das Format 'to' um. Die Ergebnisse
+// werden neben die Dateien oder in das Verzeichnis 'output' geschrieben und
+// ausgegeben, mit 'stdout' nur ausgegeben.
+func convert(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		return cli.NewExitError("convert erwartet mindestens eine Datei", 1)
+	}
+	to := c.String("to")
+	extension, present := formatExtensions[to]
+	if !present {
+		return cli.NewExitError(fmt.Sprintf("unbekanntes Format '%s', erwartet json, yaml oder xml", to), 1)
+	}
+
+	for _, file := range paths {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		p, err := processfile.Decode(content, processfile.FormatOf(file))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+		result, err := processfile.Encode(p, to)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+		if c.Bool("stdout") {
+			os.Stdout.Write(result)
+			continue
+		}
+
+		target := strings.TrimSuffix(file, filepath.Ext(file)) + extension
+		if output := c.String("output"); output != "" {
+			target = filepath.Join(output, filepath.Base(target))
+		}
+		if target == file {
+			return cli.NewExitError(fmt.Sprintf("%s ist bereits im Format %s", file, to), 2)
+		}
+		if err := (storage.OS{}).WriteFile(target, result, 0644); err != nil {
+			return err
+		}
+		fmt.Println(target)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+// Diff vergleicht zwei Prozessdateien semantisch und gibt die Unterschiede aus.
+// Wie bei 'diff' ist der Exit-Code 1, wenn es Unterschiede gibt.
+func diff(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("diff erwartet genau zwei Prozessdateien")
+	}
+
+	// Wie bei 'diff' ist der Exit-Code bei Fehlern 2
+	a, err := readProcessFile(c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 2)
+	}
+	b, err := readProcessFile(c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 2)
+	}
+
+	changes := processfile.Diff(a, b, processfile.DiffOptions{Layout: c.Bool("layout")})
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if len(changes) > 0 {
+		return cli.NewExitError("", 1)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/bpmn"
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+)
+
+// Export übersetzt Prozessdateien in ein anderes Format. Die Ergebnisse
+// werden neben die Prozessdateien oder in das Verzeichnis 'output'
+// geschrieben und ausgegeben.
+func export(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		return cli.NewExitError("export erwartet mindestens eine Datei oder ein Verzeichnis", 1)
+	}
+	if c.String("format") != "bpmn" {
+		return cli.NewExitError(fmt.Sprintf("unbekanntes Format '%s'", c.String("format")), 1)
+	}
+
+	files, err := processFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		p, err := processfile.Parse(content)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+		result, err := bpmn.Export(p)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+
+		target := strings.TrimSuffix(file, filepath.Ext(file)) + ".bpmn"
+		if output := c.String("output"); output != "" {
+			target = filepath.Join(output, filepath.Base(target))
+		}
+		if err := (storage.OS{}).WriteFile(target, result, 0644); err != nil {
+			return err
+		}
+		fmt.Println(target)
+	}
+	return nil
+}
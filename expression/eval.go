@@ -0,0 +1,680 @@
+package expression
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KeywordValue ist der Wert eines Keywords, z.B. :meldung
+type KeywordValue string
+
+// Die Werte der Auswertung sind nil, bool, int64, float64, string, KeywordValue,
+// []interface{} (Listen und Vektoren) und map[interface{}]interface{}.
+
+// EvalError ist ein Fehler bei der Auswertung eines Ausdrucks
+type EvalError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+func evalErrorf(n *Node, format string, args ...interface{}) error {
+	return &EvalError{Pos: n.Pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// Env bindet Namen an Werte
+type Env map[string]interface{}
+
+func (env Env) with(name string, value interface{}) Env {
+	r := make(Env, len(env)+1)
+	for k, v := range env {
+		r[k] = v
+	}
+	r[name] = value
+	return r
+}
+
+// Truthy entspricht der Wahrheit in Clojure: nur nil und false sind falsch
+func Truthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+// EvalString liest 'src' und wertet alle Ausdrücke aus. Das Ergebnis ist der
+// Wert des letzten Ausdrucks, bei leerem 'src' nil.
+func EvalString(src string, env Env) (interface{}, error) {
+	nodes, err := Read(src)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	for _, n := range nodes {
+		if result, err = Eval(n, env); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Eval wertet 'n' in 'env' aus. Unterstützt wird die Teilmenge von Clojure,
+// die in Data-Mappings und Bedingungen üblich ist.
+func Eval(n *Node, env Env) (interface{}, error) {
+	switch n.Kind {
+	case Nil:
+		return nil, nil
+	case Boolean:
+		return n.Text == "true", nil
+	case String, Regex:
+		return n.Text, nil
+	case Character:
+		return character(n)
+	case Keyword:
+		return KeywordValue(strings.TrimLeft(n.Text, ":")), nil
+	case Number:
+		return parseNumber(n)
+	case Symbol:
+		if v, ok := env[n.Text]; ok {
+			return v, nil
+		}
+		return nil, evalErrorf(n, "unable to resolve symbol '%s'", n.Text)
+	case Vector, Set:
+		values, err := evalAll(n.Children, env)
+		return values, err
+	case Map:
+		m := make(map[interface{}]interface{})
+		for i := 0; i+1 < len(n.Children); i += 2 {
+			k, err := Eval(n.Children[i], env)
+			if err != nil {
+				return nil, err
+			}
+			if !hashable(k) {
+				return nil, evalErrorf(n.Children[i], "unsupported map key %s", Format(k))
+			}
+			v, err := Eval(n.Children[i+1], env)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	}
+	return evalList(n, env)
+}
+
+// Die Werte der benannten Zeichen-Literale (siehe characterNames)
+var characterValues = map[string]string{
+	"newline":   "\n",
+	"space":     " ",
+	"tab":       "\t",
+	"backspace": "\b",
+	"formfeed":  "\f",
+	"return":    "\r",
+}
+
+// character liefert den Wert eines Zeichen-Literals, z.B. \a, \newline oder
+// \u00e4, als String
+func character(n *Node) (interface{}, error) {
+	name := n.Text[1:]
+	if c, present := characterValues[name]; present {
+		return c, nil
+	}
+	if len(name) == 5 && name[0] == 'u' {
+		if r, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return string(rune(r)), nil
+		}
+	}
+	if len(name) > 1 && len(name) <= 4 && name[0] == 'o' {
+		if r, err := strconv.ParseUint(name[1:], 8, 32); err == nil && r <= 0377 {
+			return string(rune(r)), nil
+		}
+	}
+	if len([]rune(name)) != 1 {
+		return nil, evalErrorf(n, "unsupported character '%s'", n.Text)
+	}
+	return name, nil
+}
+
+// hashable prüft, ob 'v' Schlüssel einer Map sein kann. Listen, Vektoren und
+// Maps können es nicht.
+func hashable(v interface{}) bool {
+	switch v.(type) {
+	case []interface{}, map[interface{}]interface{}:
+		return false
+	}
+	return true
+}
+
+func parseNumber(n *Node) (interface{}, error) {
+	t := strings.TrimSuffix(strings.TrimSuffix(n.Text, "N"), "M")
+	if i, err := strconv.ParseInt(t, 0, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(t, 64); err == nil {
+		return f, nil
+	}
+	return nil, evalErrorf(n, "unsupported number '%s'", n.Text)
+}
+
+func evalAll(nodes []*Node, env Env) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(nodes))
+	for _, c := range nodes {
+		v, err := Eval(c, env)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func evalList(n *Node, env Env) (interface{}, error) {
+	if len(n.Children) == 0 {
+		return []interface{}{}, nil
+	}
+	head, args := n.Children[0], n.Children[1:]
+
+	if head.Kind == Symbol {
+		switch head.Text {
+		case "quote":
+			if len(args) != 1 {
+				return nil, evalErrorf(n, "quote expects 1 argument")
+			}
+			return quoted(args[0]), nil
+		case "if":
+			if len(args) < 2 || len(args) > 3 {
+				return nil, evalErrorf(n, "if expects 2 or 3 arguments")
+			}
+			test, err := Eval(args[0], env)
+			if err != nil {
+				return nil, err
+			}
+			if Truthy(test) {
+				return Eval(args[1], env)
+			} else if len(args) == 3 {
+				return Eval(args[2], env)
+			}
+			return nil, nil
+		case "when", "when-not":
+			if len(args) < 1 {
+				return nil, evalErrorf(n, "%s expects a test", head.Text)
+			}
+			test, err := Eval(args[0], env)
+			if err != nil {
+				return nil, err
+			}
+			if Truthy(test) != (head.Text == "when") {
+				return nil, nil
+			}
+			return evalBody(args[1:], env)
+		case "cond":
+			for i := 0; i+1 < len(args); i += 2 {
+				test, err := Eval(args[i], env)
+				if err != nil {
+					return nil, err
+				}
+				if Truthy(test) {
+					return Eval(args[i+1], env)
+				}
+			}
+			return nil, nil
+		case "and":
+			var v interface{} = true
+			for _, a := range args {
+				var err error
+				if v, err = Eval(a, env); err != nil || !Truthy(v) {
+					return v, err
+				}
+			}
+			return v, nil
+		case "or":
+			var v interface{}
+			for _, a := range args {
+				var err error
+				if v, err = Eval(a, env); err != nil || Truthy(v) {
+					return v, err
+				}
+			}
+			return v, nil
+		case "let":
+			if len(args) < 1 || args[0].Kind != Vector || len(args[0].Children)%2 != 0 {
+				return nil, evalErrorf(n, "let expects a binding vector with an even number of forms")
+			}
+			b := args[0].Children
+			for i := 0; i < len(b); i += 2 {
+				if b[i].Kind != Symbol {
+					return nil, evalErrorf(b[i], "only simple symbols are supported in let bindings")
+				}
+				v, err := Eval(b[i+1], env)
+				if err != nil {
+					return nil, err
+				}
+				env = env.with(b[i].Text, v)
+			}
+			return evalBody(args[1:], env)
+		case "do":
+			return evalBody(args, env)
+		}
+	}
+
+	f, err := Eval(head, env)
+	if _, unresolved := err.(*EvalError); unresolved && head.Kind == Symbol {
+		builtin, ok := builtins[head.Text]
+		if !ok {
+			return nil, evalErrorf(head, "unsupported function '%s'", head.Text)
+		}
+		values, err := evalAll(args, env)
+		if err != nil {
+			return nil, err
+		}
+		v, err := builtin(values)
+		if err != nil {
+			return nil, evalErrorf(n, "%s: %s", head.Text, err)
+		}
+		return v, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	values, err := evalAll(args, env)
+	if err != nil {
+		return nil, err
+	}
+	// Keywords und Maps sind Funktionen: (:k m), (m :k)
+	switch fv := f.(type) {
+	case KeywordValue:
+		if len(values) < 1 || len(values) > 2 {
+			return nil, evalErrorf(n, "keyword lookup expects 1 or 2 arguments")
+		}
+		return get(append([]interface{}{values[0], fv}, values[1:]...))
+	case map[interface{}]interface{}:
+		if len(values) < 1 || len(values) > 2 {
+			return nil, evalErrorf(n, "map lookup expects 1 or 2 arguments")
+		}
+		return get(append([]interface{}{fv}, values...))
+	}
+	return nil, evalErrorf(head, "%s is not a function", Format(f))
+}
+
+func evalBody(body []*Node, env Env) (interface{}, error) {
+	var v interface{}
+	for _, b := range body {
+		var err error
+		if v, err = Eval(b, env); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func quoted(n *Node) interface{} {
+	switch n.Kind {
+	case Symbol:
+		return n.Text
+	case List, Vector, Set:
+		l := make([]interface{}, len(n.Children))
+		for i, c := range n.Children {
+			l[i] = quoted(c)
+		}
+		return l
+	}
+	v, _ := Eval(n, Env{})
+	return v
+}
+
+var builtins map[string]func([]interface{}) (interface{}, error)
+
+func init() {
+	builtins = map[string]func([]interface{}) (interface{}, error){
+		"str": func(args []interface{}) (interface{}, error) {
+			var s bytes.Buffer
+			for _, a := range args {
+				if str, ok := a.(string); ok {
+					s.WriteString(str)
+				} else if a != nil {
+					s.WriteString(Format(a))
+				}
+			}
+			return s.String(), nil
+		},
+		"=":     func(args []interface{}) (interface{}, error) { return equalAll(args), nil },
+		"not=":  func(args []interface{}) (interface{}, error) { return !equalAll(args), nil },
+		"not":   func(args []interface{}) (interface{}, error) { return arity(args, 1, func() interface{} { return !Truthy(args[0]) }) },
+		"nil?":  func(args []interface{}) (interface{}, error) { return arity(args, 1, func() interface{} { return args[0] == nil }) },
+		"some?": func(args []interface{}) (interface{}, error) { return arity(args, 1, func() interface{} { return args[0] != nil }) },
+		"empty?": func(args []interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("expects 1 argument")
+			}
+			c, err := count(args[0])
+			return c == 0, err
+		},
+		"count": func(args []interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("expects 1 argument")
+			}
+			return count(args[0])
+		},
+		"get": get,
+		"get-in": func(args []interface{}) (interface{}, error) {
+			if len(args) < 2 || len(args) > 3 {
+				return nil, fmt.Errorf("expects 2 or 3 arguments")
+			}
+			path, ok := args[1].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path must be a vector")
+			}
+			v := args[0]
+			for _, k := range path {
+				v, _ = get([]interface{}{v, k})
+			}
+			if v == nil && len(args) == 3 {
+				return args[2], nil
+			}
+			return v, nil
+		},
+		"contains?": func(args []interface{}) (interface{}, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("expects 2 arguments")
+			}
+			if m, ok := args[0].(map[interface{}]interface{}); ok && hashable(args[1]) {
+				_, present := m[args[1]]
+				return present, nil
+			}
+			return false, nil
+		},
+		"first": func(args []interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("expects 1 argument")
+			}
+			if l, ok := args[0].([]interface{}); ok && len(l) > 0 {
+				return l[0], nil
+			}
+			return nil, nil
+		},
+		"vector":   func(args []interface{}) (interface{}, error) { return args, nil },
+		"list":     func(args []interface{}) (interface{}, error) { return args, nil },
+		"hash-map": func(args []interface{}) (interface{}, error) { return assoc(append([]interface{}{nil}, args...)) },
+		"assoc":    assoc,
+		"keyword": func(args []interface{}) (interface{}, error) {
+			return arity(args, 1, func() interface{} { return KeywordValue(fmt.Sprint(args[0])) })
+		},
+		"name": func(args []interface{}) (interface{}, error) {
+			return arity(args, 1, func() interface{} {
+				if k, ok := args[0].(KeywordValue); ok {
+					return string(k)
+				}
+				return fmt.Sprint(args[0])
+			})
+		},
+		"subs": func(args []interface{}) (interface{}, error) {
+			if len(args) < 2 || len(args) > 3 {
+				return nil, fmt.Errorf("expects 2 or 3 arguments")
+			}
+			s, ok := args[0].(string)
+			start, ok2 := args[1].(int64)
+			if !ok || !ok2 {
+				return nil, fmt.Errorf("expects a string and an index")
+			}
+			r := []rune(s)
+			end := int64(len(r))
+			if len(args) == 3 {
+				e, ok := args[2].(int64)
+				if !ok {
+					return nil, fmt.Errorf("end must be an integer")
+				}
+				end = e
+			}
+			if start < 0 || start > end || end > int64(len(r)) {
+				return nil, fmt.Errorf("index out of range")
+			}
+			return string(r[start:end]), nil
+		},
+		"clojure.string/blank?": func(args []interface{}) (interface{}, error) {
+			return arity(args, 1, func() interface{} {
+				s, _ := args[0].(string)
+				return strings.TrimSpace(s) == ""
+			})
+		},
+		"clojure.string/upper-case": stringFunc(strings.ToUpper),
+		"clojure.string/lower-case": stringFunc(strings.ToLower),
+		"clojure.string/trim":       stringFunc(strings.TrimSpace),
+		"+":                         arithmetic(func(a, b int64) int64 { return a + b }, func(a, b float64) float64 { return a + b }, 0),
+		"*":                         arithmetic(func(a, b int64) int64 { return a * b }, func(a, b float64) float64 { return a * b }, 1),
+		"-": func(args []interface{}) (interface{}, error) {
+			if len(args) == 1 {
+				args = []interface{}{int64(0), args[0]}
+			}
+			return arithmetic(func(a, b int64) int64 { return a - b }, func(a, b float64) float64 { return a - b }, 0)(args)
+		},
+		"/": func(args []interface{}) (interface{}, error) {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("expects at least 2 arguments")
+			}
+			r, err := toFloat(args[0])
+			for _, a := range args[1:] {
+				d, err2 := toFloat(a)
+				if err2 != nil || d == 0 {
+					return nil, fmt.Errorf("invalid divisor %s", Format(a))
+				}
+				r = r / d
+			}
+			return r, err
+		},
+		"inc": func(args []interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("expects 1 argument")
+			}
+			return arithmetic(func(a, b int64) int64 { return a + b }, func(a, b float64) float64 { return a + b }, 0)(append(args, int64(1)))
+		},
+		"dec": func(args []interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("expects 1 argument")
+			}
+			return arithmetic(func(a, b int64) int64 { return a - b }, func(a, b float64) float64 { return a - b }, 0)(append(args, int64(1)))
+		},
+		"<":  comparison(func(a, b float64) bool { return a < b }),
+		"<=": comparison(func(a, b float64) bool { return a <= b }),
+		">":  comparison(func(a, b float64) bool { return a > b }),
+		">=": comparison(func(a, b float64) bool { return a >= b }),
+	}
+}
+
+func arity(args []interface{}, n int, f func() interface{}) (interface{}, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("expects %d argument(s)", n)
+	}
+	return f(), nil
+}
+
+func stringFunc(f func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expects 1 argument")
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expects a string")
+		}
+		return f(s), nil
+	}
+}
+
+func equalAll(args []interface{}) bool {
+	for i := 1; i < len(args); i++ {
+		if !equal(args[0], args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equal(a, b interface{}) bool {
+	fa, errA := toFloat(a)
+	fb, errB := toFloat(b)
+	if errA == nil && errB == nil {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func count(v interface{}) (int64, error) {
+	switch c := v.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return int64(len([]rune(c))), nil
+	case []interface{}:
+		return int64(len(c)), nil
+	case map[interface{}]interface{}:
+		return int64(len(c)), nil
+	}
+	return 0, fmt.Errorf("count not supported on %s", Format(v))
+}
+
+func get(args []interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("expects 2 or 3 arguments")
+	}
+	var v interface{}
+	switch c := args[0].(type) {
+	case map[interface{}]interface{}:
+		if hashable(args[1]) {
+			v = c[args[1]]
+		}
+	case []interface{}:
+		if i, ok := args[1].(int64); ok && i >= 0 && i < int64(len(c)) {
+			v = c[i]
+		}
+	}
+	if v == nil && len(args) == 3 {
+		return args[2], nil
+	}
+	return v, nil
+}
+
+func assoc(args []interface{}) (interface{}, error) {
+	if len(args)%2 != 1 {
+		return nil, fmt.Errorf("expects a map and key value pairs")
+	}
+	m := make(map[interface{}]interface{})
+	if args[0] != nil {
+		src, ok := args[0].(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expects a map")
+		}
+		for k, v := range src {
+			m[k] = v
+		}
+	}
+	for i := 1; i+1 < len(args); i += 2 {
+		if !hashable(args[i]) {
+			return nil, fmt.Errorf("unsupported map key %s", Format(args[i]))
+		}
+		m[args[i]] = args[i+1]
+	}
+	return m, nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("%s is not a number", Format(v))
+}
+
+func arithmetic(fi func(a, b int64) int64, ff func(a, b float64) float64, identity int64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		var ri int64 = identity
+		var rf float64 = float64(identity)
+		isFloat := false
+		for i, a := range args {
+			switch n := a.(type) {
+			case int64:
+				if i == 0 {
+					ri, rf = n, float64(n)
+				} else {
+					ri, rf = fi(ri, n), ff(rf, float64(n))
+				}
+			case float64:
+				isFloat = true
+				if i == 0 {
+					rf = n
+				} else {
+					rf = ff(rf, n)
+				}
+			default:
+				return nil, fmt.Errorf("%s is not a number", Format(a))
+			}
+		}
+		if isFloat {
+			return rf, nil
+		}
+		return ri, nil
+	}
+}
+
+func comparison(f func(a, b float64) bool) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		for i := 0; i+1 < len(args); i++ {
+			a, err := toFloat(args[i])
+			if err != nil {
+				return nil, err
+			}
+			b, err := toFloat(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if !f(a, b) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// Format liefert die Darstellung eines Wertes als Clojure-Literal
+func Format(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(x)
+	case KeywordValue:
+		return ":" + string(x)
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1e15 {
+			return strconv.FormatFloat(x, 'f', 1, 64)
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case []interface{}:
+		s := make([]string, len(x))
+		for i, e := range x {
+			s[i] = Format(e)
+		}
+		return "[" + strings.Join(s, " ") + "]"
+	case map[interface{}]interface{}:
+		s := make([]string, 0, len(x))
+		for k, e := range x {
+			s = append(s, Format(k)+" "+Format(e))
+		}
+		sort.Strings(s)
+		return "{" + strings.Join(s, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
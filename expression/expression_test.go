@@ -0,0 +1,126 @@
+package expression_test
+
+import (
+	"testing"
+
+	"github.com/frericksm/pride/expression"
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestRead(t *testing.T) {
+	nodes, err := expression.Read(`(str "Prozess: " Prozessname " - Meldung: " (:meldungMitHoechsterFehlerklasse Meldungstext))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(nodes); l != 1 {
+		t.Fatalf("Expected 1 form, but was %d", l)
+	}
+	if l := len(nodes[0].Children); l != 5 {
+		t.Errorf("Expected 5 children, but was %d", l)
+	}
+	if k := nodes[0].Children[4].Children[0].Kind; k != expression.Keyword {
+		t.Errorf("Expected keyword, but was %s", k)
+	}
+}
+
+func TestReadSyntaxError(t *testing.T) {
+	_, err := expression.Read("(str \"a\"\n  (inc x)")
+	se, ok := err.(*expression.SyntaxError)
+	if !ok {
+		t.Fatalf("Expected syntax error, but was %v", err)
+	}
+	if se.Pos.Line != 1 || se.Pos.Column != 1 {
+		t.Errorf("Expected position 1:1, but was %s", se.Pos)
+	}
+
+	_, err = expression.Read("{:a 1 :b}")
+	if err == nil {
+		t.Errorf("Expected syntax error for odd map literal")
+	}
+}
+
+func TestLint(t *testing.T) {
+	scope := expression.Scope{"Prozessname": struct{}{}, "Meldungstext": struct{}{}}
+
+	if p := expression.Lint(`(let [x Prozessname {:keys [a]} Meldungstext] (str x a (map #(inc %) [1 2])))`, scope); len(p) != 0 {
+		t.Errorf("Expected no problems, but was %v", p)
+	}
+
+	p := expression.Lint("(str\n  Prozesname)", scope)
+	if len(p) != 1 {
+		t.Fatalf("Expected 1 problem, but was %v", p)
+	}
+	if p[0].Pos.Line != 2 || p[0].Pos.Column != 3 {
+		t.Errorf("Expected position 2:3, but was %s", p[0].Pos)
+	}
+	if m := p[0].Message; m != "unknown symbol 'Prozesname', did you mean 'Prozessname'?" {
+		t.Errorf("Unexpected message %s", m)
+	}
+}
+
+func TestLintProcess(t *testing.T) {
+	p := processfile.FromBytes(processfile.FileContent("../processfile/testdata/A1.process"))
+	if problems := expression.LintProcess(p); len(problems) != 0 {
+		t.Errorf("Expected no problems, but was %v", problems)
+	}
+}
+
+func TestEval(t *testing.T) {
+	env := expression.Env{
+		"Prozessname":  "A1",
+		"Meldungstext": map[interface{}]interface{}{expression.KeywordValue("klasse"): int64(3)},
+	}
+
+	v, err := expression.EvalString(`(and (= Prozessname "A1") (> (:klasse Meldungstext) 2))`, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != true {
+		t.Errorf("Expected true, but was %v", v)
+	}
+
+	v, err = expression.EvalString(`(let [k (inc (:klasse Meldungstext))] (str Prozessname "-" k))`, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "A1-4" {
+		t.Errorf("Expected A1-4, but was %v", v)
+	}
+
+	if _, err := expression.EvalString(`(str Unbekannt)`, env); err == nil {
+		t.Errorf("Expected error for unknown symbol")
+	}
+}
+
+func TestEvalCharacters(t *testing.T) {
+	for src, expected := range map[string]string{`\a`: "a", `\newline`: "\n", `\space`: " ", `\tab`: "\t", `\ä`: "ä", `\u00e4`: "ä", `\o101`: "A"} {
+		if v, err := expression.EvalString(src, nil); err != nil || v != expected {
+			t.Errorf("%s: Expected %q, but was %q (%v)", src, expected, v, err)
+		}
+	}
+}
+
+func TestEvalCollectionKeys(t *testing.T) {
+	env := expression.Env{"m": map[interface{}]interface{}{int64(1): "a"}}
+
+	// Vektoren und Maps als Schlüssel sind Fehler, keine Panics
+	for _, src := range []string{`{[1] 2}`, `(assoc m {} 1)`, `(hash-map [1] 2)`} {
+		if _, err := expression.EvalString(src, env); err == nil {
+			t.Errorf("%s: Expected error for unsupported map key", src)
+		}
+	}
+	// Nachschlagen mit solchen Schlüsseln findet nichts
+	for src, expected := range map[string]interface{}{`(get m [1 2])`: nil, `(get m [1] 0)`: int64(0), `(contains? m [1])`: false, `(get m 1)`: "a"} {
+		if v, err := expression.EvalString(src, env); err != nil || v != expected {
+			t.Errorf("%s: Expected %v, but was %v (%v)", src, expected, v, err)
+		}
+	}
+}
+
+func TestEvalArity(t *testing.T) {
+	for _, src := range []string{`(inc)`, `(dec)`, `(inc 1 2)`} {
+		if _, err := expression.EvalString(src, nil); err == nil {
+			t.Errorf("%s: Expected arity error", src)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package expression
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+// Scope ist die Menge der Namen, die in einem Ausdruck aufgelöst werden können
+type Scope map[string]struct{}
+
+var e struct{}
+
+// with liefert einen neuen Scope, der zusätzlich 'names' enthält
+func (s Scope) with(names ...string) Scope {
+	r := make(Scope, len(s)+len(names))
+	for n := range s {
+		r[n] = e
+	}
+	for _, n := range names {
+		r[n] = e
+	}
+	return r
+}
+
+// ProcessScope enthält die Namen der formalen Parameter und Variablen von 'p'
+func ProcessScope(p *processfile.Process) Scope {
+	s := make(Scope)
+	for _, fp := range p.FormalParameters {
+		s[fp.Name] = e
+	}
+	for _, v := range p.Variables {
+		s[v.Name] = e
+	}
+	return s
+}
+
+// Die Funktionen aus clojure.core, die in Data-Mappings auch als Werte
+// auftreten, z.B. (map str l)
+var core = Scope{}
+
+func init() {
+	for _, n := range strings.Fields(`
+		* + - / < <= = == > >= not= inc dec max min mod quot rem
+		and or not nil? some? true? false? empty? not-empty zero? pos? neg? even? odd? number? string? keyword? map? vector? coll? seq?
+		str subs name keyword symbol format println print pr-str prn-str
+		first second last rest next butlast nth get get-in assoc assoc-in dissoc update update-in merge merge-with select-keys keys vals find contains?
+		count conj cons concat into list vector vec set hash-map hash-set sorted-map array-map seq reverse sort sort-by distinct frequencies group-by partition
+		map mapv mapcat filter filterv remove reduce reduce-kv some every? not-any? keep take drop take-while drop-while range repeat apply partial comp identity constantly juxt
+		int long double bigdec boolean char
+		clojure.string/join clojure.string/split clojure.string/blank? clojure.string/trim clojure.string/upper-case clojure.string/lower-case
+		throw ex-info`) {
+		core[n] = e
+	}
+}
+
+// Formen, deren erstes Argument ein Binding-Vektor ist
+var bindingForms = map[string]bool{
+	"let": true, "loop": true, "binding": true, "for": true, "doseq": true, "dotimes": true,
+	"when-let": true, "if-let": true, "when-some": true, "if-some": true, "with-open": true,
+}
+
+type linter struct {
+	problems []Problem
+}
+
+// Problem ist ein Fehler in einem Ausdruck
+type Problem struct {
+	Pos     Pos
+	Message string
+}
+
+func (l *linter) report(pos Pos, format string, args ...interface{}) {
+	l.problems = append(l.problems, Problem{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// Lint liest 'src' und prüft, dass alle Symbole in 'scope' oder clojure.core
+// aufgelöst werden können. Lokale Bindungen (let, fn, for, ...) werden dabei
+// berücksichtigt. Ein Syntaxfehler wird als einziges Problem gemeldet.
+func Lint(src string, scope Scope) []Problem {
+	nodes, err := Read(src)
+	if err != nil {
+		se := err.(*SyntaxError)
+		return []Problem{{Pos: se.Pos, Message: "syntax error: " + se.Message}}
+	}
+
+	l := &linter{}
+	for _, n := range nodes {
+		l.form(n, scope)
+	}
+	return l.problems
+}
+
+func (l *linter) forms(nodes []*Node, scope Scope) {
+	for _, n := range nodes {
+		l.form(n, scope)
+	}
+}
+
+func (l *linter) form(n *Node, scope Scope) {
+	switch n.Kind {
+	case Symbol:
+		l.symbol(n, scope)
+	case Vector, Map, Set:
+		l.forms(n.Children, scope)
+	case List:
+		l.list(n, scope)
+	}
+}
+
+func (l *linter) symbol(n *Node, scope Scope) {
+	name := n.Text
+	if _, ok := scope[name]; ok {
+		return
+	}
+	if _, ok := core[name]; ok {
+		return
+	}
+	// Qualifizierte Symbole, Java-Klassen und Interop werden nicht geprüft
+	if name == "&" || name == "." || strings.ContainsAny(name, "/.") {
+		return
+	}
+
+	if suggestion := closest(name, scope); suggestion != "" {
+		l.report(n.Pos, "unknown symbol '%s', did you mean '%s'?", name, suggestion)
+	} else {
+		l.report(n.Pos, "unknown symbol '%s'", name)
+	}
+}
+
+func (l *linter) list(n *Node, scope Scope) {
+	if len(n.Children) == 0 {
+		return
+	}
+	head, args := n.Children[0], n.Children[1:]
+	if head.Kind != Symbol {
+		l.forms(n.Children, scope)
+		return
+	}
+
+	switch {
+	case head.Text == "quote" || head.Text == "var":
+		return
+	case head.Text == "fn" || head.Text == "fn*":
+		l.fn(args, scope)
+		return
+	case head.Text == "." || head.Text == "new":
+		// (. obj method args) bzw. (new Klasse args)
+		if len(args) > 0 {
+			l.form(args[0], scope)
+		}
+		if len(args) > 2 {
+			l.forms(args[2:], scope)
+		}
+		return
+	case head.Text == "catch":
+		// (catch Klasse e body)
+		if len(args) > 2 {
+			l.forms(args[2:], scope.with(bindings(args[1])...))
+		}
+		return
+	case bindingForms[head.Text]:
+		if len(args) > 0 && args[0].Kind == Vector {
+			l.forms(args[1:], l.bindingVector(args[0], scope))
+			return
+		}
+	}
+
+	// Der Funktionskopf wird nicht geprüft. Unbekannte Funktionen sind
+	// Makros oder Funktionen der ISP.
+	l.forms(args, scope)
+}
+
+// bindingVector prüft die Ausdrücke eines Binding-Vektors [a x b y] und
+// liefert den Scope mit den gebundenen Namen. :let, :when und :while aus
+// 'for' und 'doseq' werden unterstützt.
+func (l *linter) bindingVector(v *Node, scope Scope) Scope {
+	c := v.Children
+	for i := 0; i+1 < len(c); i += 2 {
+		pattern, value := c[i], c[i+1]
+		if pattern.Kind == Keyword {
+			if pattern.Text == ":let" && value.Kind == Vector {
+				scope = l.bindingVector(value, scope)
+			} else {
+				l.form(value, scope)
+			}
+			continue
+		}
+		l.form(value, scope)
+		scope = scope.with(bindings(pattern)...)
+	}
+	return scope
+}
+
+func (l *linter) fn(args []*Node, scope Scope) {
+	if len(args) > 0 && args[0].Kind == Symbol {
+		scope = scope.with(args[0].Text)
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return
+	}
+	if args[0].Kind == Vector {
+		l.forms(args[1:], scope.with(bindings(args[0])...))
+		return
+	}
+	if args[0].Kind == List && len(args[0].Children) > 0 && args[0].Children[0].Kind == Vector {
+		// Mehrere Aritäten: (fn ([x] ...) ([x y] ...))
+		for _, arity := range args {
+			if arity.Kind == List && len(arity.Children) > 0 {
+				l.forms(arity.Children[1:], scope.with(bindings(arity.Children[0])...))
+			}
+		}
+		return
+	}
+	// #(...): die Argumente heißen %, %1, %2, ... und %&
+	l.forms(args, scope.with("%", "%&", "%1", "%2", "%3", "%4", "%5", "%6", "%7", "%8", "%9"))
+}
+
+// bindings liefert die in einem (destrukturierenden) Muster gebundenen Namen
+func bindings(pattern *Node) []string {
+	var names []string
+	switch pattern.Kind {
+	case Symbol:
+		if pattern.Text != "&" && pattern.Text != "_" {
+			names = append(names, pattern.Text)
+		}
+	case Vector:
+		for i := 0; i < len(pattern.Children); i++ {
+			c := pattern.Children[i]
+			if c.Kind == Keyword && c.Text == ":as" {
+				continue
+			}
+			names = append(names, bindings(c)...)
+		}
+	case Map:
+		for i := 0; i+1 < len(pattern.Children); i += 2 {
+			k, v := pattern.Children[i], pattern.Children[i+1]
+			if k.Kind == Keyword {
+				switch k.Text {
+				case ":keys", ":strs", ":syms":
+					for _, s := range v.Children {
+						if s.Kind == Symbol {
+							names = append(names, s.Text)
+						}
+					}
+				case ":as":
+					names = append(names, bindings(v)...)
+				}
+				continue
+			}
+			names = append(names, bindings(k)...)
+		}
+	}
+	return names
+}
+
+// closest liefert den Namen aus 'scope' mit dem kleinsten Editierabstand zu
+// 'name', wenn dieser höchstens 2 beträgt
+func closest(name string, scope Scope) string {
+	candidates := make([]string, 0, len(scope))
+	for n := range scope {
+		candidates = append(candidates, n)
+	}
+	sort.Strings(candidates)
+
+	best, distance := "", 3
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(c)); d < distance {
+			best, distance = c, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// LintProcess prüft alle aktuellen Parameter der Data-Mappings und alle
+// Bedingungen der Transitionen von 'p' gegen die formalen Parameter und
+// Variablen des Prozesses.
+func LintProcess(p *processfile.Process) []processfile.Problem {
+	scope := ProcessScope(p)
+
+	var problems []processfile.Problem
+	for _, a := range p.Activities {
+		for _, dm := range a.Body.DataMappings {
+			for _, pr := range Lint(dm.ActualParameter.Text(), scope) {
+				problems = append(problems, processfile.Problem{
+					ActivityId: a.Id, Activity: a.Name, Element: "data-mapping", Id: dm.FormalParameter,
+					Line: pr.Pos.Line, Column: pr.Pos.Column, Message: pr.Message,
+				})
+			}
+		}
+		for _, t := range a.Transitions {
+			for _, pr := range Lint(t.Condition.Text(), scope) {
+				problems = append(problems, processfile.Problem{
+					ActivityId: a.Id, Activity: a.Name, Element: "condition", Id: t.Id,
+					Line: pr.Pos.Line, Column: pr.Pos.Column, Message: pr.Message,
+				})
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,442 @@
+// Package expression liest die Clojure-Ausdrücke der Data-Mappings und
+// Bedingungen einer Prozessdefinition und prüft deren Symbole.
+package expression
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind ist die Art eines gelesenen Ausdrucks
+type Kind int
+
+const (
+	List Kind = iota
+	Vector
+	Map
+	Set
+	Symbol
+	Keyword
+	String
+	Number
+	Character
+	Regex
+	Nil
+	Boolean
+)
+
+var kindNames = []string{"list", "vector", "map", "set", "symbol", "keyword", "string", "number", "character", "regex", "nil", "boolean"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Pos ist eine Position in einem Ausdruck. Line und Column sind 1-basiert,
+// Column zählt Zeichen, nicht Bytes.
+type Pos struct {
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Node ist ein gelesener Ausdruck.
+//
+// Text enthält bei Symbolen, Keywords, Zahlen und Zeichen das Token, bei
+// Strings und Regexen den Inhalt ohne Anführungszeichen. Listen, Vektoren,
+// Maps und Sets enthalten ihre Elemente in Children. Reader-Makros werden
+// aufgelöst: 'x wird zu (quote x), @x zu (deref x) und #(...) zu (fn* ...).
+type Node struct {
+	Kind     Kind
+	Pos      Pos
+	Text     string
+	Children []*Node
+}
+
+// IsSymbol prüft, ob 'n' das Symbol 'name' ist
+func (n *Node) IsSymbol(name string) bool {
+	return n != nil && n.Kind == Symbol && n.Text == name
+}
+
+func (n *Node) String() string {
+	switch n.Kind {
+	case List, Vector, Map, Set:
+		open, close := map[Kind]string{List: "(", Vector: "[", Map: "{", Set: "#{"}[n.Kind], map[Kind]string{List: ")", Vector: "]", Map: "}", Set: "}"}[n.Kind]
+		s := make([]string, len(n.Children))
+		for i, c := range n.Children {
+			s[i] = c.String()
+		}
+		return open + strings.Join(s, " ") + close
+	case String:
+		return fmt.Sprintf("%q", n.Text)
+	case Regex:
+		return fmt.Sprintf("#%q", n.Text)
+	}
+	return n.Text
+}
+
+// SyntaxError ist ein Fehler beim Lesen eines Ausdrucks
+type SyntaxError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+type reader struct {
+	src    string
+	offset int
+	pos    Pos
+}
+
+// Read liest alle Ausdrücke aus 'src'
+func Read(src string) ([]*Node, error) {
+	r := &reader{src: src, pos: Pos{1, 1}}
+	var nodes []*Node
+	for {
+		r.skipWhitespace()
+		if r.eof() {
+			return nodes, nil
+		}
+		n, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+}
+
+func (r *reader) eof() bool {
+	return r.offset >= len(r.src)
+}
+
+func (r *reader) peek() rune {
+	c, _ := utf8.DecodeRuneInString(r.src[r.offset:])
+	return c
+}
+
+func (r *reader) next() rune {
+	c, size := utf8.DecodeRuneInString(r.src[r.offset:])
+	r.offset += size
+	if c == '\n' {
+		r.pos.Line++
+		r.pos.Column = 1
+	} else {
+		r.pos.Column++
+	}
+	return c
+}
+
+func (r *reader) errorf(pos Pos, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func isWhitespace(c rune) bool {
+	return unicode.IsSpace(c) || c == ','
+}
+
+func isDelimiter(c rune) bool {
+	return isWhitespace(c) || strings.ContainsRune("()[]{}\";", c)
+}
+
+func (r *reader) skipWhitespace() {
+	for !r.eof() {
+		c := r.peek()
+		if c == ';' {
+			for !r.eof() && r.peek() != '\n' {
+				r.next()
+			}
+		} else if isWhitespace(c) {
+			r.next()
+		} else {
+			return
+		}
+	}
+}
+
+// read liest den nächsten Ausdruck. Bei #_ ist das Ergebnis nil.
+func (r *reader) read() (*Node, error) {
+	pos := r.pos
+	c := r.next()
+	switch c {
+	case '(':
+		return r.readCollection(List, ')', pos)
+	case '[':
+		return r.readCollection(Vector, ']', pos)
+	case '{':
+		n, err := r.readCollection(Map, '}', pos)
+		if err == nil && len(n.Children)%2 != 0 {
+			return nil, r.errorf(pos, "map literal must contain an even number of forms")
+		}
+		return n, err
+	case ')', ']', '}':
+		return nil, r.errorf(pos, "unmatched delimiter '%c'", c)
+	case '"':
+		s, err := r.readString(pos)
+		return &Node{Kind: String, Pos: pos, Text: s}, err
+	case '\\':
+		return r.readCharacter(pos)
+	case '\'':
+		return r.readWrapped("quote", pos)
+	case '`':
+		return r.readWrapped("quote", pos)
+	case '@':
+		return r.readWrapped("deref", pos)
+	case '~':
+		if !r.eof() && r.peek() == '@' {
+			r.next()
+			return r.readWrapped("unquote-splicing", pos)
+		}
+		return r.readWrapped("unquote", pos)
+	case '^':
+		// Metadaten werden gelesen und verworfen
+		if _, err := r.readForm(pos); err != nil {
+			return nil, err
+		}
+		return r.readForm(pos)
+	case '#':
+		return r.readDispatch(pos)
+	}
+
+	token := string(c) + r.readToken()
+	return r.classify(token, pos)
+}
+
+// readForm liest den nächsten Ausdruck, der kein #_ ist
+func (r *reader) readForm(start Pos) (*Node, error) {
+	for {
+		r.skipWhitespace()
+		if r.eof() {
+			return nil, r.errorf(start, "unexpected end of expression")
+		}
+		n, err := r.read()
+		if err != nil || n != nil {
+			return n, err
+		}
+	}
+}
+
+func (r *reader) readWrapped(name string, pos Pos) (*Node, error) {
+	n, err := r.readForm(pos)
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Kind: List, Pos: pos, Children: []*Node{{Kind: Symbol, Pos: pos, Text: name}, n}}, nil
+}
+
+func (r *reader) readCollection(kind Kind, close rune, pos Pos) (*Node, error) {
+	n := &Node{Kind: kind, Pos: pos, Children: []*Node{}}
+	for {
+		r.skipWhitespace()
+		if r.eof() {
+			return nil, r.errorf(pos, "unclosed %s, expected '%c'", kind, close)
+		}
+		if r.peek() == close {
+			r.next()
+			return n, nil
+		}
+		c, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			n.Children = append(n.Children, c)
+		}
+	}
+}
+
+func (r *reader) readString(pos Pos) (string, error) {
+	var s bytes.Buffer
+	for {
+		if r.eof() {
+			return "", r.errorf(pos, "unterminated string")
+		}
+		escPos := r.pos
+		c := r.next()
+		switch c {
+		case '"':
+			return s.String(), nil
+		case '\\':
+			if r.eof() {
+				return "", r.errorf(pos, "unterminated string")
+			}
+			e := r.next()
+			switch e {
+			case 'n':
+				s.WriteRune('\n')
+			case 't':
+				s.WriteRune('\t')
+			case 'r':
+				s.WriteRune('\r')
+			case 'b':
+				s.WriteRune('\b')
+			case 'f':
+				s.WriteRune('\f')
+			case '"', '\\':
+				s.WriteRune(e)
+			case 'u':
+				var code rune
+				for i := 0; i < 4; i++ {
+					if r.eof() {
+						return "", r.errorf(escPos, "invalid unicode escape")
+					}
+					d := r.next()
+					v := strings.IndexRune("0123456789abcdef", unicode.ToLower(d))
+					if v < 0 {
+						return "", r.errorf(escPos, "invalid unicode escape")
+					}
+					code = code*16 + rune(v)
+				}
+				s.WriteRune(code)
+			default:
+				return "", r.errorf(escPos, "unsupported escape character '\\%c'", e)
+			}
+		default:
+			s.WriteRune(c)
+		}
+	}
+}
+
+var characterNames = map[string]bool{
+	"newline": true, "space": true, "tab": true, "formfeed": true, "backspace": true, "return": true,
+}
+
+func (r *reader) readCharacter(pos Pos) (*Node, error) {
+	if r.eof() {
+		return nil, r.errorf(pos, "unexpected end of expression")
+	}
+	token := string(r.next()) + r.readToken()
+	if utf8.RuneCountInString(token) > 1 && !characterNames[token] &&
+		!(token[0] == 'u' && len(token) == 5) && !(token[0] == 'o' && len(token) <= 4) {
+		return nil, r.errorf(pos, "unsupported character '\\%s'", token)
+	}
+	return &Node{Kind: Character, Pos: pos, Text: "\\" + token}, nil
+}
+
+func (r *reader) readDispatch(pos Pos) (*Node, error) {
+	if r.eof() {
+		return nil, r.errorf(pos, "unexpected end of expression")
+	}
+	c := r.next()
+	switch c {
+	case '{':
+		return r.readCollection(Set, '}', pos)
+	case '(':
+		n, err := r.readCollection(List, ')', pos)
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Kind: List, Pos: pos, Children: []*Node{{Kind: Symbol, Pos: pos, Text: "fn*"}, n}}, nil
+	case '"':
+		s, err := r.readString(pos)
+		return &Node{Kind: Regex, Pos: pos, Text: s}, err
+	case '_':
+		_, err := r.readForm(pos)
+		return nil, err
+	case '\'':
+		return r.readWrapped("var", pos)
+	}
+	return nil, r.errorf(pos, "unsupported dispatch macro '#%c'", c)
+}
+
+func (r *reader) readToken() string {
+	start := r.offset
+	for !r.eof() && !isDelimiter(r.peek()) {
+		r.next()
+	}
+	return r.src[start:r.offset]
+}
+
+func isNumber(token string) bool {
+	t := strings.TrimLeft(token, "+-")
+	return len(t) > 0 && unicode.IsDigit(rune(t[0]))
+}
+
+func (r *reader) classify(token string, pos Pos) (*Node, error) {
+	switch {
+	case token == "nil":
+		return &Node{Kind: Nil, Pos: pos, Text: token}, nil
+	case token == "true" || token == "false":
+		return &Node{Kind: Boolean, Pos: pos, Text: token}, nil
+	case isNumber(token):
+		if !validNumber(token) {
+			return nil, r.errorf(pos, "invalid number '%s'", token)
+		}
+		return &Node{Kind: Number, Pos: pos, Text: token}, nil
+	case strings.HasPrefix(token, ":"):
+		if token == ":" || token == "::" || strings.HasSuffix(token, ":") || strings.HasSuffix(token, "/") {
+			return nil, r.errorf(pos, "invalid keyword '%s'", token)
+		}
+		return &Node{Kind: Keyword, Pos: pos, Text: token}, nil
+	}
+	if strings.HasSuffix(token, ":") || (strings.HasSuffix(token, "/") && token != "/") || strings.Contains(token, "::") {
+		return nil, r.errorf(pos, "invalid symbol '%s'", token)
+	}
+	return &Node{Kind: Symbol, Pos: pos, Text: token}, nil
+}
+
+// validNumber prüft Ganzzahlen, Dezimalzahlen, Brüche und Radix-Zahlen
+func validNumber(token string) bool {
+	t := strings.TrimLeft(token, "+-")
+	if len(t) < len(token)-1 {
+		return false
+	}
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "N"), "M")
+
+	if i := strings.IndexAny(t, "rR"); i > 0 {
+		for _, c := range t[i+1:] {
+			if !unicode.IsDigit(c) && !unicode.IsLetter(c) {
+				return false
+			}
+		}
+		return len(t) > i+1
+	}
+	if strings.HasPrefix(t, "0x") || strings.HasPrefix(t, "0X") {
+		for _, c := range t[2:] {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+		return len(t) > 2
+	}
+	if parts := strings.Split(t, "/"); len(parts) == 2 {
+		return digits(parts[0]) && digits(parts[1])
+	}
+
+	mantissa, exponent := t, ""
+	if i := strings.IndexAny(t, "eE"); i >= 0 {
+		mantissa, exponent = t[:i], strings.TrimLeft(t[i+1:], "+-")
+		if !digits(exponent) {
+			return false
+		}
+	}
+	parts := strings.Split(mantissa, ".")
+	switch len(parts) {
+	case 1:
+		return digits(parts[0])
+	case 2:
+		return digits(parts[0]) && (parts[1] == "" || digits(parts[1]))
+	}
+	return false
+}
+
+func digits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
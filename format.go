@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+)
+
+// processFiles liefert alle Prozessdateien unterhalb der Pfade 'paths'.
+// Versteckte Verzeichnisse werden übersprungen.
+func processFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if p != path && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(p) == ".process" {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readProcessFile liest und parst die Prozessdatei 'path'. Fehler enthalten
+// den Pfad der Datei.
+func readProcessFile(path string) (*processfile.Process, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := processfile.Parse(content)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", path, err))
+	}
+	return p, nil
+}
+
+// Format schreibt Prozessdateien in kanonischer Form. Mit der Option 'check'
+// werden nicht formatierte Dateien nur aufgelistet und der Exit-Code ist 1.
+func format(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := processFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	unformatted := 0
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		formatted, err := processfile.Format(content)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+		if bytes.Equal(content, formatted) {
+			continue
+		}
+
+		unformatted++
+		fmt.Println(file)
+		if c.Bool("check") {
+			continue
+		}
+		if err := (storage.OS{}).WriteFile(file, formatted, 0644); err != nil {
+			return err
+		}
+	}
+
+	if c.Bool("check") && unformatted > 0 {
+		return cli.NewExitError("", 1)
+	}
+	return nil
+}
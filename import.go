@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/bpmn"
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/scaffold"
+	"github.com/frericksm/pride/storage"
+)
+
+// Import übersetzt BPMN-Dateien in Prozessdateien. Die Ergebnisse werden
+// neben die BPMN-Dateien oder in das Verzeichnis 'output' geschrieben und
+// ausgegeben, Probleme der neuen Prozessdefinitionen auf stderr gemeldet.
+// Vorhandene Prozessdateien werden nicht überschrieben.
+func importProcesses(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		return cli.NewExitError("import erwartet mindestens eine BPMN-Datei", 1)
+	}
+	if c.String("id") != "" && len(paths) > 1 {
+		return cli.NewExitError("die Option 'id' ist nur für eine BPMN-Datei möglich", 1)
+	}
+
+	var mapping *bpmn.Mapping
+	if path := c.String("mapping"); path != "" {
+		var err error
+		if mapping, err = bpmn.LoadMapping(path); err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", path, err), 1)
+		}
+	}
+
+	for _, file := range paths {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		p, err := bpmn.Import(content, mapping)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+		if id := c.String("id"); id != "" {
+			p.Id, p.Name = id, scaffold.ProcessData("", id).Name
+		}
+		if err := scaffold.CheckProcessId(p.Id); err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+
+		target := strings.TrimSuffix(file, filepath.Ext(file)) + ".process"
+		if output := c.String("output"); output != "" {
+			target = filepath.Join(output, filepath.Base(target))
+		}
+		if _, err := os.Stat(target); err == nil {
+			return cli.NewExitError(fmt.Sprintf("%s existiert bereits", target), 2)
+		}
+		if err := (storage.OS{}).WriteFile(target, processfile.ToBytes(p), 0644); err != nil {
+			return err
+		}
+		fmt.Println(target)
+		for _, problem := range processfile.Validate(p) {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", target, problem)
+		}
+	}
+	return nil
+}
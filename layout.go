@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/layout"
+	"github.com/frericksm/pride/storage"
+)
+
+// Layout ordnet die Aktivitäten von Prozessdateien in Ebenen neu an. Nur die
+// node-graphics-info wird geändert, geänderte Dateien werden ausgegeben.
+func layoutProcesses(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		return cli.NewExitError("layout erwartet mindestens eine Datei oder ein Verzeichnis", 1)
+	}
+
+	files, err := processFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	options := layout.DefaultOptions
+	options.LayerGap = c.Int("layer-gap")
+	options.NodeGap = c.Int("node-gap")
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		result, err := layout.LayoutContent(content, options)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s: %s", file, err), 2)
+		}
+		if bytes.Equal(content, result) {
+			continue
+		}
+
+		fmt.Println(file)
+		// Atomar schreiben, die Datei behält ihre Rechte
+		if err := (storage.OS{}).WriteFile(file, result, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
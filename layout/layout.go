@@ -0,0 +1,268 @@
+// Package layout berechnet die Anordnung der Aktivitäten eines Prozesses in
+// Ebenen (nach Sugiyama): Zyklen werden aufgebrochen, die Aktivitäten den
+// Ebenen nach dem längsten Pfad vom START-Event zugeordnet, die Kreuzungen der
+// Transitionen mit der Baryzentermethode verringert und die Koordinaten so
+// gesetzt, dass jede Ebene unter der vorherigen zentriert ist.
+package layout
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+// Options steuert die Abstände des Layouts
+type Options struct {
+	// Linker und oberer Rand
+	MarginX, MarginY int
+	// Vertikaler Abstand zwischen zwei Ebenen
+	LayerGap int
+	// Horizontaler Abstand zwischen zwei Aktivitäten einer Ebene
+	NodeGap int
+	// Anzahl der Durchläufe der Kreuzungsminimierung
+	Sweeps int
+}
+
+// DefaultOptions sind die Abstände, die auch der Editor verwendet
+var DefaultOptions = Options{MarginX: 20, MarginY: 20, LayerGap: 40, NodeGap: 30, Sweeps: 8}
+
+// Größe der Events und Höhe und Mindestbreite der übrigen Aktivitäten
+const (
+	EVENT_SIZE      = 30
+	ACTIVITY_HEIGHT = 30
+	MIN_WIDTH       = 55
+	// Geschätzte Breite eines Zeichens des Namens
+	CHAR_WIDTH = 7
+)
+
+// node ist eine Aktivität oder ein Hilfsknoten auf einer Transition, die
+// mehrere Ebenen überspringt
+type node struct {
+	id            string
+	dummy         bool
+	width, height int
+	layer         int
+	order         float64
+	in, out       []*node
+}
+
+// size liefert die Größe der Aktivität 'a'
+func size(a *processfile.Activity) (int, int) {
+	if a.Body.ActivityType == "EVENT" {
+		return EVENT_SIZE, EVENT_SIZE
+	}
+	width := len([]rune(a.Name))*CHAR_WIDTH + 10
+	if width < MIN_WIDTH {
+		width = MIN_WIDTH
+	}
+	return width, ACTIVITY_HEIGHT
+}
+
+// Layout berechnet die node-graphics-info aller Aktivitäten von 'p', je
+// Aktivitäts-Id
+func Layout(p *processfile.Process, options Options) map[string]processfile.NodeGraphicsInfo {
+	nodes := make(map[string]*node)
+	var order []*node
+	for i := range p.Activities {
+		a := &p.Activities[i]
+		if _, present := nodes[a.Id]; present {
+			continue
+		}
+		w, h := size(a)
+		n := &node{id: a.Id, width: w, height: h}
+		nodes[a.Id] = n
+		order = append(order, n)
+	}
+
+	// Kanten ohne Selbstschleifen und Transitionen zu unbekannten Aktivitäten
+	type edge struct{ from, to *node }
+	var edges []edge
+	for i := range p.Activities {
+		a := &p.Activities[i]
+		for _, t := range a.Transitions {
+			to, present := nodes[t.To]
+			if !present || to == nodes[a.Id] {
+				continue
+			}
+			edges = append(edges, edge{nodes[a.Id], to})
+		}
+	}
+
+	// 1. Zyklen aufbrechen: Rückwärtskanten einer Tiefensuche, die bei den
+	// START-Events (und danach bei allen noch nicht besuchten Aktivitäten)
+	// beginnt, werden umgedreht
+	succ := make(map[*node][]*node)
+	for _, e := range edges {
+		succ[e.from] = append(succ[e.from], e.to)
+	}
+	state := make(map[*node]int)
+	back := make(map[[2]*node]bool)
+	var dfs func(n *node)
+	dfs = func(n *node) {
+		state[n] = 1
+		for _, m := range succ[n] {
+			switch state[m] {
+			case 0:
+				dfs(m)
+			case 1:
+				back[[2]*node{n, m}] = true
+			}
+		}
+		state[n] = 2
+	}
+	for i := range p.Activities {
+		if a := &p.Activities[i]; a.Body.EventType == "START" && state[nodes[a.Id]] == 0 {
+			dfs(nodes[a.Id])
+		}
+	}
+	for _, n := range order {
+		if state[n] == 0 {
+			dfs(n)
+		}
+	}
+	for _, e := range edges {
+		from, to := e.from, e.to
+		if back[[2]*node{from, to}] {
+			from, to = to, from
+		}
+		from.out = append(from.out, to)
+		to.in = append(to.in, from)
+	}
+
+	// 2. Ebenen nach dem längsten Pfad
+	var assign func(n *node) int
+	assigned := make(map[*node]bool)
+	assign = func(n *node) int {
+		if assigned[n] {
+			return n.layer
+		}
+		assigned[n] = true
+		for _, m := range n.in {
+			if l := assign(m) + 1; l > n.layer {
+				n.layer = l
+			}
+		}
+		return n.layer
+	}
+	layers := 0
+	for _, n := range order {
+		if l := assign(n) + 1; l > layers {
+			layers = l
+		}
+	}
+
+	// 3. Hilfsknoten für Kanten über mehrere Ebenen
+	all := append([]*node{}, order...)
+	for _, n := range order {
+		for i, m := range n.out {
+			prev := n
+			for l := n.layer + 1; l < m.layer; l++ {
+				d := &node{id: fmt.Sprintf("%s-%s-%d", n.id, m.id, l), dummy: true, layer: l, in: []*node{prev}}
+				if prev == n {
+					n.out[i] = d
+				} else {
+					prev.out = []*node{d}
+				}
+				all = append(all, d)
+				prev = d
+			}
+			if prev != n {
+				prev.out = []*node{m}
+				for j, k := range m.in {
+					if k == n {
+						m.in[j] = prev
+						break
+					}
+				}
+			}
+		}
+	}
+
+	// 4. Kreuzungen verringern: abwechselnd von oben und von unten nach dem
+	// Mittelwert der Positionen der Nachbarn sortieren
+	levels := make([][]*node, layers)
+	for _, n := range all {
+		n.order = float64(len(levels[n.layer]))
+		levels[n.layer] = append(levels[n.layer], n)
+	}
+	barycenter := func(n *node, neighbours []*node) float64 {
+		if len(neighbours) == 0 {
+			return n.order
+		}
+		sum := 0.0
+		for _, m := range neighbours {
+			sum += m.order
+		}
+		return sum / float64(len(neighbours))
+	}
+	sortLevel := func(level []*node, key func(n *node) float64) {
+		keys := make(map[*node]float64)
+		for _, n := range level {
+			keys[n] = key(n)
+		}
+		sort.SliceStable(level, func(i, j int) bool { return keys[level[i]] < keys[level[j]] })
+		for i, n := range level {
+			n.order = float64(i)
+		}
+	}
+	for sweep := 0; sweep < options.Sweeps; sweep++ {
+		if sweep%2 == 0 {
+			for l := 1; l < layers; l++ {
+				sortLevel(levels[l], func(n *node) float64 { return barycenter(n, n.in) })
+			}
+		} else {
+			for l := layers - 2; l >= 0; l-- {
+				sortLevel(levels[l], func(n *node) float64 { return barycenter(n, n.out) })
+			}
+		}
+	}
+
+	// 5. Koordinaten: die Ebenen untereinander, jede horizontal zentriert
+	widths := make([]int, layers)
+	heights := make([]int, layers)
+	max_width := 0
+	for l, level := range levels {
+		for i, n := range level {
+			if i > 0 {
+				widths[l] += options.NodeGap
+			}
+			widths[l] += n.width
+			if n.height > heights[l] {
+				heights[l] = n.height
+			}
+		}
+		if widths[l] > max_width {
+			max_width = widths[l]
+		}
+	}
+
+	infos := make(map[string]processfile.NodeGraphicsInfo)
+	y := options.MarginY
+	for l, level := range levels {
+		x := options.MarginX + (max_width-widths[l])/2
+		for _, n := range level {
+			if !n.dummy {
+				infos[n.id] = processfile.NodeGraphicsInfo{
+					CoordinateX: x,
+					CoordinateY: y + (heights[l]-n.height)/2,
+					Width:       n.width,
+					Height:      n.height,
+				}
+			}
+			x += n.width + options.NodeGap
+		}
+		y += heights[l] + options.LayerGap
+	}
+	return infos
+}
+
+// LayoutContent ordnet die Aktivitäten der Prozessdatei 'content' neu an.
+// Nur die node-graphics-info der Aktivitäten wird geändert.
+func LayoutContent(content []byte, options Options) ([]byte, error) {
+	p, err := processfile.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return processfile.SetGraphicsInfo(content, Layout(p, options))
+}
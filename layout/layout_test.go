@@ -0,0 +1,79 @@
+package layout_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/frericksm/pride/layout"
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestLayout(t *testing.T) {
+	content := processfile.FileContent("../processfile/testdata/A1.process")
+	result, err := layout.LayoutContent(content, layout.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Außer den node-graphics-info bleibt der Inhalt unverändert
+	strip := func(b []byte) []string {
+		var lines []string
+		for _, line := range strings.Split(string(b), "\n") {
+			if !strings.Contains(line, "node-graphics-info") {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+	if a, b := strip(content), strip(result); strings.Join(a, "\n") != strings.Join(b, "\n") {
+		t.Errorf("Expected only node-graphics-info to change, but was\n%s", result)
+	}
+
+	// Jede Aktivität liegt unterhalb ihrer Vorgänger und keine überlappt eine andere
+	p := processfile.FromBytes(result)
+	y := make(map[string]int)
+	for _, a := range p.Activities {
+		y[a.Id] = a.Body.NodeGraphicsInfo.CoordinateY
+	}
+	for _, a := range p.Activities {
+		for _, tr := range a.Transitions {
+			if y[tr.To] <= y[a.Id] {
+				t.Errorf("Expected %s below %s", tr.To, a.Id)
+			}
+		}
+	}
+	if pairs := processfile.Overlapping(p); len(pairs) != 0 {
+		t.Errorf("Expected no overlapping activities, but was %v", pairs)
+	}
+
+	// Ein zweites Layout ändert nichts
+	again, _ := layout.LayoutContent(result, layout.DefaultOptions)
+	if !bytes.Equal(result, again) {
+		t.Errorf("Expected layout to be stable")
+	}
+}
+
+func TestLayoutCycle(t *testing.T) {
+	content := []byte(`<process id="p">
+  <activities>
+    <activity id="s"><body activity-type="EVENT" event-type="START"/><transitions><transition id="1" to="a"/></transitions></activity>
+    <activity id="a" name="A"><body activity-type="IMPLEMENTATION" implementation-type="TASK"></body><transitions><transition id="1" to="b"/><transition id="2" to="e"/></transitions></activity>
+    <activity id="b" name="B"><body activity-type="IMPLEMENTATION" implementation-type="TASK"></body><transitions><transition id="1" to="a"/></transitions></activity>
+    <activity id="e"><body activity-type="EVENT" event-type="END"></body></activity>
+  </activities>
+</process>`)
+	result, err := layout.LayoutContent(content, layout.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := processfile.FromBytes(result)
+	for _, a := range p.Activities {
+		if a.Id != "s" && a.Body.NodeGraphicsInfo.Height == 0 {
+			t.Errorf("Expected node-graphics-info for %s, but was\n%s", a.Id, result)
+		}
+	}
+	if p.Activities[0].Body.NodeGraphicsInfo.Height != 0 {
+		t.Errorf("Expected no node-graphics-info for the empty body of s")
+	}
+}
@@ -2,20 +2,24 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"log"
 	"net/http"
+	"time"
 
 
 	"github.com/urfave/cli"
 	"os"
 	"github.com/neelance/graphql-go"
 	"github.com/neelance/graphql-go/relay"
-	"github.com/fsnotify/fsnotify"
 
 	"github.com/frericksm/pride/bundle"
+	"github.com/frericksm/pride/layout"
+	"github.com/frericksm/pride/processfile"
 	"github.com/frericksm/pride/resource"
+	"github.com/frericksm/pride/storage"
 	"github.com/frericksm/pride/utils"
 	"github.com/frericksm/pride/context"
 )
@@ -45,18 +49,32 @@ func bundleRootDir(c *cli.Context) string {
 	return cwd
 }
 
-func createFileWatcher() *fsnotify.Watcher {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		panic(err)
+// Erzeugt den Storage für die Option 'watch'. Bei 'none' werden Änderungen
+// nicht beobachtet.
+func createStorage(c *cli.Context) (fs storage.Storage, watch bool, err error) {
+	switch c.String("watch") {
+	case "fsnotify":
+		return storage.OS{}, true, nil
+	case "poll":
+		return storage.OS{PollInterval: c.Duration("poll-interval")}, true, nil
+	case "none":
+		return storage.OS{}, false, nil
 	}
-	return watcher
+	return nil, false, errors.New(fmt.Sprintf("unbekannter Watcher '%s'", c.String("watch")))
+}
+
+// Die Option für das Template-Verzeichnis neuer Bundles und Prozesse
+var templatesFlag = cli.StringFlag{
+	Name: "templates",
+	Usage: "Das `VERZEICHNIS` der Templates für neue Bundles und Prozesse",
 }
 
 // Server startet einen HTTP-Server der 
 // a) unter der URI "/query" einen GraphQL-Endpunkt bereitstellt 
 // b) unter der URI "/" eine GraphiQL-Oberfläche anzeigt
 // c) under der URI "/bundles" das Lesen und Schreiben von Dateien eines Bundles ermöglicht.
+// d) unter der URI "/health" den Zustand des Index als JSON liefert
+// e) unter der URI "/schema/process.json" das JSON-Schema der Prozessdefinitionen liefert
 func serve(c *cli.Context) error {
 	http.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write(page)
@@ -64,9 +82,24 @@ func serve(c *cli.Context) error {
 
 	bundleRootDir := bundleRootDir(c)
 
-	w := createFileWatcher()
-	defer w.Close()
-	bundle.StartWatching(w, bundleRootDir)
+	fs, watch, err := createStorage(c)
+	if err != nil {
+		return err
+	}
+	if watch {
+		w, err := bundle.StartWatching(fs, bundleRootDir, bundle.WatchOptions{
+			FormatOnSave: c.Bool("fmt-on-save"),
+			QuietPeriod: c.Duration("quiet-period"),
+		})
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+	} else if err := bundle.StartRefreshing(fs, bundleRootDir, c.Duration("refresh-interval")); err != nil {
+		return err
+	}
+
+	bundle.StartTrashRetention(fs, bundleRootDir, c.Duration("trash-retention"))
 
 	log.Println(fmt.Sprintf("Serving directory: %s", bundleRootDir))
 	
@@ -74,6 +107,8 @@ func serve(c *cli.Context) error {
 	
 	ctxHandler1 := context.Handler{
 		BundleRootDir: bundleRootDir,
+		Storage: fs,
+		Templates: c.String("templates"),
 		Handler: &relay.Handler{
 			Schema: schema,
 		},
@@ -82,9 +117,22 @@ func serve(c *cli.Context) error {
 
 	ctxHandler2 := context.Handler{
 		BundleRootDir: bundleRootDir,
+		Storage: fs,
 		Handler: &resource.Handler{},
 		}
 	http.Handle("/bundles/", &ctxHandler2)
+
+	ctxHandler3 := context.Handler{
+		BundleRootDir: bundleRootDir,
+		Storage: fs,
+		Handler: &bundle.HealthHandler{},
+	}
+	http.Handle("/health", &ctxHandler3)
+
+	http.Handle("/schema/process.json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write([]byte(processfile.JSONSchema))
+	}))
 	
 	port := fmt.Sprintf(":%d",c.Int("port"))
 	log.Println(fmt.Sprintf("Listening on port %d", c.Int("port")))
@@ -128,6 +176,71 @@ func main() {
 					Usage: `Der ` + "`PORT`" + ` an dem sich der Server bindet. Muß ein Wert 
                          zwischen 8190 bis 9190 sein.`,
 				},
+				cli.BoolFlag{
+					Name: "fmt-on-save",
+					Usage: "Gespeicherte Prozessdateien kanonisch formatieren (siehe 'fmt')",
+				},
+				cli.DurationFlag{
+					Name: "quiet-period",
+					Value: 500 * time.Millisecond,
+					Usage: `Änderungen eines Bundles werden gesammelt, bis für diese ` + "`DAUER`" + `
+                         keine weitere Änderung eintrifft. 0 schaltet das Sammeln ab`,
+				},
+				cli.StringFlag{
+					Name: "watch",
+					Value: "fsnotify",
+					Usage: `Die ` + "`ART`" + `, wie Änderungen erkannt werden: 'fsnotify' (Events
+                         des Betriebssystems), 'poll' (regelmäßiges Lesen, für
+                         NFS- und SMB-Laufwerke) oder 'none'`,
+				},
+				cli.DurationFlag{
+					Name: "poll-interval",
+					Value: 2 * time.Second,
+					Usage: "Die `DAUER` zwischen zwei Abfragen bei --watch=poll",
+				},
+				cli.DurationFlag{
+					Name: "refresh-interval",
+					Value: time.Minute,
+					Usage: `Bei --watch=none wird der Index nach dieser ` + "`DAUER`" + ` neu gebaut.
+                         0 baut ihn nur beim Start`,
+				},
+				cli.DurationFlag{
+					Name: "trash-retention",
+					Value: 30 * 24 * time.Hour,
+					Usage: `Gelöschte Bundles, Verzeichnisse und Dateien werden nach dieser
+                         ` + "`DAUER`" + ` endgültig aus dem Papierkorb (.trash) entfernt.
+                         0 behält sie, bis sie mit 'purge' gelöscht werden`,
+				},
+				templatesFlag,
+			},
+		},
+		{
+			Name:    "new",
+			Usage:   "Legt ein Bundle oder einen Prozess an",
+			Description:
+			`Legt ein Bundle mit META-INF/MANIFEST.MF oder eine Prozessdatei mit einem
+   START- und einem END-Event und einer Transition dazwischen an. Die
+   Verzeichnisse des Packages der Prozess-Id werden dabei angelegt.
+
+   Ein Template-Verzeichnis (--templates) kann im Unterverzeichnis 'bundle'
+   die Dateien neuer Bundles und in 'process.process' die Vorlage neuer
+   Prozesse enthalten. Die Templates können {{.Bundle}}, {{.Id}}, {{.Name}},
+   {{.Package}} und {{uuid}} verwenden.`,
+			Subcommands: []cli.Command{
+				{
+					Name:      "bundle",
+					Usage:     "Legt ein Bundle im Verzeichnis 'dir' an",
+					ArgsUsage: "name",
+					Action:    newBundle,
+					Flags:     []cli.Flag{templatesFlag},
+				},
+				{
+					Name:      "process",
+					Usage:     "Legt eine Prozessdatei in einem Bundle an",
+					ArgsUsage: "bundle id",
+					Action:    newProcess,
+					Flags:     []cli.Flag{templatesFlag},
+				},
 			},
 		},
 		{
@@ -135,6 +248,260 @@ func main() {
 			Usage:   "Builds the bundle jar file",
 			Action:  build,
 		},
+		{
+			Name:      "diff",
+			Usage:     "Vergleicht zwei Prozessdateien semantisch",
+			ArgsUsage: "a.process b.process",
+			Description:
+			`Listet hinzugefügte und entfernte Aktivitäten, geänderte Transitionen
+   und Bedingungen, geänderte Data-Mappings und formale Parameter. Reine
+   Layout-Änderungen (node-graphics-info) werden nur mit der Option
+   'layout' ausgegeben.`,
+			Action:  diff,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name: "layout, l",
+					Usage: "Auch Layout-Änderungen ausgeben",
+				},
+			},
+		},
+		{
+			Name:      "merge",
+			Usage:     "Dreiwege-Merge von Prozessdateien (Git-Merge-Driver)",
+			ArgsUsage: "base ours theirs",
+			Description:
+			`Führt die Änderungen von 'ours' und 'theirs' gegenüber 'base' auf Ebene
+   der Aktivitäten, Transitionen und formalen Parameter zusammen und schreibt
+   das Ergebnis nach 'ours'. Konflikte werden auf stderr gemeldet.
+
+   Verwendung als Git-Merge-Driver (.git/config bzw. .gitattributes):
+
+     [merge "pride"]
+         driver = pride merge %O %A %B
+
+     *.process merge=pride`,
+			Action:  merge,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name: "json",
+					Usage: "Konflikte als JSON ausgeben",
+				},
+			},
+		},
+		{
+			Name:      "fmt",
+			Usage:     "Formatiert Prozessdateien kanonisch",
+			ArgsUsage: "[dateien oder verzeichnisse]",
+			Description:
+			`Schreibt alle Prozessdateien unterhalb der angegebenen Pfade (Default:
+   aktuelles Verzeichnis) in einer kanonischen Form: feste Reihenfolge der
+   Attribute, nach Namen sortierte Properties, CDATA für Beschreibungen und
+   aktuelle Parameter und einheitliche Einrückung. Geänderte Dateien werden
+   ausgegeben.`,
+			Action:  format,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name: "check",
+					Usage: `Dateien nicht schreiben, sondern nur auflisten. Exit-Code 1,
+                         wenn eine Datei nicht kanonisch formatiert ist`,
+				},
+			},
+		},
+		{
+			Name:      "layout",
+			Usage:     "Ordnet die Aktivitäten von Prozessdateien automatisch an",
+			ArgsUsage: "dateien oder verzeichnisse",
+			Description:
+			`Berechnet die Koordinaten und Größen aller Aktivitäten der Prozessdateien
+   unterhalb der angegebenen Pfade neu: die Aktivitäten werden entlang der
+   Transitionen in Ebenen von oben nach unten angeordnet, mit möglichst
+   wenigen Kreuzungen. Nur die node-graphics-info wird geändert, der übrige
+   Inhalt der Dateien bleibt unverändert. Geänderte Dateien werden
+   ausgegeben.`,
+			Action:  layoutProcesses,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name: "layer-gap",
+					Value: layout.DefaultOptions.LayerGap,
+					Usage: "Vertikaler Abstand zwischen zwei Ebenen",
+				},
+				cli.IntFlag{
+					Name: "node-gap",
+					Value: layout.DefaultOptions.NodeGap,
+					Usage: "Horizontaler Abstand zwischen zwei Aktivitäten einer Ebene",
+				},
+			},
+		},
+		{
+			Name:      "convert",
+			Usage:     "Wandelt Prozessdefinitionen zwischen XML, JSON und YAML um",
+			ArgsUsage: "dateien",
+			Description:
+			`Liest jede Datei im Format ihrer Endung (.json, .yaml oder .yml, sonst
+   XML) und schreibt sie im Format 'to' mit der Endung .json, .yaml oder
+   .process neben die Datei (oder in das Verzeichnis 'output').
+   Beschreibungen, Ausdrücke und Bedingungen sind in JSON und YAML einfache
+   Strings. Das JSON-Schema liefert der Server unter /schema/process.json.
+   Die Umwandlung von XML in JSON oder YAML und zurück ergibt die Datei in
+   kanonischer Form (siehe fmt).`,
+			Action:  convert,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: "to",
+					Usage: "Das Zielformat: json, yaml oder xml",
+				},
+				cli.StringFlag{
+					Name: "output, o",
+					Usage: "Das `VERZEICHNIS` der umgewandelten Dateien",
+				},
+				cli.BoolFlag{
+					Name: "stdout",
+					Usage: "Das Ergebnis ausgeben, statt es zu schreiben",
+				},
+			},
+		},
+		{
+			Name:      "export",
+			Usage:     "Exportiert Prozessdateien nach BPMN 2.0",
+			ArgsUsage: "dateien oder verzeichnisse",
+			Description:
+			`Übersetzt die Prozessdateien unterhalb der angegebenen Pfade in BPMN 2.0
+   mit Diagramm (BPMN DI), z.B. zur Anzeige in Standard-BPMN-Werkzeugen.
+   START- und END-Events werden zu Start- und End-Events, TASK-Aktivitäten
+   zu Service-Tasks, SUB_FLOW-Aktivitäten zu Call-Activities und
+   Transitionen zu Sequence-Flows mit ihren Bedingungen. Das Ergebnis wird
+   mit der Endung .bpmn neben die Prozessdatei (oder in das Verzeichnis
+   'output') geschrieben.`,
+			Action:  export,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: "format",
+					Value: "bpmn",
+					Usage: "Das Zielformat, derzeit nur bpmn",
+				},
+				cli.StringFlag{
+					Name: "output, o",
+					Usage: "Das `VERZEICHNIS` der exportierten Dateien",
+				},
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "Importiert BPMN-2.0-Dateien als Prozessdateien",
+			ArgsUsage: "dateien.bpmn",
+			Description:
+			`Übersetzt den ersten Prozess jeder BPMN-Datei in eine Prozessdatei mit
+   neu erzeugten UUIDs. Start- und End-Events werden zu START- und
+   END-Events, Call-Activities zu SUB_FLOW-Aktivitäten und alle Tasks zu
+   TASK-Aktivitäten. Gateways und Zwischen-Events entfallen, ihre
+   Sequence-Flows werden zu Transitionen mit den verknüpften Bedingungen.
+   Das Layout wird aus BPMN DI übernommen. Die implementation-ref-id der
+   Tasks legt das Mapping fest (JSON oder YAML):
+
+     tasks:
+       Protokoll schreiben: de.fi.prosupport.task.ProtokollEintragSchreiben
+
+   Gesucht wird nach dem Attribut 'implementation', dem Namen und der Id
+   des Tasks. Das Ergebnis wird mit der Endung .process neben die
+   BPMN-Datei (oder in das Verzeichnis 'output') geschrieben.`,
+			Action:  importProcesses,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: "mapping, m",
+					Usage: "Die `DATEI` mit dem Mapping der Tasks",
+				},
+				cli.StringFlag{
+					Name: "id",
+					Usage: "Die `ID` des Prozesses, statt der Id aus der BPMN-Datei",
+				},
+				cli.StringFlag{
+					Name: "output, o",
+					Usage: "Das `VERZEICHNIS` der importierten Dateien",
+				},
+			},
+		},
+		{
+			Name:      "validate",
+			Usage:     "Prüft Prozessdateien vor dem Deployment",
+			ArgsUsage: "[dateien oder verzeichnisse]",
+			Description:
+			`Prüft die Struktur der Prozessdateien unterhalb der angegebenen Pfade
+   (Default: aktuelles Verzeichnis) und liest die Clojure-Ausdrücke aller
+   Data-Mappings und Bedingungen. Gemeldet werden ungültiges XML, doppelte
+   Ids und Namen, fehlende START- und END-Events, Transitionen zu unbekannten
+   Aktivitäten, Syntaxfehler in Ausdrücken mit ihrer Position und Symbole,
+   die weder formale Parameter noch Variablen des Prozesses sind.
+
+   Die Data-Mappings der TASK-Aktivitäten werden gegen den Task-Katalog
+   (META-INF/tasks.json oder tasks.yaml) des Bundles geprüft: unbekannte und
+   fehlende Pflichtparameter, Literale vom falschen Typ und OUT-Parameter
+   ohne Variable.
+
+   Die Data-Mappings der SUB_FLOW-Aktivitäten werden gegen die formalen
+   Parameter der aufgerufenen Prozesse geprüft, die in den Bundles des
+   Verzeichnisses 'dir' gesucht werden: Mappings auf unbekannte Parameter,
+   fehlende Pflichtparameter und OUT- und INOUT-Parameter ohne Variable.`,
+			Action:  validate,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: "catalog",
+					Usage: "Der Task-Katalog (`DATEI`) für alle Dateien statt der Kataloge der Bundles",
+				},
+			},
+		},
+		{
+			Name:      "tasks",
+			Usage:     "Listet die Aufrufe von Tasks",
+			ArgsUsage: "[task-id]",
+			Description:
+			`Gibt für jeden Task (Aktivität mit implementation-type TASK) der Bundles
+   im Verzeichnis 'dir' aus, wie oft er aufgerufen wird, und je Aufruf das
+   Bundle, den Prozess, die Aktivität und die formalen Parameter der
+   Data-Mappings. Ist eine Task-Id angegeben, z.B.
+   de.fi.prosupport.task.ProtokollEintragSchreiben, nur deren Aufrufe.`,
+			Action:  tasks,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name: "json",
+					Usage: "Aufrufe als JSON ausgeben",
+				},
+			},
+		},
+		{
+			Name:      "simulate",
+			Usage:     "Simuliert einen Prozess mit gestubten Tasks",
+			ArgsUsage: "datei.process",
+			Description:
+			`Führt den Prozess ab seinem START-Event aus, wertet die Bedingungen der
+   Transitionen aus und simuliert aufgerufene Prozesse (SUB_FLOW) rekursiv.
+   Aufgerufene Prozesse werden in den Bundles des Verzeichnisses 'dir'
+   gesucht. Eingaben und die Ausgaben der Tasks stehen im Fixture (JSON
+   oder YAML):
+
+     inputs:
+       Prozessname: A1
+     tasks:
+       de.fi.prosupport.task.ProtokollEintragSchreiben:
+         outputs:
+           protokollId: p-1
+     processes:
+       de.michael.A2: {outputs: {ergebnis: 42}}
+
+   Ausgegeben werden das Protokoll der Aktivitäten und der Endzustand der
+   Variablen und Parameter.`,
+			Action:  simulateProcess,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: "fixture, f",
+					Usage: "Die `DATEI` mit Eingaben und Stubs",
+				},
+				cli.IntFlag{
+					Name: "max-steps",
+					Value: 10000,
+					Usage: "Maximale Anzahl ausgeführter Aktivitäten",
+				},
+			},
+		},
 	}
 
 	
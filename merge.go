@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+)
+
+// Merge führt einen Dreiwege-Merge von Prozessdateien durch und schreibt das
+// Ergebnis nach 'ours'. Damit ist das Kommando als Git-Merge-Driver nutzbar:
+//
+//	[merge "pride"]
+//		name = pride process merge
+//		driver = pride merge %O %A %B
+//
+// Konflikte werden auf stderr gemeldet, der Exit-Code ist dann 1. 'ours'
+// enthält dann wie bei git Konfliktmarker: oben das Ergebnis mit den
+// Konflikten zugunsten von ours, unten zugunsten von theirs entschieden. Bis
+// die Konflikte aufgelöst sind, ist die Datei kein gültiges XML. Kann eine der
+// Dateien nicht gelesen werden, ist der Exit-Code 2 und 'ours' bleibt
+// unverändert.
+func merge(c *cli.Context) error {
+	if c.NArg() != 3 {
+		return errors.New("merge erwartet die Dateien base, ours und theirs")
+	}
+	ours_path := c.Args().Get(1)
+
+	var versions [3]*processfile.Process
+	for i := range versions {
+		p, err := readProcessFile(c.Args().Get(i))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 2)
+		}
+		versions[i] = p
+	}
+	base, ours, theirs := versions[0], versions[1], versions[2]
+
+	result, conflicts := processfile.Merge(base, ours, theirs)
+
+	content := processfile.ToBytes(result)
+	if len(conflicts) > 0 {
+		resolved_theirs, _ := processfile.Merge(base, theirs, ours)
+		content = conflictMarkers(content, processfile.ToBytes(resolved_theirs))
+	}
+	if err := (storage.OS{}).WriteFile(ours_path, content, 0644); err != nil {
+		return err
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	if c.Bool("json") {
+		report, err := json.MarshalIndent(conflicts, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, string(report))
+	} else {
+		for _, conflict := range conflicts {
+			fmt.Fprintln(os.Stderr, conflict)
+		}
+	}
+	return cli.NewExitError(fmt.Sprintf("%d Konflikt(e) in %s", len(conflicts), ours_path), 1)
+}
+
+// conflictMarkers setzt 'ours' und 'theirs' wie git in Konfliktmarker
+func conflictMarkers(ours []byte, theirs []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("<<<<<<< ours\n")
+	b.Write(ours)
+	b.WriteString("=======\n")
+	b.Write(theirs)
+	b.WriteString(">>>>>>> theirs\n")
+	return b.Bytes()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/scaffold"
+	"github.com/frericksm/pride/storage"
+)
+
+// NewBundle legt ein Bundle mit META-INF/MANIFEST.MF im Verzeichnis 'dir' an
+func newBundle(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("new bundle erwartet genau einen Namen", 1)
+	}
+	dir, err := scaffold.CreateBundle(storage.OS{}, bundleRootDir(c), c.Args().Get(0), c.String("templates"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Println(dir)
+	return nil
+}
+
+// NewProcess legt eine Prozessdatei mit START- und END-Event in einem Bundle
+// des Verzeichnisses 'dir' an
+func newProcess(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("new process erwartet ein Bundle und eine Prozess-Id", 1)
+	}
+	path, err := scaffold.CreateProcess(storage.OS{}, bundleRootDir(c), c.Args().Get(0), c.Args().Get(1), c.String("templates"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Println(path)
+	return nil
+}
@@ -0,0 +1,259 @@
+package processfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Die Namen des Task-Katalogs im Verzeichnis META-INF eines Bundles
+var TaskCatalogFiles = []string{"tasks.json", "tasks.yaml", "tasks.yml"}
+
+// Die Typen, die ein Parameter im Task-Katalog haben kann. 'any' (oder ein
+// fehlender Typ) wird nicht geprüft.
+var taskParameterTypes = map[string]struct{}{
+	"": {}, "any": {}, "string": {}, "number": {}, "boolean": {}, "list": {}, "map": {},
+}
+
+// TaskParameter ist ein formaler Parameter eines Tasks
+type TaskParameter struct {
+	Name string `json:"name" yaml:"name"`
+	// IN, OUT oder INOUT, Default ist IN
+	Direction string `json:"direction,omitempty" yaml:"direction"`
+	Required  bool   `json:"required,omitempty" yaml:"required"`
+	// string, number, boolean, list, map oder any
+	Type        string `json:"type,omitempty" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description"`
+}
+
+// TaskDefinition beschreibt einen Task mit der Id (der implementation-ref-id
+// der TASK-Aktivitäten) und seinen formalen Parametern
+type TaskDefinition struct {
+	Id          string          `json:"id" yaml:"id"`
+	Description string          `json:"description,omitempty" yaml:"description"`
+	Parameters  []TaskParameter `json:"parameters" yaml:"parameters"`
+}
+
+// Parameter liefert den formalen Parameter 'name' des Tasks
+func (t *TaskDefinition) Parameter(name string) (*TaskParameter, bool) {
+	for i := range t.Parameters {
+		if t.Parameters[i].Name == name {
+			return &t.Parameters[i], true
+		}
+	}
+	return nil, false
+}
+
+// TaskCatalog ist der Katalog der Tasks eines Bundles, z.B. in
+// META-INF/tasks.yaml:
+//
+//	tasks:
+//	  - id: de.fi.prosupport.task.ProtokollEintragSchreiben
+//	    parameters:
+//	      - {name: kategorie, required: true, type: string}
+//	      - {name: protokollId, direction: INOUT}
+type TaskCatalog struct {
+	Tasks []TaskDefinition `json:"tasks" yaml:"tasks"`
+}
+
+// Task liefert die Definition des Tasks 'id'
+func (c *TaskCatalog) Task(id string) (*TaskDefinition, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for i := range c.Tasks {
+		if c.Tasks[i].Id == id {
+			return &c.Tasks[i], true
+		}
+	}
+	return nil, false
+}
+
+// MergeTaskCatalogs fasst die Kataloge 'catalogs' zusammen. Ist ein Task in
+// mehreren Katalogen definiert, gilt die erste Definition. Die Tasks sind
+// nach Id sortiert.
+func MergeTaskCatalogs(catalogs ...*TaskCatalog) *TaskCatalog {
+	merged := &TaskCatalog{Tasks: []TaskDefinition{}}
+	ids := make(map[string]struct{})
+	for _, c := range catalogs {
+		if c == nil {
+			continue
+		}
+		for _, t := range c.Tasks {
+			if _, present := ids[t.Id]; present {
+				continue
+			}
+			ids[t.Id] = struct{}{}
+			merged.Tasks = append(merged.Tasks, t)
+		}
+	}
+	sort.Slice(merged.Tasks, func(i, j int) bool { return merged.Tasks[i].Id < merged.Tasks[j].Id })
+	return merged
+}
+
+// IsTaskCatalog prüft, ob der Pfad 'rel' (relativ zum Bundle-Verzeichnis)
+// ein Task-Katalog ist
+func IsTaskCatalog(rel string) bool {
+	dir, name := filepath.Split(filepath.ToSlash(rel))
+	if strings.TrimPrefix(dir, "/") != "META-INF/" {
+		return false
+	}
+	for _, n := range TaskCatalogFiles {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTaskCatalog liest einen Task-Katalog im JSON- oder YAML-Format (nach
+// Endung von 'name') und prüft ihn auf doppelte Ids und Parameter, ungültige
+// Richtungen und unbekannte Typen
+func ParseTaskCatalog(name string, content []byte) (*TaskCatalog, error) {
+	var c TaskCatalog
+	var err error
+	switch filepath.Ext(name) {
+	case ".json":
+		err = json.Unmarshal(content, &c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &c)
+	default:
+		err = errors.New(fmt.Sprintf("unsupported task catalog format '%s'", filepath.Ext(name)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{})
+	for i := range c.Tasks {
+		t := &c.Tasks[i]
+		if t.Id == "" {
+			return nil, errors.New(fmt.Sprintf("task %d: missing id", i+1))
+		}
+		if _, present := ids[t.Id]; present {
+			return nil, errors.New(fmt.Sprintf("task %s: duplicate id", t.Id))
+		}
+		ids[t.Id] = struct{}{}
+
+		names := make(map[string]struct{})
+		for j := range t.Parameters {
+			f := &t.Parameters[j]
+			if f.Name == "" {
+				return nil, errors.New(fmt.Sprintf("task %s: parameter %d: missing name", t.Id, j+1))
+			}
+			if _, present := names[f.Name]; present {
+				return nil, errors.New(fmt.Sprintf("task %s: duplicate parameter %s", t.Id, f.Name))
+			}
+			names[f.Name] = struct{}{}
+			if f.Direction == "" {
+				f.Direction = "IN"
+			}
+			switch f.Direction {
+			case "IN", "OUT", "INOUT":
+			default:
+				return nil, errors.New(fmt.Sprintf("task %s: parameter %s: invalid direction '%s'", t.Id, f.Name, f.Direction))
+			}
+			if _, present := taskParameterTypes[f.Type]; !present {
+				return nil, errors.New(fmt.Sprintf("task %s: parameter %s: unknown type '%s'", t.Id, f.Name, f.Type))
+			}
+		}
+		if t.Parameters == nil {
+			t.Parameters = []TaskParameter{}
+		}
+	}
+	return &c, nil
+}
+
+var number_literal = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?M?N?$`)
+var symbol_literal = regexp.MustCompile(`^[^\s()\[\]{}"';0-9:][^\s()\[\]{}"';]*$`)
+
+// literalType liefert den Typ eines Ausdrucks, der ein Literal ist: string,
+// number, boolean, list, map oder nil. Für andere Ausdrücke ist das Ergebnis
+// leer.
+func literalType(expr string) string {
+	expr = strings.TrimSpace(expr)
+	switch {
+	case expr == "nil":
+		return "nil"
+	case expr == "true" || expr == "false":
+		return "boolean"
+	case len(expr) >= 2 && strings.HasPrefix(expr, `"`) && strings.HasSuffix(expr, `"`):
+		return "string"
+	case number_literal.MatchString(expr):
+		return "number"
+	case strings.HasPrefix(expr, "[") || strings.HasPrefix(expr, "'("):
+		return "list"
+	case strings.HasPrefix(expr, "{"):
+		return "map"
+	}
+	return ""
+}
+
+// ValidateTasks prüft die Data-Mappings aller TASK-Aktivitäten von 'p' gegen
+// den Katalog 'c': unbekannte formale Parameter, fehlende Pflichtparameter,
+// Literale vom falschen Typ und OUT-Parameter, denen keine Variable
+// zugeordnet ist. Tasks, die nicht im Katalog stehen, werden nicht geprüft.
+func ValidateTasks(p *Process, c *TaskCatalog) []Problem {
+	var problems []Problem
+	for _, a := range p.Activities {
+		if a.Body.ImplementationType != "TASK" {
+			continue
+		}
+		if t, found := c.Task(a.Body.ImplementationRefId); found {
+			problems = append(problems, validateMappings(a, "task", t)...)
+		}
+	}
+	return problems
+}
+
+// validateMappings prüft die Data-Mappings der Aktivität 'a' gegen die
+// Signatur 't' des aufgerufenen Tasks oder Prozesses ('kind')
+func validateMappings(a Activity, kind string, t *TaskDefinition) []Problem {
+	var problems []Problem
+	add := func(id string, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			ActivityId: a.Id,
+			Activity:   a.Name,
+			Element:    "data-mapping",
+			Id:         id,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	mapped := make(map[string]struct{})
+	for _, m := range a.Body.DataMappings {
+		mapped[m.FormalParameter] = struct{}{}
+		f, found := t.Parameter(m.FormalParameter)
+		if !found {
+			add(m.FormalParameter, "unknown parameter of %s %s", kind, t.Id)
+			continue
+		}
+		expr := m.ActualParameter.Text()
+		literal := literalType(expr)
+		if f.Direction != "IN" {
+			if literal != "" || !symbol_literal.MatchString(strings.TrimSpace(expr)) {
+				add(f.Name, "%s parameter has to be mapped to a variable", f.Direction)
+			}
+			continue
+		}
+		if literal == "nil" {
+			if f.Required {
+				add(f.Name, "required parameter is nil")
+			}
+		} else if literal != "" && f.Type != "" && f.Type != "any" && literal != f.Type {
+			add(f.Name, "expected %s, but was %s %s", f.Type, literal, strings.TrimSpace(expr))
+		}
+	}
+	for _, f := range t.Parameters {
+		if _, present := mapped[f.Name]; f.Required && !present {
+			add(f.Name, "missing required parameter of %s %s", kind, t.Id)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,79 @@
+package processfile_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func readCatalog(t *testing.T) *processfile.TaskCatalog {
+	content, err := ioutil.ReadFile("testdata/tasks.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := processfile.ParseTaskCatalog("tasks.yaml", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return catalog
+}
+
+func TestValidateTasks(t *testing.T) {
+	catalog := readCatalog(t)
+	p := readA1()
+	if problems := processfile.ValidateTasks(p, catalog); len(problems) != 0 {
+		t.Fatalf("Expected no problems, but was %v", problems)
+	}
+
+	// Unbekannter Parameter, fehlender Pflichtparameter, falscher Typ und
+	// ein INOUT-Parameter ohne Variable
+	task := catalog.Tasks[0]
+	task.Parameters = append(task.Parameters, processfile.TaskParameter{Name: "quelle", Direction: "IN", Required: true})
+	task.Parameters[0].Type = "number"
+	task.Parameters[2].Name = "text"
+	task.Parameters[1].Direction = "OUT"
+	for i := range p.Activities {
+		if p.Activities[i].Body.ImplementationRefId == task.Id {
+			p.Activities[i].Body.DataMappings[1].ActualParameter.Value = []byte(`"p-1"`)
+		}
+	}
+	problems := processfile.ValidateTasks(p, &processfile.TaskCatalog{Tasks: []processfile.TaskDefinition{task}})
+	expected := []string{
+		"kategorie: expected number, but was string",
+		"protokollId: OUT parameter has to be mapped to a variable",
+		"eintrag: unknown parameter",
+		"text: missing required parameter",
+		"quelle: missing required parameter",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, but was %v", len(expected), problems)
+	}
+	for i, e := range expected {
+		if !strings.Contains(problems[i].String(), e) {
+			t.Errorf("Expected problem '%s', but was '%s'", e, problems[i])
+		}
+	}
+}
+
+func TestParseTaskCatalog(t *testing.T) {
+	for _, content := range []string{
+		`{"tasks": [{"id": "a"}, {"id": "a"}]}`,
+		`{"tasks": [{"id": "a", "parameters": [{"name": "x", "direction": "UP"}]}]}`,
+		`{"tasks": [{"id": "a", "parameters": [{"name": "x", "type": "date"}]}]}`,
+		`{"tasks": [{"parameters": []}]}`,
+	} {
+		if _, err := processfile.ParseTaskCatalog("tasks.json", []byte(content)); err == nil {
+			t.Errorf("Expected error for %s", content)
+		}
+	}
+
+	catalog, err := processfile.ParseTaskCatalog("tasks.json", []byte(`{"tasks": [{"id": "a", "parameters": [{"name": "x"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if catalog.Tasks[0].Parameters[0].Direction != "IN" {
+		t.Errorf("Expected default direction IN, but was %s", catalog.Tasks[0].Parameters[0].Direction)
+	}
+}
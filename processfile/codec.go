@@ -0,0 +1,191 @@
+package processfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Die Formate, in denen eine Prozessdefinition gelesen und geschrieben werden
+// kann
+const (
+	FORMAT_XML  = "xml"
+	FORMAT_JSON = "json"
+	FORMAT_YAML = "yaml"
+)
+
+// In JSON und YAML sind Beschreibungen, aktuelle Parameter und Bedingungen
+// einfache Strings. Beim Lesen werden Beschreibungen und aktuelle Parameter
+// wie von Canonicalize in CDATA-Abschnitte verpackt.
+
+// jsonString liefert 's' als JSON-String ohne HTML-Escapes
+func jsonString(s string) ([]byte, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(s); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func (d Description) MarshalJSON() ([]byte, error) {
+	return jsonString(d.Text())
+}
+
+func (d *Description) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return err
+	}
+	d.Value = cdata(text)
+	return nil
+}
+
+func (d Description) MarshalYAML() (interface{}, error) {
+	return d.Text(), nil
+}
+
+func (d *Description) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	d.Value = cdata(text)
+	return nil
+}
+
+func (a ActualParameter) MarshalJSON() ([]byte, error) {
+	return jsonString(a.Text())
+}
+
+func (a *ActualParameter) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return err
+	}
+	a.Value = cdata(text)
+	return nil
+}
+
+func (a ActualParameter) MarshalYAML() (interface{}, error) {
+	return a.Text(), nil
+}
+
+func (a *ActualParameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	a.Value = cdata(text)
+	return nil
+}
+
+func (c Condition) MarshalJSON() ([]byte, error) {
+	return jsonString(c.Value)
+}
+
+func (c *Condition) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &c.Value)
+}
+
+func (c Condition) MarshalYAML() (interface{}, error) {
+	return c.Value, nil
+}
+
+func (c *Condition) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return unmarshal(&c.Value)
+}
+
+// withEmptyLists liefert eine Kopie von 'p', in der fehlende Listen leer
+// sind, damit JSON und YAML immer Listen enthalten (und nie null)
+func withEmptyLists(p *Process) *Process {
+	c := *p
+	if c.FormalParameters == nil {
+		c.FormalParameters = []FormalParameter{}
+	}
+	if c.Variables == nil {
+		c.Variables = []Variable{}
+	}
+	if c.Properties == nil {
+		c.Properties = []Property{}
+	}
+	c.Activities = append([]Activity{}, p.Activities...)
+	for i := range c.Activities {
+		a := &c.Activities[i]
+		if a.Body.DataMappings == nil {
+			a.Body.DataMappings = []DataMapping{}
+		}
+		if a.Transitions == nil {
+			a.Transitions = []Transition{}
+		}
+	}
+	return &c
+}
+
+// FormatOf liefert das Format einer Datei nach ihrer Endung: .json, .yaml
+// und .yml sind JSON und YAML, alle anderen XML
+func FormatOf(path string) string {
+	switch filepath.Ext(path) {
+	case ".json":
+		return FORMAT_JSON
+	case ".yaml", ".yml":
+		return FORMAT_YAML
+	}
+	return FORMAT_XML
+}
+
+// Encode liefert die Prozessdefinition 'p' im Format 'format'. XML ist das
+// Format der Prozessdateien (siehe ToBytes), JSON und YAML entsprechen
+// JSONSchema.
+func Encode(p *Process, format string) ([]byte, error) {
+	switch format {
+	case FORMAT_XML:
+		return ToBytes(p), nil
+	case FORMAT_JSON:
+		// Ausdrücke und Beschreibungen bleiben lesbar ('<' statt '\u003c')
+		var content bytes.Buffer
+		e := json.NewEncoder(&content)
+		e.SetEscapeHTML(false)
+		e.SetIndent("", "  ")
+		if err := e.Encode(withEmptyLists(p)); err != nil {
+			return nil, err
+		}
+		return content.Bytes(), nil
+	case FORMAT_YAML:
+		return yaml.Marshal(withEmptyLists(p))
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported format '%s'", format))
+}
+
+// Decode liest eine Prozessdefinition im Format 'format'. Das Ergebnis ist in
+// kanonischer Form (siehe Canonicalize), so dass die Umwandlung von XML in
+// JSON oder YAML und zurück dasselbe Ergebnis liefert wie Format.
+func Decode(content []byte, format string) (*Process, error) {
+	var p Process
+	var err error
+	switch format {
+	case FORMAT_XML:
+		var parsed *Process
+		if parsed, err = Parse(content); err == nil {
+			p = *parsed
+		}
+	case FORMAT_JSON:
+		d := json.NewDecoder(bytes.NewReader(content))
+		d.DisallowUnknownFields()
+		err = d.Decode(&p)
+	case FORMAT_YAML:
+		err = yaml.UnmarshalStrict(content, &p)
+	default:
+		err = errors.New(fmt.Sprintf("unsupported format '%s'", format))
+	}
+	if err != nil {
+		return nil, err
+	}
+	Canonicalize(&p)
+	return &p, nil
+}
@@ -0,0 +1,93 @@
+package processfile_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestCodecs(t *testing.T) {
+	// Die Umwandlung ist verlustfrei: zurück in XML ergibt sich die Datei des
+	// ISP-Editors (mit LF als Zeilenende)
+	formatted := bytes.Replace(processfile.FileContent("testdata/A1.process"), []byte("\r\n"), []byte("\n"), -1)
+	p := readA1()
+	p.Activities[0].Transitions[0].Condition = processfile.NewCondition(`(= kategorie "FEHLER")`)
+
+	for _, format := range []string{processfile.FORMAT_JSON, processfile.FORMAT_YAML} {
+		encoded, err := processfile.Encode(readA1(), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := processfile.Decode(encoded, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if result := processfile.ToBytes(decoded); string(result) != string(formatted) {
+			t.Errorf("%s: Expected\n%s\nbut was\n%s", format, formatted, result)
+		}
+
+		// Bedingungen bleiben erhalten
+		encoded, _ = processfile.Encode(p, format)
+		decoded, _ = processfile.Decode(encoded, format)
+		if c := decoded.Activities[0].Transitions[0].Condition.Text(); c != `(= kategorie "FEHLER")` {
+			t.Errorf("%s: Expected condition, but was %s", format, c)
+		}
+	}
+
+	encoded, _ := processfile.Encode(readA1(), processfile.FORMAT_JSON)
+	if !strings.Contains(string(encoded), `"actualParameter": "\"FEHLER\""`) {
+		t.Errorf("Expected actual parameter without CDATA, but was\n%s", encoded)
+	}
+	if !strings.Contains(string(encoded), `"description": "Dies ist <keine><![ '#~/ Beschreibung"`) {
+		t.Errorf("Expected description without HTML escapes, but was\n%s", encoded)
+	}
+	if _, err := processfile.Decode([]byte(`{"id": "A1", "activitys": []}`), processfile.FORMAT_JSON); err == nil {
+		t.Errorf("Expected error for unknown field")
+	}
+	if _, err := processfile.Encode(readA1(), "csv"); err == nil {
+		t.Errorf("Expected error for unknown format")
+	}
+}
+
+// checkSchema prüft, ob alle Felder von 'value' im Schema 'schema' beschrieben sind
+func checkSchema(t *testing.T, path string, value interface{}, schema map[string]interface{}, definitions map[string]interface{}) {
+	if ref, present := schema["$ref"].(string); present {
+		schema = definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, field := range v {
+			s, present := properties[key].(map[string]interface{})
+			if !present {
+				t.Errorf("Expected %s.%s in schema", path, key)
+				continue
+			}
+			checkSchema(t, path+"."+key, field, s, definitions)
+		}
+	case []interface{}:
+		if schema["type"] != "array" {
+			t.Errorf("Expected array type for %s", path)
+			return
+		}
+		for _, item := range v {
+			checkSchema(t, path+"[]", item, schema["items"].(map[string]interface{}), definitions)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(processfile.JSONSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := processfile.Encode(readA1(), processfile.FORMAT_JSON)
+	var value interface{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		t.Fatal(err)
+	}
+	checkSchema(t, "process", value, schema, schema["definitions"].(map[string]interface{}))
+}
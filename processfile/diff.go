@@ -0,0 +1,256 @@
+package processfile
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Arten einer Änderung
+const (
+	ADDED   = "ADDED"
+	REMOVED = "REMOVED"
+	CHANGED = "CHANGED"
+)
+
+// Change beschreibt einen semantischen Unterschied zwischen zwei Prozessdefinitionen.
+//
+// Element benennt die Art des Elements (activity, transition, data-mapping, ...),
+// Id identifiziert es innerhalb von Context (z.B. die Aktivität einer Transition).
+// Bei CHANGED benennt Field das geänderte Attribut.
+type Change struct {
+	Kind    string
+	Element string
+	Id      string
+	Context string
+	Field   string
+	Old     string
+	New     string
+}
+
+// DiffOptions steuert, welche Unterschiede Diff meldet
+type DiffOptions struct {
+	// Layout meldet auch reine Änderungen der NodeGraphicsInfo
+	Layout bool
+}
+
+func (c Change) String() string {
+	var prefix string
+	switch c.Kind {
+	case ADDED:
+		prefix = "+"
+	case REMOVED:
+		prefix = "-"
+	default:
+		prefix = "~"
+	}
+
+	s := fmt.Sprintf("%s %s %s", prefix, c.Element, c.Id)
+	if c.Context != "" {
+		s = fmt.Sprintf("%s (%s)", s, c.Context)
+	}
+	switch c.Kind {
+	case ADDED:
+		if c.New != "" {
+			s = fmt.Sprintf("%s: %s", s, strconv.Quote(c.New))
+		}
+	case REMOVED:
+		if c.Old != "" {
+			s = fmt.Sprintf("%s: %s", s, strconv.Quote(c.Old))
+		}
+	default:
+		s = fmt.Sprintf("%s %s: %s -> %s", s, c.Field, strconv.Quote(c.Old), strconv.Quote(c.New))
+	}
+	return s
+}
+
+type differ struct {
+	opts    DiffOptions
+	changes []Change
+}
+
+func (d *differ) add(c Change) {
+	d.changes = append(d.changes, c)
+}
+
+func (d *differ) field(element, id, context, field, old, new string) {
+	if old != new {
+		d.add(Change{Kind: CHANGED, Element: element, Id: id, Context: context, Field: field, Old: old, New: new})
+	}
+}
+
+// Diff vergleicht die Prozessdefinitionen a und b semantisch. Elemente werden
+// über ihre Ids einander zugeordnet, Data-Mappings über den Namen des formalen
+// Parameters. Die Reihenfolge der Elemente in der Datei spielt keine Rolle.
+func Diff(a, b *Process, opts DiffOptions) []Change {
+	d := &differ{opts: opts}
+
+	d.field("process", a.Id, "", "id", a.Id, b.Id)
+	d.field("process", a.Id, "", "name", a.Name, b.Name)
+	d.field("process", a.Id, "", "description", a.Description.Text(), b.Description.Text())
+
+	d.formalParameters(a.FormalParameters, b.FormalParameters)
+	d.variables(a.Variables, b.Variables)
+	d.properties(a.Properties, b.Properties)
+	d.activities(a.Activities, b.Activities)
+
+	return d.changes
+}
+
+func (d *differ) formalParameters(as, bs []FormalParameter) {
+	bm := make(map[string]FormalParameter)
+	for _, fp := range bs {
+		bm[fp.Id] = fp
+	}
+	am := make(map[string]FormalParameter)
+	for _, fa := range as {
+		am[fa.Id] = fa
+		fb, present := bm[fa.Id]
+		if !present {
+			d.add(Change{Kind: REMOVED, Element: "formal-parameter", Id: fa.Id, Old: fa.Name})
+			continue
+		}
+		d.field("formal-parameter", fa.Id, fa.Name, "name", fa.Name, fb.Name)
+		d.field("formal-parameter", fa.Id, fa.Name, "direction", fa.Direction, fb.Direction)
+		d.field("formal-parameter", fa.Id, fa.Name, "required", strconv.FormatBool(fa.Required), strconv.FormatBool(fb.Required))
+		d.field("formal-parameter", fa.Id, fa.Name, "hidden", strconv.FormatBool(fa.Hidden), strconv.FormatBool(fb.Hidden))
+		d.field("formal-parameter", fa.Id, fa.Name, "description", fa.Description.Text(), fb.Description.Text())
+	}
+	for _, fb := range bs {
+		if _, present := am[fb.Id]; !present {
+			d.add(Change{Kind: ADDED, Element: "formal-parameter", Id: fb.Id, New: fb.Name})
+		}
+	}
+}
+
+func (d *differ) variables(as, bs []Variable) {
+	bm := make(map[string]Variable)
+	for _, v := range bs {
+		bm[v.Id] = v
+	}
+	am := make(map[string]Variable)
+	for _, va := range as {
+		am[va.Id] = va
+		vb, present := bm[va.Id]
+		if !present {
+			d.add(Change{Kind: REMOVED, Element: "variable", Id: va.Id, Old: va.Name})
+			continue
+		}
+		d.field("variable", va.Id, va.Name, "name", va.Name, vb.Name)
+		d.field("variable", va.Id, va.Name, "hidden", strconv.FormatBool(va.Hidden), strconv.FormatBool(vb.Hidden))
+	}
+	for _, vb := range bs {
+		if _, present := am[vb.Id]; !present {
+			d.add(Change{Kind: ADDED, Element: "variable", Id: vb.Id, New: vb.Name})
+		}
+	}
+}
+
+func (d *differ) properties(as, bs []Property) {
+	bm := make(map[string]Property)
+	for _, p := range bs {
+		bm[p.Id] = p
+	}
+	am := make(map[string]Property)
+	for _, pa := range as {
+		am[pa.Id] = pa
+		pb, present := bm[pa.Id]
+		if !present {
+			d.add(Change{Kind: REMOVED, Element: "property", Id: pa.Id, Old: pa.Name})
+			continue
+		}
+		d.field("property", pa.Id, pa.Name, "name", pa.Name, pb.Name)
+		d.field("property", pa.Id, pa.Name, "value", pa.Value, pb.Value)
+		d.field("property", pa.Id, pa.Name, "description", pa.Description.Text(), pb.Description.Text())
+	}
+	for _, pb := range bs {
+		if _, present := am[pb.Id]; !present {
+			d.add(Change{Kind: ADDED, Element: "property", Id: pb.Id, New: pb.Name})
+		}
+	}
+}
+
+func (d *differ) activities(as, bs []Activity) {
+	bm := make(map[string]Activity)
+	for _, a := range bs {
+		bm[a.Id] = a
+	}
+	am := make(map[string]Activity)
+	for _, aa := range as {
+		am[aa.Id] = aa
+		ab, present := bm[aa.Id]
+		if !present {
+			d.add(Change{Kind: REMOVED, Element: "activity", Id: aa.Id, Old: aa.Name})
+			continue
+		}
+		d.activity(aa, ab)
+	}
+	for _, ab := range bs {
+		if _, present := am[ab.Id]; !present {
+			d.add(Change{Kind: ADDED, Element: "activity", Id: ab.Id, New: ab.Name})
+		}
+	}
+}
+
+func (d *differ) activity(a, b Activity) {
+	d.field("activity", a.Id, a.Name, "name", a.Name, b.Name)
+	d.field("activity", a.Id, a.Name, "activity-type", a.Body.ActivityType, b.Body.ActivityType)
+	d.field("activity", a.Id, a.Name, "event-type", a.Body.EventType, b.Body.EventType)
+	d.field("activity", a.Id, a.Name, "implementation-type", a.Body.ImplementationType, b.Body.ImplementationType)
+	d.field("activity", a.Id, a.Name, "implementation-ref-id", a.Body.ImplementationRefId, b.Body.ImplementationRefId)
+
+	if d.opts.Layout {
+		ga, gb := a.Body.NodeGraphicsInfo, b.Body.NodeGraphicsInfo
+		d.field("node-graphics-info", a.Id, a.Name, "coordinate-x", strconv.Itoa(ga.CoordinateX), strconv.Itoa(gb.CoordinateX))
+		d.field("node-graphics-info", a.Id, a.Name, "coordinate-y", strconv.Itoa(ga.CoordinateY), strconv.Itoa(gb.CoordinateY))
+		d.field("node-graphics-info", a.Id, a.Name, "width", strconv.Itoa(ga.Width), strconv.Itoa(gb.Width))
+		d.field("node-graphics-info", a.Id, a.Name, "height", strconv.Itoa(ga.Height), strconv.Itoa(gb.Height))
+	}
+
+	d.dataMappings(a, b)
+	d.transitions(a, b)
+}
+
+func (d *differ) dataMappings(a, b Activity) {
+	bm := make(map[string]DataMapping)
+	for _, dm := range b.Body.DataMappings {
+		bm[dm.FormalParameter] = dm
+	}
+	am := make(map[string]DataMapping)
+	for _, da := range a.Body.DataMappings {
+		am[da.FormalParameter] = da
+		db, present := bm[da.FormalParameter]
+		if !present {
+			d.add(Change{Kind: REMOVED, Element: "data-mapping", Id: da.FormalParameter, Context: a.Name, Old: da.ActualParameter.Text()})
+			continue
+		}
+		d.field("data-mapping", da.FormalParameter, a.Name, "actual-parameter", da.ActualParameter.Text(), db.ActualParameter.Text())
+	}
+	for _, db := range b.Body.DataMappings {
+		if _, present := am[db.FormalParameter]; !present {
+			d.add(Change{Kind: ADDED, Element: "data-mapping", Id: db.FormalParameter, Context: a.Name, New: db.ActualParameter.Text()})
+		}
+	}
+}
+
+func (d *differ) transitions(a, b Activity) {
+	bm := make(map[string]Transition)
+	for _, t := range b.Transitions {
+		bm[t.Id] = t
+	}
+	am := make(map[string]Transition)
+	for _, ta := range a.Transitions {
+		am[ta.Id] = ta
+		tb, present := bm[ta.Id]
+		if !present {
+			d.add(Change{Kind: REMOVED, Element: "transition", Id: ta.Id, Context: a.Name, Old: ta.To})
+			continue
+		}
+		d.field("transition", ta.Id, a.Name, "to", ta.To, tb.To)
+		d.field("condition", ta.Id, a.Name, "condition", ta.Condition.Text(), tb.Condition.Text())
+	}
+	for _, tb := range b.Transitions {
+		if _, present := am[tb.Id]; !present {
+			d.add(Change{Kind: ADDED, Element: "transition", Id: tb.Id, Context: a.Name, New: tb.To})
+		}
+	}
+}
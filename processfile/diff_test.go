@@ -0,0 +1,42 @@
+package processfile_test
+
+import (
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestDiffIdentical(t *testing.T) {
+	a := processfile.FromBytes(processfile.FileContent("testdata/A1.process"))
+	b := processfile.FromBytes(processfile.FileContent("testdata/A1.process"))
+
+	if changes := processfile.Diff(a, b, processfile.DiffOptions{}); len(changes) != 0 {
+		t.Errorf("Expected no changes, but was %v", changes)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := processfile.FromBytes(processfile.FileContent("testdata/A1.process"))
+	b := processfile.FromBytes(processfile.FileContent("testdata/A1.process"))
+
+	// Layout-Änderung, geänderter Ausdruck, entfernte Aktivität
+	b.Activities[1].Body.NodeGraphicsInfo.CoordinateX = 500
+	b.Activities[1].Body.DataMappings[0].ActualParameter.Value = []byte(`<![CDATA["INFO"]]>`)
+	b.Activities = append(b.Activities[:3], b.Activities[4:]...)
+
+	changes := processfile.Diff(a, b, processfile.DiffOptions{})
+	if l := len(changes); l != 2 {
+		t.Fatalf("Expected 2 changes, but was %d: %v", l, changes)
+	}
+	if c := changes[0]; c.Kind != processfile.CHANGED || c.Element != "data-mapping" || c.Id != "kategorie" || c.New != `"INFO"` {
+		t.Errorf("Unexpected change %v", c)
+	}
+	if c := changes[1]; c.Kind != processfile.REMOVED || c.Element != "activity" || c.Id != "e94a0a3c-21eb-4773-b210-bea8bd8ae150" {
+		t.Errorf("Unexpected change %v", c)
+	}
+
+	changes = processfile.Diff(a, b, processfile.DiffOptions{Layout: true})
+	if l := len(changes); l != 3 {
+		t.Errorf("Expected 3 changes with layout, but was %d: %v", l, changes)
+	}
+}
@@ -0,0 +1,203 @@
+package processfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+)
+
+// cdata verpackt 'text' in einen CDATA-Abschnitt. Ein enthaltenes ']]>' wird
+// auf zwei Abschnitte aufgeteilt.
+func cdata(text string) []byte {
+	if text == "" {
+		return nil
+	}
+	return []byte("<![CDATA[" + strings.Replace(text, "]]>", "]]]]><![CDATA[>", -1) + "]]>")
+}
+
+// Canonicalize bringt die Prozessdefinition 'p' in die kanonische Form:
+// Properties sind nach Namen sortiert, Beschreibungen und aktuelle Parameter
+// stehen einheitlich in CDATA-Abschnitten.
+func Canonicalize(p *Process) {
+	p.Description.Value = cdata(p.Description.Text())
+
+	for i := range p.FormalParameters {
+		fp := &p.FormalParameters[i]
+		fp.Description.Value = cdata(fp.Description.Text())
+	}
+
+	for i := range p.Properties {
+		pr := &p.Properties[i]
+		pr.Description.Value = cdata(pr.Description.Text())
+	}
+	sort.SliceStable(p.Properties, func(i, j int) bool {
+		if p.Properties[i].Name != p.Properties[j].Name {
+			return p.Properties[i].Name < p.Properties[j].Name
+		}
+		return p.Properties[i].Id < p.Properties[j].Id
+	})
+
+	for i := range p.Activities {
+		a := &p.Activities[i]
+		for j := range a.Body.DataMappings {
+			ap := &a.Body.DataMappings[j].ActualParameter
+			ap.Value = cdata(ap.Text())
+		}
+		for j := range a.Transitions {
+			t := &a.Transitions[j]
+			t.Condition = NewCondition(t.Condition.Text())
+		}
+	}
+}
+
+// Format liefert den Inhalt einer Prozessdatei in kanonischer Form. Die
+// Reihenfolge der Attribute und die Einrückung legt ToBytes fest, die
+// Zeilenenden (der ISP-Editor schreibt CRLF) bleiben erhalten. Eine vom
+// ISP-Editor geschriebene Datei bleibt so unverändert. Leere Dateien bleiben
+// leer.
+func Format(content []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return content, nil
+	}
+
+	var p Process
+	if err := xml.Unmarshal(content, &p); err != nil {
+		return nil, err
+	}
+	Canonicalize(&p)
+	formatted := ToBytes(&p)
+	if bytes.Contains(content, []byte("\r\n")) {
+		formatted = bytes.Replace(formatted, []byte("\n"), []byte("\r\n"), -1)
+	}
+	return formatted, nil
+}
+
+func (l DataMappings) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if l == nil {
+		return nil
+	}
+	return e.EncodeElement(struct {
+		DataMappings []DataMapping `xml:"data-mapping"`
+	}{l}, start)
+}
+
+func (l *DataMappings) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		DataMappings []DataMapping `xml:"data-mapping"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*l = append(DataMappings{}, v.DataMappings...)
+	return nil
+}
+
+// withDataMappings liefert eine Kopie von 'p', in der wie beim ISP-Editor
+// genau die Aktivitäten vom Typ IMPLEMENTATION (eventuell leere)
+// data-mappings haben
+func withDataMappings(p *Process) *Process {
+	c := *p
+	c.Activities = append([]Activity(nil), p.Activities...)
+	for i := range c.Activities {
+		body := &c.Activities[i].Body
+		switch {
+		case body.ActivityType == "IMPLEMENTATION" && body.DataMappings == nil:
+			body.DataMappings = DataMappings{}
+		case body.ActivityType != "IMPLEMENTATION" && len(body.DataMappings) == 0:
+			body.DataMappings = nil
+		}
+	}
+	return &c
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;",
+		"\t", "&#9;", "\n", "&#10;", "\r", "&#13;")
+)
+
+// xmlElement ist ein Element, das writeXML gerade schreibt
+type xmlElement struct {
+	name     string
+	open     bool // das Start-Tag ist noch nicht mit '>' abgeschlossen
+	children bool
+	text     bytes.Buffer
+}
+
+// writeText schließt das Start-Tag von 'e' ab und schreibt den gesammelten
+// Text. Beschreibungen und aktuelle Parameter stehen in CDATA-Abschnitten.
+func (e *xmlElement) writeText(out *bytes.Buffer) {
+	if e.open {
+		out.WriteString(">")
+		e.open = false
+	}
+	text := e.text.String()
+	e.text.Reset()
+	switch {
+	case e.children && strings.TrimSpace(text) == "":
+	case e.name == "description" || e.name == "actual-parameter":
+		out.Write(cdata(text))
+	default:
+		textEscaper.WriteString(out, text)
+	}
+}
+
+// writeXML schreibt das von xml.Marshal erzeugte Dokument 'content' so, wie
+// es der ISP-Editor schreibt: das Wurzelelement direkt hinter der
+// XML-Deklaration, die Attribute nach Namen sortiert, leere Elemente
+// selbstschließend und zwei Leerzeichen Einrückung je Ebene.
+func writeXML(content []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString(strings.TrimSuffix(xml.Header, "\n"))
+
+	var stack []*xmlElement
+	d := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = true
+				parent.writeText(&out)
+				out.WriteString("\n" + strings.Repeat("  ", len(stack)))
+			}
+			attrs := append([]xml.Attr(nil), t.Attr...)
+			sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name.Local < attrs[j].Name.Local })
+			out.WriteString("<" + t.Name.Local)
+			for _, a := range attrs {
+				out.WriteString(" " + a.Name.Local + `="`)
+				attrEscaper.WriteString(&out, a.Value)
+				out.WriteString(`"`)
+			}
+			stack = append(stack, &xmlElement{name: t.Name.Local, open: true})
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if e.open && e.text.Len() == 0 {
+				out.WriteString("/>")
+				continue
+			}
+			e.writeText(&out)
+			if e.children {
+				out.WriteString("\n" + strings.Repeat("  ", len(stack)))
+			}
+			out.WriteString("</" + e.name + ">")
+		}
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package processfile_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestFormat(t *testing.T) {
+	content := processfile.FileContent("testdata/A1.process")
+
+	formatted, err := processfile.Format(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Eine vom ISP-Editor geschriebene Datei bleibt unverändert
+	if !bytes.Equal(formatted, content) {
+		t.Errorf("Expected editor file unchanged, but was\n%s", formatted)
+	}
+
+	again, err := processfile.Format(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(formatted, again) {
+		t.Errorf("Format is not idempotent")
+	}
+
+	p := processfile.FromBytes(formatted)
+	if d := p.Description.Text(); d != "Dies ist <keine><![ '#~/ Beschreibung" {
+		t.Errorf("Unexpected description %s", d)
+	}
+	if c := processfile.Diff(processfile.FromBytes(content), p, processfile.DiffOptions{Layout: true}); len(c) != 0 {
+		t.Errorf("Expected no semantic changes, but was %v", c)
+	}
+}
+
+func TestFormatCanonical(t *testing.T) {
+	content := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<process name="P" id="p">
+	<description>Eine &lt;Beschreibung&gt;</description>
+	<formal-parameters></formal-parameters>
+	<properties>
+		<property value="2" name="b" id="p2"/>
+		<property value="1" name="a" id="p1"/>
+	</properties>
+	<activities>
+		<activity id="s" name="Start">
+			<body activity-type="EVENT" event-type="START"><data-mappings/><node-graphics-info coordinate-x="1.6" coordinate-y="2" width="30" height="30"/></body>
+			<transitions><transition id="t1" to="e"><condition>(= a "x")</condition></transition><transition id="t2" to="e"><condition></condition></transition></transitions>
+		</activity>
+	</activities>
+</process>`)
+	expected := `<?xml version="1.0" encoding="UTF-8"?><process id="p" name="P">
+  <description><![CDATA[Eine <Beschreibung>]]></description>
+  <formal-parameters/>
+  <variables/>
+  <properties>
+    <property id="p1" name="a" value="1">
+      <description/>
+    </property>
+    <property id="p2" name="b" value="2">
+      <description/>
+    </property>
+  </properties>
+  <activities>
+    <activity id="s" name="Start">
+      <body activity-type="EVENT" event-type="START">
+        <node-graphics-info coordinate-x="2" coordinate-y="2" height="30" width="30"/>
+      </body>
+      <transitions>
+        <transition id="t1" to="e">
+          <condition>(= a "x")</condition>
+        </transition>
+        <transition id="t2" to="e"/>
+      </transitions>
+    </activity>
+  </activities>
+</process>
+`
+	formatted, err := processfile.Format(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != expected {
+		t.Errorf("Expected\n%s\nbut was\n%s", expected, formatted)
+	}
+}
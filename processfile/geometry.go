@@ -0,0 +1,165 @@
+package processfile
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseCoordinate liest den Wert eines Attributs der node-graphics-info.
+// Ein leerer Wert ist 0, Dezimalzahlen werden gerundet.
+func parseCoordinate(attr xml.Attr) (int, error) {
+	value := strings.TrimSpace(attr.Value)
+	if value == "" {
+		return 0, nil
+	}
+	if i, err := strconv.Atoi(value); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > math.MaxInt32 {
+		return 0, errors.New(fmt.Sprintf("node-graphics-info: invalid %s '%s'", attr.Name.Local, attr.Value))
+	}
+	return int(math.Round(f)), nil
+}
+
+// UnmarshalXML liest die node-graphics-info. Ein Attribut, das keine Zahl
+// ist, ist ein Fehler mit dem Namen und Wert des Attributs.
+func (g *NodeGraphicsInfo) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*g = NodeGraphicsInfo{}
+	for _, attr := range start.Attr {
+		var field *int
+		switch attr.Name.Local {
+		case "coordinate-x":
+			field = &g.CoordinateX
+		case "coordinate-y":
+			field = &g.CoordinateY
+		case "width":
+			field = &g.Width
+		case "height":
+			field = &g.Height
+		default:
+			continue
+		}
+		value, err := parseCoordinate(attr)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	return d.Skip()
+}
+
+// Rect ist ein achsenparalleles Rechteck im Diagramm
+type Rect struct {
+	X, Y, Width, Height int
+}
+
+// Rect liefert das Rechteck der Aktivität
+func (g NodeGraphicsInfo) Rect() Rect {
+	return Rect{X: g.CoordinateX, Y: g.CoordinateY, Width: g.Width, Height: g.Height}
+}
+
+// Empty prüft, ob das Rechteck keine Fläche hat
+func (r Rect) Empty() bool {
+	return r.Width <= 0 || r.Height <= 0
+}
+
+// Overlaps prüft, ob sich die Rechtecke 'r' und 'o' überlappen. Rechtecke,
+// die sich nur berühren, überlappen sich nicht.
+func (r Rect) Overlaps(o Rect) bool {
+	if r.Empty() || o.Empty() {
+		return false
+	}
+	return r.X < o.X+o.Width && o.X < r.X+r.Width && r.Y < o.Y+o.Height && o.Y < r.Y+r.Height
+}
+
+// Union liefert das kleinste Rechteck, das 'r' und 'o' enthält
+func (r Rect) Union(o Rect) Rect {
+	if r.Empty() {
+		return o
+	}
+	if o.Empty() {
+		return r
+	}
+	x0, y0 := min(r.X, o.X), min(r.Y, o.Y)
+	x1, y1 := max(r.X+r.Width, o.X+o.Width), max(r.Y+r.Height, o.Y+o.Height)
+	return Rect{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// snap rundet 'v' auf ein Vielfaches von 'grid'
+func snap(v int, grid int) int {
+	return int(math.Round(float64(v)/float64(grid))) * grid
+}
+
+// Snap liefert die node-graphics-info mit Position und Größe auf ein
+// Vielfaches von 'grid' gerundet. Die Größe wird dabei nicht 0.
+func (g NodeGraphicsInfo) Snap(grid int) NodeGraphicsInfo {
+	if grid <= 1 {
+		return g
+	}
+	s := NodeGraphicsInfo{
+		CoordinateX: snap(g.CoordinateX, grid),
+		CoordinateY: snap(g.CoordinateY, grid),
+		Width:       snap(g.Width, grid),
+		Height:      snap(g.Height, grid),
+	}
+	if s.Width == 0 && g.Width > 0 {
+		s.Width = grid
+	}
+	if s.Height == 0 && g.Height > 0 {
+		s.Height = grid
+	}
+	return s
+}
+
+// BoundingBox liefert das kleinste Rechteck, das alle Aktivitäten von 'p'
+// enthält. Aktivitäten ohne Größe werden nicht berücksichtigt.
+func BoundingBox(p *Process) Rect {
+	var box Rect
+	for _, a := range p.Activities {
+		box = box.Union(a.Body.NodeGraphicsInfo.Rect())
+	}
+	return box
+}
+
+// Overlapping liefert die Paare von Aktivitäten (je Id), die sich im
+// Diagramm überlappen
+func Overlapping(p *Process) [][2]string {
+	var pairs [][2]string
+	for i := range p.Activities {
+		a := p.Activities[i].Body.NodeGraphicsInfo.Rect()
+		for j := i + 1; j < len(p.Activities); j++ {
+			if a.Overlaps(p.Activities[j].Body.NodeGraphicsInfo.Rect()) {
+				pairs = append(pairs, [2]string{p.Activities[i].Id, p.Activities[j].Id})
+			}
+		}
+	}
+	return pairs
+}
+
+// SnapToGrid rundet Position und Größe aller Aktivitäten von 'p' auf ein
+// Vielfaches von 'grid'
+func SnapToGrid(p *Process, grid int) {
+	for i := range p.Activities {
+		body := &p.Activities[i].Body
+		body.NodeGraphicsInfo = body.NodeGraphicsInfo.Snap(grid)
+	}
+}
@@ -0,0 +1,61 @@
+package processfile_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestNodeGraphicsInfo(t *testing.T) {
+	p, err := processfile.Parse([]byte(`<process id="p"><activities>
+    <activity id="a"><body><node-graphics-info coordinate-x="10" coordinate-y="12.6" width="30" height=""/></body></activity>
+  </activities></process>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := p.Activities[0].Body.NodeGraphicsInfo; g != (processfile.NodeGraphicsInfo{CoordinateX: 10, CoordinateY: 13, Width: 30}) {
+		t.Errorf("Expected 10, 13, 30, 0, but was %v", g)
+	}
+
+	_, err = processfile.Parse([]byte(`<process id="p"><activities>
+    <activity id="a"><body><node-graphics-info coordinate-x="links"/></body></activity>
+  </activities></process>`))
+	if err == nil || !strings.Contains(err.Error(), "coordinate-x 'links'") {
+		t.Errorf("Expected error for coordinate-x, but was %v", err)
+	}
+
+	content := string(processfile.ToBytes(readA1()))
+	if !strings.Contains(content, `coordinate-x="347" coordinate-y="94" height="30" width="125"`) {
+		t.Errorf("Expected numeric node-graphics-info, but was %s", content)
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	p := readA1()
+	if box := processfile.BoundingBox(p); box != (processfile.Rect{X: 347, Y: 21, Width: 125, Height: 298}) {
+		t.Errorf("Expected bounding box 347, 21, 125, 298, but was %v", box)
+	}
+	if pairs := processfile.Overlapping(p); len(pairs) != 0 {
+		t.Errorf("Expected no overlapping activities, but was %v", pairs)
+	}
+
+	p.Activities[1].Body.NodeGraphicsInfo.CoordinateY = 30
+	if pairs := processfile.Overlapping(p); len(pairs) != 1 || pairs[0][0] != p.Activities[0].Id || pairs[0][1] != p.Activities[1].Id {
+		t.Errorf("Expected overlapping START and Protokoll, but was %v", pairs)
+	}
+
+	// Sich berührende Rechtecke überlappen nicht
+	a := processfile.Rect{X: 0, Y: 0, Width: 10, Height: 10}
+	if a.Overlaps(processfile.Rect{X: 10, Y: 0, Width: 10, Height: 10}) {
+		t.Errorf("Expected touching rectangles not to overlap")
+	}
+
+	processfile.SnapToGrid(p, 10)
+	if g := p.Activities[0].Body.NodeGraphicsInfo; g != (processfile.NodeGraphicsInfo{CoordinateX: 390, CoordinateY: 20, Width: 30, Height: 30}) {
+		t.Errorf("Expected snapped START event, but was %v", g)
+	}
+	if g := (processfile.NodeGraphicsInfo{Width: 4, Height: 4}).Snap(10); g.Width != 10 || g.Height != 10 {
+		t.Errorf("Expected minimal size of one grid unit, but was %v", g)
+	}
+}
@@ -0,0 +1,113 @@
+package processfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Eine Ersetzung im Inhalt einer Prozessdatei
+type edit struct {
+	from, to int
+	text     []byte
+}
+
+// setAttribute setzt das Attribut 'name' im Start-Tag 'tag' auf 'value'.
+// Fehlt das Attribut, wird es am Ende des Tags angefügt.
+func setAttribute(tag []byte, name string, value string) []byte {
+	quoted := []byte(fmt.Sprintf(`%s="%s"`, name, value))
+	re := regexp.MustCompile(`(\s)` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+	if re.Match(tag) {
+		return re.ReplaceAllFunc(tag, func(m []byte) []byte {
+			return append([]byte{m[0]}, quoted...)
+		})
+	}
+	end := len(tag) - 1
+	if bytes.HasSuffix(tag, []byte("/>")) {
+		end = len(tag) - 2
+	}
+	for end > 0 && (tag[end-1] == ' ' || tag[end-1] == '\t' || tag[end-1] == '\n' || tag[end-1] == '\r') {
+		end--
+	}
+	result := append([]byte{}, tag[:end]...)
+	result = append(result, ' ')
+	result = append(result, quoted...)
+	return append(result, tag[end:]...)
+}
+
+func setGraphicsAttributes(tag []byte, info NodeGraphicsInfo) []byte {
+	tag = setAttribute(tag, "coordinate-x", strconv.Itoa(info.CoordinateX))
+	tag = setAttribute(tag, "coordinate-y", strconv.Itoa(info.CoordinateY))
+	tag = setAttribute(tag, "width", strconv.Itoa(info.Width))
+	return setAttribute(tag, "height", strconv.Itoa(info.Height))
+}
+
+// SetGraphicsInfo setzt die node-graphics-info der Aktivitäten mit den Ids
+// aus 'infos' im Inhalt einer Prozessdatei. Der übrige Inhalt bleibt
+// unverändert, auch Formatierung, Kommentare und CDATA-Abschnitte. Fehlt
+// einer Aktivität die node-graphics-info, wird sie am Ende ihres Bodys
+// eingefügt.
+func SetGraphicsInfo(content []byte, infos map[string]NodeGraphicsInfo) ([]byte, error) {
+	var edits []edit
+	d := xml.NewDecoder(bytes.NewReader(content))
+
+	activity := ""
+	found := false
+	// Ein leerer Body (<body .../>) bekommt keine node-graphics-info
+	empty_body := false
+	for {
+		offset := int(d.InputOffset())
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch e := t.(type) {
+		case xml.StartElement:
+			switch e.Name.Local {
+			case "activity":
+				activity, found = "", false
+				for _, a := range e.Attr {
+					if a.Name.Local == "id" {
+						activity = a.Value
+					}
+				}
+			case "body":
+				empty_body = bytes.HasSuffix(content[offset:d.InputOffset()], []byte("/>"))
+			case "node-graphics-info":
+				info, present := infos[activity]
+				if activity == "" || !present || found {
+					continue
+				}
+				found = true
+				end := int(d.InputOffset())
+				edits = append(edits, edit{offset, end, setGraphicsAttributes(content[offset:end], info)})
+			}
+		case xml.EndElement:
+			switch e.Name.Local {
+			case "body":
+				info, present := infos[activity]
+				if activity == "" || !present || found || empty_body {
+					continue
+				}
+				found = true
+				tag := setGraphicsAttributes([]byte("<node-graphics-info/>"), info)
+				edits = append(edits, edit{offset, offset, tag})
+			case "activity":
+				activity = ""
+			}
+		}
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].from > edits[j].from })
+	result := append([]byte{}, content...)
+	for _, e := range edits {
+		result = append(result[:e.from], append(append([]byte{}, e.text...), result[e.to:]...)...)
+	}
+	return result, nil
+}
@@ -0,0 +1,350 @@
+package processfile
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Gründe für einen Konflikt beim Mergen
+const (
+	BOTH_MODIFIED      = "BOTH_MODIFIED"
+	BOTH_ADDED         = "BOTH_ADDED"
+	MODIFIED_DELETED   = "MODIFIED_DELETED"
+	DELETED_MODIFIED   = "DELETED_MODIFIED"
+	DANGLING_REFERENCE = "DANGLING_REFERENCE"
+)
+
+// Conflict beschreibt eine Änderung, die beim Dreiwege-Merge nicht automatisch
+// aufgelöst werden konnte. Im Ergebnis des Merge gilt dann der Stand von 'ours'.
+type Conflict struct {
+	Reason  string `json:"reason"`
+	Element string `json:"element"`
+	Id      string `json:"id"`
+	Context string `json:"context,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Base    string `json:"base,omitempty"`
+	Ours    string `json:"ours,omitempty"`
+	Theirs  string `json:"theirs,omitempty"`
+}
+
+func (c Conflict) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Reason, c.Element, c.Id)
+	if c.Context != "" {
+		s = fmt.Sprintf("%s (%s)", s, c.Context)
+	}
+	if c.Field != "" {
+		s = fmt.Sprintf("%s %s: base %q, ours %q, theirs %q", s, c.Field, c.Base, c.Ours, c.Theirs)
+	}
+	return s
+}
+
+type merger struct {
+	conflicts []Conflict
+}
+
+// field mergt einen einfachen Wert
+func (m *merger) field(element, id, context, field, base, ours, theirs string) string {
+	switch {
+	case ours == theirs:
+		return ours
+	case ours == base:
+		return theirs
+	case theirs == base:
+		return ours
+	}
+	m.conflicts = append(m.conflicts, Conflict{
+		Reason: BOTH_MODIFIED, Element: element, Id: id, Context: context, Field: field,
+		Base: base, Ours: ours, Theirs: theirs,
+	})
+	return ours
+}
+
+func (m *merger) bytes(element, id, context, field string, base, ours, theirs []byte) []byte {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours
+	case reflect.DeepEqual(ours, base):
+		return theirs
+	case reflect.DeepEqual(theirs, base):
+		return ours
+	}
+	m.conflicts = append(m.conflicts, Conflict{
+		Reason: BOTH_MODIFIED, Element: element, Id: id, Context: context, Field: field,
+		Base: innerText(base), Ours: innerText(ours), Theirs: innerText(theirs),
+	})
+	return ours
+}
+
+// element ist ein über 'key' identifiziertes Listenelement
+type element struct {
+	key   string
+	value interface{}
+}
+
+// elements mergt drei Listen über die Schlüssel ihrer Elemente. Die Reihenfolge
+// folgt 'ours', in 'theirs' hinzugefügte Elemente werden angehängt. Wurde ein
+// Element auf beiden Seiten unterschiedlich geändert, entscheidet 'both'. Ist
+// 'both' nil, ist das ein Konflikt.
+func (m *merger) elements(kind, context string, base, ours, theirs []element,
+	both func(b, o, t interface{}) interface{}) []element {
+
+	index := func(l []element) map[string]interface{} {
+		r := make(map[string]interface{})
+		for _, e := range l {
+			r[e.key] = e.value
+		}
+		return r
+	}
+	bm, om, tm := index(base), index(ours), index(theirs)
+
+	conflict := func(reason, key string) {
+		m.conflicts = append(m.conflicts, Conflict{Reason: reason, Element: kind, Id: key, Context: context})
+	}
+
+	result := make([]element, 0, len(ours))
+	for _, o := range ours {
+		b, inBase := bm[o.key]
+		t, inTheirs := tm[o.key]
+		switch {
+		case !inBase && !inTheirs:
+			// Nur in ours hinzugefügt
+			result = append(result, o)
+		case !inBase:
+			// Auf beiden Seiten hinzugefügt
+			if !reflect.DeepEqual(o.value, t) {
+				conflict(BOTH_ADDED, o.key)
+			}
+			result = append(result, o)
+		case !inTheirs:
+			// In theirs gelöscht
+			if !reflect.DeepEqual(o.value, b) {
+				conflict(MODIFIED_DELETED, o.key)
+				result = append(result, o)
+			}
+		case reflect.DeepEqual(o.value, t), reflect.DeepEqual(t, b):
+			result = append(result, o)
+		case reflect.DeepEqual(o.value, b):
+			result = append(result, element{o.key, t})
+		case both != nil:
+			result = append(result, element{o.key, both(b, o.value, t)})
+		default:
+			conflict(BOTH_MODIFIED, o.key)
+			result = append(result, o)
+		}
+	}
+
+	for _, t := range theirs {
+		if _, inOurs := om[t.key]; inOurs {
+			continue
+		}
+		if b, inBase := bm[t.key]; inBase {
+			// In ours gelöscht
+			if !reflect.DeepEqual(t.value, b) {
+				conflict(DELETED_MODIFIED, t.key)
+			}
+			continue
+		}
+		// Nur in theirs hinzugefügt
+		result = append(result, t)
+	}
+	return result
+}
+
+// Merge führt die Änderungen von 'ours' und 'theirs' gegenüber 'base' zusammen.
+// Elemente werden über ihre Ids zugeordnet: Aktivitäten, Transitionen, formale
+// Parameter, Variablen und Properties über ihre Id, Data-Mappings über den
+// Namen des formalen Parameters. Ändern beide Seiten eine Aktivität, wird sie
+// attributweise zusammengeführt.
+func Merge(base, ours, theirs *Process) (*Process, []Conflict) {
+	m := &merger{}
+
+	result := *ours
+	result.Id = m.field("process", ours.Id, "", "id", base.Id, ours.Id, theirs.Id)
+	result.Name = m.field("process", ours.Id, "", "name", base.Name, ours.Name, theirs.Name)
+	result.Description.Value = m.bytes("process", ours.Id, "", "description",
+		base.Description.Value, ours.Description.Value, theirs.Description.Value)
+
+	result.FormalParameters = toFormalParameters(m.elements("formal-parameter", "",
+		formalParameterElements(base.FormalParameters),
+		formalParameterElements(ours.FormalParameters),
+		formalParameterElements(theirs.FormalParameters), nil))
+
+	result.Variables = toVariables(m.elements("variable", "",
+		variableElements(base.Variables),
+		variableElements(ours.Variables),
+		variableElements(theirs.Variables), nil))
+
+	result.Properties = toProperties(m.elements("property", "",
+		propertyElements(base.Properties),
+		propertyElements(ours.Properties),
+		propertyElements(theirs.Properties), nil))
+
+	result.Activities = toActivities(m.elements("activity", "",
+		activityElements(base.Activities),
+		activityElements(ours.Activities),
+		activityElements(theirs.Activities),
+		func(b, o, t interface{}) interface{} {
+			return m.activity(b.(Activity), o.(Activity), t.(Activity))
+		}))
+
+	m.danglingTransitions(&result)
+
+	return &result, m.conflicts
+}
+
+func (m *merger) activity(base, ours, theirs Activity) Activity {
+	result := ours
+	result.Name = m.field("activity", ours.Id, ours.Name, "name", base.Name, ours.Name, theirs.Name)
+
+	bb, ob, tb := base.Body, ours.Body, theirs.Body
+	result.Body.ActivityType = m.field("activity", ours.Id, ours.Name, "activity-type", bb.ActivityType, ob.ActivityType, tb.ActivityType)
+	result.Body.EventType = m.field("activity", ours.Id, ours.Name, "event-type", bb.EventType, ob.EventType, tb.EventType)
+	result.Body.ImplementationType = m.field("activity", ours.Id, ours.Name, "implementation-type", bb.ImplementationType, ob.ImplementationType, tb.ImplementationType)
+	result.Body.ImplementationRefId = m.field("activity", ours.Id, ours.Name, "implementation-ref-id", bb.ImplementationRefId, ob.ImplementationRefId, tb.ImplementationRefId)
+
+	// Das Layout einer Aktivität wird als Ganzes übernommen
+	switch {
+	case reflect.DeepEqual(ob.NodeGraphicsInfo, bb.NodeGraphicsInfo):
+		result.Body.NodeGraphicsInfo = tb.NodeGraphicsInfo
+	case !reflect.DeepEqual(tb.NodeGraphicsInfo, bb.NodeGraphicsInfo) && !reflect.DeepEqual(tb.NodeGraphicsInfo, ob.NodeGraphicsInfo):
+		m.conflicts = append(m.conflicts, Conflict{Reason: BOTH_MODIFIED, Element: "node-graphics-info", Id: ours.Id, Context: ours.Name})
+	}
+
+	result.Body.DataMappings = toDataMappings(m.elements("data-mapping", ours.Name,
+		dataMappingElements(bb.DataMappings),
+		dataMappingElements(ob.DataMappings),
+		dataMappingElements(tb.DataMappings),
+		func(b, o, t interface{}) interface{} {
+			dm := o.(DataMapping)
+			dm.ActualParameter.Value = m.bytes("data-mapping", dm.FormalParameter, ours.Name, "actual-parameter",
+				b.(DataMapping).ActualParameter.Value, dm.ActualParameter.Value, t.(DataMapping).ActualParameter.Value)
+			return dm
+		}))
+
+	result.Transitions = toTransitions(m.elements("transition", ours.Name,
+		transitionElements(base.Transitions),
+		transitionElements(ours.Transitions),
+		transitionElements(theirs.Transitions),
+		func(b, o, t interface{}) interface{} {
+			tr := o.(Transition)
+			tr.To = m.field("transition", tr.Id, ours.Name, "to", b.(Transition).To, tr.To, t.(Transition).To)
+			tr.Condition = NewCondition(m.field("transition", tr.Id, ours.Name, "condition",
+				b.(Transition).Condition.Text(), tr.Condition.Text(), t.(Transition).Condition.Text()))
+			return tr
+		}))
+
+	return result
+}
+
+// danglingTransitions meldet Transitionen, deren Ziel durch den Merge entfallen ist
+func (m *merger) danglingTransitions(p *Process) {
+	ids := make(map[string]struct{})
+	for _, a := range p.Activities {
+		ids[a.Id] = struct{}{}
+	}
+	for _, a := range p.Activities {
+		for _, t := range a.Transitions {
+			if _, present := ids[t.To]; !present {
+				m.conflicts = append(m.conflicts, Conflict{
+					Reason: DANGLING_REFERENCE, Element: "transition", Id: t.Id, Context: a.Name, Field: "to", Ours: t.To,
+				})
+			}
+		}
+	}
+}
+
+func formalParameterElements(l []FormalParameter) []element {
+	r := make([]element, 0, len(l))
+	for _, v := range l {
+		r = append(r, element{v.Id, v})
+	}
+	return r
+}
+
+func toFormalParameters(l []element) []FormalParameter {
+	r := make([]FormalParameter, 0, len(l))
+	for _, e := range l {
+		r = append(r, e.value.(FormalParameter))
+	}
+	return r
+}
+
+func variableElements(l []Variable) []element {
+	r := make([]element, 0, len(l))
+	for _, v := range l {
+		r = append(r, element{v.Id, v})
+	}
+	return r
+}
+
+func toVariables(l []element) []Variable {
+	r := make([]Variable, 0, len(l))
+	for _, e := range l {
+		r = append(r, e.value.(Variable))
+	}
+	return r
+}
+
+func propertyElements(l []Property) []element {
+	r := make([]element, 0, len(l))
+	for _, v := range l {
+		r = append(r, element{v.Id, v})
+	}
+	return r
+}
+
+func toProperties(l []element) []Property {
+	r := make([]Property, 0, len(l))
+	for _, e := range l {
+		r = append(r, e.value.(Property))
+	}
+	return r
+}
+
+func activityElements(l []Activity) []element {
+	r := make([]element, 0, len(l))
+	for _, v := range l {
+		r = append(r, element{v.Id, v})
+	}
+	return r
+}
+
+func toActivities(l []element) []Activity {
+	r := make([]Activity, 0, len(l))
+	for _, e := range l {
+		r = append(r, e.value.(Activity))
+	}
+	return r
+}
+
+func dataMappingElements(l []DataMapping) []element {
+	r := make([]element, 0, len(l))
+	for _, v := range l {
+		r = append(r, element{v.FormalParameter, v})
+	}
+	return r
+}
+
+func toDataMappings(l []element) []DataMapping {
+	r := make([]DataMapping, 0, len(l))
+	for _, e := range l {
+		r = append(r, e.value.(DataMapping))
+	}
+	return r
+}
+
+func transitionElements(l []Transition) []element {
+	r := make([]element, 0, len(l))
+	for _, v := range l {
+		r = append(r, element{v.Id, v})
+	}
+	return r
+}
+
+func toTransitions(l []element) []Transition {
+	r := make([]Transition, 0, len(l))
+	for _, e := range l {
+		r = append(r, e.value.(Transition))
+	}
+	return r
+}
@@ -0,0 +1,59 @@
+package processfile_test
+
+import (
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func readA1() *processfile.Process {
+	return processfile.FromBytes(processfile.FileContent("testdata/A1.process"))
+}
+
+func TestMerge(t *testing.T) {
+	base, ours, theirs := readA1(), readA1(), readA1()
+
+	// ours ändert einen Ausdruck, theirs benennt dieselbe Aktivität um und entfernt A2
+	ours.Activities[1].Body.DataMappings[0].ActualParameter.Value = []byte(`<![CDATA["INFO"]]>`)
+	theirs.Activities[1].Name = "Protokollieren"
+	theirs.Activities[2].Transitions[0].To = "8944fcd4-f497-4c7a-9699-9315fb980d4d"
+	theirs.Activities = append(theirs.Activities[:3], theirs.Activities[4:]...)
+
+	result, conflicts := processfile.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, but was %v", conflicts)
+	}
+	if l := len(result.Activities); l != 4 {
+		t.Errorf("Expected 4 activities, but was %d", l)
+	}
+	if n := result.Activities[1].Name; n != "Protokollieren" {
+		t.Errorf("Expected name Protokollieren, but was %s", n)
+	}
+	if v := result.Activities[1].Body.DataMappings[0].ActualParameter.Text(); v != `"INFO"` {
+		t.Errorf("Expected actual parameter \"INFO\", but was %s", v)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	base, ours, theirs := readA1(), readA1(), readA1()
+
+	ours.Activities[1].Name = "Protokoll schreiben"
+	theirs.Activities[1].Name = "Protokollieren"
+	// ours entfernt A2, theirs ändert dessen Aufruf
+	ours.Activities = append(ours.Activities[:3], ours.Activities[4:]...)
+	theirs.Activities[3].Body.ImplementationRefId = "de.michael.A3"
+
+	_, conflicts := processfile.Merge(base, ours, theirs)
+	if l := len(conflicts); l != 3 {
+		t.Fatalf("Expected 3 conflicts, but was %d: %v", l, conflicts)
+	}
+	if c := conflicts[0]; c.Reason != processfile.BOTH_MODIFIED || c.Field != "name" {
+		t.Errorf("Unexpected conflict %v", c)
+	}
+	if c := conflicts[1]; c.Reason != processfile.DELETED_MODIFIED || c.Id != "e94a0a3c-21eb-4773-b210-bea8bd8ae150" {
+		t.Errorf("Unexpected conflict %v", c)
+	}
+	if c := conflicts[2]; c.Reason != processfile.DANGLING_REFERENCE {
+		t.Errorf("Unexpected conflict %v", c)
+	}
+}
@@ -0,0 +1,37 @@
+package processfile
+
+import (
+	"fmt"
+)
+
+// Problem beschreibt einen Fehler in einer Prozessdefinition.
+//
+// ActivityId und Activity benennen die betroffene Aktivität (leer, wenn der
+// Prozess als Ganzes betroffen ist), Element und Id das Element innerhalb der
+// Aktivität. Line und Column sind 1-basierte Positionen innerhalb eines
+// Ausdrucks (bei XML-Fehlern die Zeile der Datei), 0 wenn keine Position
+// bekannt ist.
+type Problem struct {
+	ActivityId string `json:"activityId,omitempty"`
+	Activity   string `json:"activity,omitempty"`
+	Element    string `json:"element"`
+	Id         string `json:"id,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+	Message    string `json:"message"`
+}
+
+func (p Problem) String() string {
+	s := ""
+	if p.Activity != "" || p.ActivityId != "" {
+		s = fmt.Sprintf("activity %q ", p.Activity)
+	}
+	s = s + p.Element
+	if p.Id != "" {
+		s = fmt.Sprintf("%s %s", s, p.Id)
+	}
+	if p.Line > 0 {
+		s = fmt.Sprintf("%s %d:%d", s, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s: %s", s, p.Message)
+}
@@ -3,7 +3,7 @@ package processfile
 import 
 (
 	"encoding/xml"
-//	"bytes"
+	"bytes"
 	"fmt"
 	"io/ioutil"
 //	"os"
@@ -11,14 +11,14 @@ import
 )
 
 type Process struct {
-	XMLName xml.Name `xml:"process"`
-	Id  string    `xml:"id,attr"`
-	Name  string    `xml:"name,attr"`
-	Description Description  `xml:"description"`
-	FormalParameters []FormalParameter `xml:"formal-parameters>formal-parameter"`
-	Variables        []Variable `xml:"variables>variable"`
-	Properties       []Property `xml:"properties>property"`
-	Activities       []Activity `xml:"activities>activity"`
+	XMLName xml.Name `xml:"process" json:"-" yaml:"-"`
+	Id  string    `xml:"id,attr" json:"id" yaml:"id"`
+	Name  string    `xml:"name,attr" json:"name" yaml:"name"`
+	Description Description  `xml:"description" json:"description" yaml:"description"`
+	FormalParameters []FormalParameter `xml:"formal-parameters>formal-parameter" json:"formalParameters" yaml:"formalParameters"`
+	Variables        []Variable `xml:"variables>variable" json:"variables" yaml:"variables"`
+	Properties       []Property `xml:"properties>property" json:"properties" yaml:"properties"`
+	Activities       []Activity `xml:"activities>activity" json:"activities" yaml:"activities"`
 }
 
 type Description struct {
@@ -26,69 +26,121 @@ type Description struct {
 }
 
 type FormalParameter struct {
-	Id  string    `xml:"id,attr"`
-	Name  string    `xml:"name,attr"`
-	Description Description  `xml:"description"`
-	Direction string `xml:"direction,attr"`
-	Hidden bool      `xml:"hidden,attr"`
-	Required bool      `xml:"required,attr"`
+	Id  string    `xml:"id,attr" json:"id" yaml:"id"`
+	Name  string    `xml:"name,attr" json:"name" yaml:"name"`
+	Description Description  `xml:"description" json:"description" yaml:"description"`
+	Direction string `xml:"direction,attr,omitempty" json:"direction" yaml:"direction"`
+	Hidden bool      `xml:"hidden,attr" json:"hidden" yaml:"hidden"`
+	Required bool      `xml:"required,attr" json:"required" yaml:"required"`
 }
 
 type Variable struct {
-	Id  string    `xml:"id,attr"`
-	Name  string    `xml:"name,attr"`
-	Hidden bool      `xml:"hidden,attr"`
+	Id  string    `xml:"id,attr" json:"id" yaml:"id"`
+	Name  string    `xml:"name,attr" json:"name" yaml:"name"`
+	Hidden bool      `xml:"hidden,attr" json:"hidden" yaml:"hidden"`
 }
 
 type Property struct {
-	Id  string    `xml:"id,attr"`
-	Name  string    `xml:"name,attr"`
-	Value string  `xml:"value,attr"`
-	Description Description  `xml:"description"`
+	Id  string    `xml:"id,attr" json:"id" yaml:"id"`
+	Name  string    `xml:"name,attr" json:"name" yaml:"name"`
+	Value string  `xml:"value,attr" json:"value" yaml:"value"`
+	Description Description  `xml:"description" json:"description" yaml:"description"`
 }
 
 type Activity struct {
-	Id  string    `xml:"id,attr"`
-	Name  string    `xml:"name,attr"`
-	Body Body  `xml:"body"`
-	Transitions []Transition  `xml:"transitions>transition"`
+	Id  string    `xml:"id,attr" json:"id" yaml:"id"`
+	Name  string    `xml:"name,attr" json:"name" yaml:"name"`
+	Body Body  `xml:"body" json:"body" yaml:"body"`
+	Transitions []Transition  `xml:"transitions>transition" json:"transitions" yaml:"transitions"`
 }
 
 type Body struct {
-	ActivityType  string    `xml:"activity-type,attr"`
-	EventType    string    `xml:"event-type,attr"`
-	ImplementationRefId  string    `xml:"implementation-ref-id,attr"`
-	ImplementationType  string    `xml:"implementation-type,attr"`
-	DataMappings []DataMapping  `xml:"data-mappings>data-mapping"`
-	NodeGraphicsInfo NodeGraphicsInfo `xml:"node-graphics-info"`
+	ActivityType  string    `xml:"activity-type,attr" json:"activityType" yaml:"activityType"`
+	EventType    string    `xml:"event-type,attr,omitempty" json:"eventType,omitempty" yaml:"eventType,omitempty"`
+	ImplementationRefId  string    `xml:"implementation-ref-id,attr,omitempty" json:"implementationRefId,omitempty" yaml:"implementationRefId,omitempty"`
+	ImplementationType  string    `xml:"implementation-type,attr,omitempty" json:"implementationType,omitempty" yaml:"implementationType,omitempty"`
+	DataMappings DataMappings  `xml:"data-mappings" json:"dataMappings" yaml:"dataMappings"`
+	NodeGraphicsInfo NodeGraphicsInfo `xml:"node-graphics-info" json:"nodeGraphicsInfo" yaml:"nodeGraphicsInfo"`
 }
 
+// DataMappings sind die Data-Mappings einer Aktivität. Eine leere Liste wird
+// als leeres data-mappings-Element geschrieben, nil gar nicht.
+type DataMappings []DataMapping
+
 type DataMapping  struct {
-	FormalParameter string  `xml:"formal-parameter,attr"`
-	ActualParameter ActualParameter  `xml:"actual-parameter"`
+	FormalParameter string  `xml:"formal-parameter,attr" json:"formalParameter" yaml:"formalParameter"`
+	ActualParameter ActualParameter  `xml:"actual-parameter" json:"actualParameter" yaml:"actualParameter"`
 }
 
 type ActualParameter struct {
 	Value []byte `xml:",innerxml"`
 }
 
+// NodeGraphicsInfo ist die Position (linke obere Ecke) und Größe einer
+// Aktivität im Diagramm. Beim Lesen sind auch Dezimalzahlen erlaubt, sie
+// werden gerundet (siehe UnmarshalXML).
 type NodeGraphicsInfo struct {
-	CoordinateX string  `xml:"coordinate-x,attr"`
-	CoordinateY string  `xml:"coordinate-y,attr"`
-	With        string  `xml:"width,attr"`
-	Height      string  `xml:"height,attr"`
+	CoordinateX int  `xml:"coordinate-x,attr" json:"coordinateX" yaml:"coordinateX"`
+	CoordinateY int  `xml:"coordinate-y,attr" json:"coordinateY" yaml:"coordinateY"`
+	Width       int  `xml:"width,attr" json:"width" yaml:"width"`
+	Height      int  `xml:"height,attr" json:"height" yaml:"height"`
 }
 
 type Transition struct {
-	Id  string    `xml:"id,attr"`
-	To  string    `xml:"to,attr"`
-	Condition Condition  `xml:"condition"`
+	Id  string    `xml:"id,attr" json:"id" yaml:"id"`
+	To  string    `xml:"to,attr" json:"to" yaml:"to"`
+	Condition *Condition  `xml:"condition,omitempty" json:"condition,omitempty" yaml:"condition,omitempty"`
 }
 
+// Condition ist die Bedingung einer Transition. Transitionen ohne Bedingung
+// haben keine Condition (nil).
 type Condition struct {
 	Value string  `xml:",chardata"`
 }
 
+// NewCondition liefert die Bedingung 'value', für einen leeren Ausdruck nil
+func NewCondition(value string) *Condition {
+	if value == "" {
+		return nil
+	}
+	return &Condition{Value: value}
+}
+
+// Text liefert den Ausdruck der Bedingung, ohne Bedingung einen leeren String
+func (c *Condition) Text() string {
+	if c == nil {
+		return ""
+	}
+	return c.Value
+}
+
+// Text liefert den Inhalt der Beschreibung ohne CDATA-Klammern und Entities
+func (d Description) Text() string {
+	return innerText(d.Value)
+}
+
+// Text liefert den Ausdruck des aktuellen Parameters ohne CDATA-Klammern und Entities
+func (a ActualParameter) Text() string {
+	return innerText(a.Value)
+}
+
+// innerText dekodiert den Zeicheninhalt eines innerxml-Feldes. CDATA-Abschnitte
+// und escapter Text ergeben dabei denselben Wert.
+func innerText(innerxml []byte) string {
+	var text bytes.Buffer
+	d := xml.NewDecoder(bytes.NewReader(append(append([]byte("<x>"), innerxml...), []byte("</x>")...)))
+	for {
+		t, err := d.Token()
+		if err != nil {
+			break
+		}
+		if c, ok := t.(xml.CharData); ok {
+			text.Write(c)
+		}
+	}
+	return text.String()
+}
+
 func (p Process) String() string {
 	return fmt.Sprintf("%s - %s", p.Name, p.Description)
 }
@@ -109,10 +161,13 @@ func FromBytes(content []byte) *Process {
 	return &p
 }
 
+// ToBytes liefert die Prozessdefinition 'p' als XML in der Form, in der sie
+// der ISP-Editor schreibt (siehe writeXML)
 func ToBytes(p *Process) []byte  {
-	content, error := xml.MarshalIndent(*p, "", "  ")
+	content, error := xml.Marshal(withDataMappings(p))
+	utils.Check(error)
+	content, error = writeXML(content)
 	utils.Check(error)
-	content = append([]byte(xml.Header), content...)
 	return content	
 }
 
@@ -0,0 +1,111 @@
+package processfile
+
+// JSONSchema beschreibt die Prozessdefinition im JSON-Format (siehe Encode).
+// Der Server veröffentlicht es unter /schema/process.json.
+const JSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://github.com/frericksm/pride/schema/process.json",
+  "title": "Prozessdefinition",
+  "type": "object",
+  "required": ["id", "name", "activities"],
+  "additionalProperties": false,
+  "properties": {
+    "id": {"type": "string"},
+    "name": {"type": "string"},
+    "description": {"type": "string"},
+    "formalParameters": {"type": "array", "items": {"$ref": "#/definitions/formalParameter"}},
+    "variables": {"type": "array", "items": {"$ref": "#/definitions/variable"}},
+    "properties": {"type": "array", "items": {"$ref": "#/definitions/property"}},
+    "activities": {"type": "array", "items": {"$ref": "#/definitions/activity"}}
+  },
+  "definitions": {
+    "formalParameter": {
+      "type": "object",
+      "required": ["id", "name"],
+      "additionalProperties": false,
+      "properties": {
+        "id": {"type": "string"},
+        "name": {"type": "string"},
+        "description": {"type": "string"},
+        "direction": {"enum": ["", "IN", "OUT", "INOUT"]},
+        "hidden": {"type": "boolean"},
+        "required": {"type": "boolean"}
+      }
+    },
+    "variable": {
+      "type": "object",
+      "required": ["id", "name"],
+      "additionalProperties": false,
+      "properties": {
+        "id": {"type": "string"},
+        "name": {"type": "string"},
+        "hidden": {"type": "boolean"}
+      }
+    },
+    "property": {
+      "type": "object",
+      "required": ["id", "name"],
+      "additionalProperties": false,
+      "properties": {
+        "id": {"type": "string"},
+        "name": {"type": "string"},
+        "value": {"type": "string"},
+        "description": {"type": "string"}
+      }
+    },
+    "activity": {
+      "type": "object",
+      "required": ["id", "name", "body"],
+      "additionalProperties": false,
+      "properties": {
+        "id": {"type": "string"},
+        "name": {"type": "string"},
+        "body": {"$ref": "#/definitions/body"},
+        "transitions": {"type": "array", "items": {"$ref": "#/definitions/transition"}}
+      }
+    },
+    "body": {
+      "type": "object",
+      "required": ["activityType"],
+      "additionalProperties": false,
+      "properties": {
+        "activityType": {"type": "string", "examples": ["EVENT", "IMPLEMENTATION"]},
+        "eventType": {"type": "string", "examples": ["START", "END"]},
+        "implementationRefId": {"type": "string"},
+        "implementationType": {"type": "string", "examples": ["TASK", "SUB_FLOW"]},
+        "dataMappings": {"type": "array", "items": {"$ref": "#/definitions/dataMapping"}},
+        "nodeGraphicsInfo": {"$ref": "#/definitions/nodeGraphicsInfo"}
+      }
+    },
+    "dataMapping": {
+      "type": "object",
+      "required": ["formalParameter", "actualParameter"],
+      "additionalProperties": false,
+      "properties": {
+        "formalParameter": {"type": "string"},
+        "actualParameter": {"type": "string", "description": "Ein Ausdruck"}
+      }
+    },
+    "nodeGraphicsInfo": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "coordinateX": {"type": "integer"},
+        "coordinateY": {"type": "integer"},
+        "width": {"type": "integer", "minimum": 0},
+        "height": {"type": "integer", "minimum": 0}
+      }
+    },
+    "transition": {
+      "type": "object",
+      "required": ["id", "to"],
+      "additionalProperties": false,
+      "properties": {
+        "id": {"type": "string"},
+        "to": {"type": "string", "description": "Die Id der Zielaktivität"},
+        "condition": {"type": "string", "description": "Ein Ausdruck, leer ohne Bedingung"}
+      }
+    }
+  }
+}
+`
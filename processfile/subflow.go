@@ -0,0 +1,38 @@
+package processfile
+
+// Signature liefert die formalen Parameter des Prozesses als Signatur eines
+// Aufrufs mit der Id 'id'. Parameter ohne Richtung sind IN-Parameter.
+func (p *Process) Signature(id string) *TaskDefinition {
+	t := &TaskDefinition{Id: id, Parameters: make([]TaskParameter, 0, len(p.FormalParameters))}
+	for _, f := range p.FormalParameters {
+		direction := f.Direction
+		if direction == "" {
+			direction = "IN"
+		}
+		t.Parameters = append(t.Parameters, TaskParameter{
+			Name:      f.Name,
+			Direction: direction,
+			Required:  f.Required,
+		})
+	}
+	return t
+}
+
+// ValidateSubFlows prüft die Data-Mappings aller SUB_FLOW-Aktivitäten von 'p'
+// gegen die formalen Parameter der aufgerufenen Prozesse: Mappings auf nicht
+// vorhandene Parameter, fehlende Pflichtparameter und OUT- und
+// INOUT-Parameter, denen keine Variable zugeordnet ist. 'resolve' liefert den
+// aufgerufenen Prozess zur implementation-ref-id. Aufrufe von Prozessen, die
+// 'resolve' nicht findet, werden nicht geprüft.
+func ValidateSubFlows(p *Process, resolve func(id string) (*Process, bool)) []Problem {
+	var problems []Problem
+	for _, a := range p.Activities {
+		if a.Body.ImplementationType != "SUB_FLOW" || a.Body.ImplementationRefId == "" {
+			continue
+		}
+		if callee, found := resolve(a.Body.ImplementationRefId); found {
+			problems = append(problems, validateMappings(a, "process", callee.Signature(a.Body.ImplementationRefId))...)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,62 @@
+package processfile_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestValidateSubFlows(t *testing.T) {
+	p := readA1()
+	callee := readA1()
+	resolve := func(id string) (*processfile.Process, bool) {
+		if strings.HasSuffix(id, "Haupt_Schufa_026") {
+			return callee, true
+		}
+		return nil, false
+	}
+	if problems := processfile.ValidateSubFlows(p, resolve); len(problems) != 0 {
+		t.Fatalf("Expected no problems, but was %v", problems)
+	}
+
+	// Meldungstext ist Pflicht und protokollId ein INOUT-Parameter, dem ein
+	// Literal zugeordnet ist
+	callee.FormalParameters[1].Required = true
+	callee.FormalParameters[2].Direction = "INOUT"
+	for i := range p.Activities {
+		if a := &p.Activities[i]; a.Name == "A1" {
+			a.Body.DataMappings = append(a.Body.DataMappings, processfile.DataMapping{FormalParameter: "kategorie"})
+		}
+	}
+	problems := processfile.ValidateSubFlows(p, resolve)
+	expected := []string{
+		"protokollId: INOUT parameter has to be mapped to a variable",
+		"kategorie: unknown parameter of process",
+		"Meldungstext: missing required parameter of process",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, but was %v", len(expected), problems)
+	}
+	for i, e := range expected {
+		if !strings.Contains(problems[i].String(), e) || problems[i].Activity != "A1" {
+			t.Errorf("Expected problem '%s', but was '%s'", e, problems[i])
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	p := readA1()
+	p.FormalParameters[0].Direction = ""
+	p.FormalParameters[1].Direction = "OUT"
+	s := p.Signature("A1")
+	if s.Id != "A1" || len(s.Parameters) != len(p.FormalParameters) {
+		t.Fatalf("Expected %d parameters of A1, but was %v", len(p.FormalParameters), s)
+	}
+	if d := s.Parameters[0].Direction; d != "IN" {
+		t.Errorf("Expected direction IN for parameter without direction, but was '%s'", d)
+	}
+	if d := s.Parameters[1].Direction; d != "OUT" {
+		t.Errorf("Expected direction OUT, but was '%s'", d)
+	}
+}
@@ -0,0 +1,148 @@
+package processfile
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// Parse liest eine Prozessdefinition. Anders als FromBytes liefert Parse bei
+// ungültigem XML einen Fehler statt einer Panic.
+func Parse(content []byte) (*Process, error) {
+	if len(content) == 0 {
+		return nil, errors.New("empty process definition")
+	}
+	var p Process
+	if err := xml.Unmarshal(content, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ParseProblem beschreibt den Fehler 'err' von Parse als Problem
+func ParseProblem(err error) Problem {
+	problem := Problem{Element: "process", Message: err.Error()}
+	if se, ok := err.(*xml.SyntaxError); ok {
+		problem.Line = se.Line
+		problem.Message = se.Msg
+	}
+	return problem
+}
+
+// Validate prüft die Struktur einer Prozessdefinition: eindeutige Ids und
+// Namen, genau ein START- und mindestens ein END-Event, Transitionen zu
+// vorhandenen Aktivitäten und die Referenzen von TASK- und
+// SUB_FLOW-Aktivitäten. Die Ausdrücke der Data-Mappings und Bedingungen prüft
+// expression.LintProcess.
+func Validate(p *Process) []Problem {
+	var problems []Problem
+	add := func(a *Activity, element string, id string, format string, args ...interface{}) {
+		problem := Problem{Element: element, Id: id, Message: fmt.Sprintf(format, args...)}
+		if a != nil {
+			problem.ActivityId, problem.Activity = a.Id, a.Name
+		}
+		problems = append(problems, problem)
+	}
+
+	if p.Id == "" {
+		add(nil, "process", "", "missing id")
+	}
+
+	names := make(map[string]string)
+	for _, f := range p.FormalParameters {
+		if f.Name == "" {
+			add(nil, "formal-parameter", f.Id, "missing name")
+		} else if _, present := names[f.Name]; present {
+			add(nil, "formal-parameter", f.Name, "duplicate name")
+		}
+		names[f.Name] = "formal-parameter"
+		// Ohne Richtung ist ein Parameter ein IN-Parameter (siehe Signature)
+		switch f.Direction {
+		case "", "IN", "OUT", "INOUT":
+		default:
+			add(nil, "formal-parameter", f.Name, "invalid direction '%s'", f.Direction)
+		}
+	}
+	for _, v := range p.Variables {
+		if v.Name == "" {
+			add(nil, "variable", v.Id, "missing name")
+		} else if kind, present := names[v.Name]; present {
+			add(nil, "variable", v.Name, "name already used by a %s", kind)
+		}
+		names[v.Name] = "variable"
+	}
+
+	activities := make(map[string]struct{})
+	for _, a := range p.Activities {
+		if a.Id == "" {
+			continue
+		}
+		if _, present := activities[a.Id]; present {
+			add(nil, "activity", a.Id, "duplicate id")
+		}
+		activities[a.Id] = struct{}{}
+	}
+
+	starts, ends := 0, 0
+	for i := range p.Activities {
+		a := &p.Activities[i]
+		if a.Id == "" {
+			add(a, "activity", "", "missing id")
+		}
+
+		switch a.Body.ActivityType {
+		case "EVENT":
+			switch a.Body.EventType {
+			case "START":
+				starts++
+			case "END":
+				ends++
+				if len(a.Transitions) > 0 {
+					add(a, "activity", a.Id, "END event with outgoing transitions")
+				}
+			default:
+				add(a, "activity", a.Id, "invalid event type '%s'", a.Body.EventType)
+			}
+		case "IMPLEMENTATION":
+			switch a.Body.ImplementationType {
+			case "TASK", "SUB_FLOW":
+				if a.Body.ImplementationRefId == "" {
+					add(a, "activity", a.Id, "missing implementation-ref-id")
+				}
+			default:
+				add(a, "activity", a.Id, "invalid implementation type '%s'", a.Body.ImplementationType)
+			}
+		default:
+			add(a, "activity", a.Id, "invalid activity type '%s'", a.Body.ActivityType)
+		}
+
+		for _, m := range a.Body.DataMappings {
+			if m.FormalParameter == "" {
+				add(a, "data-mapping", "", "missing formal-parameter")
+			}
+		}
+
+		// Die Ids der Transitionen sind je Aktivität eindeutig
+		transitions := make(map[string]struct{})
+		for _, t := range a.Transitions {
+			if t.Id == "" {
+				add(a, "transition", "", "missing id")
+			} else if _, present := transitions[t.Id]; present {
+				add(a, "transition", t.Id, "duplicate id")
+			}
+			transitions[t.Id] = struct{}{}
+			if _, present := activities[t.To]; !present {
+				add(a, "transition", t.Id, "unknown target activity '%s'", t.To)
+			}
+		}
+	}
+
+	if starts != 1 {
+		add(nil, "process", p.Id, "expected exactly one START event, but found %d", starts)
+	}
+	if ends == 0 {
+		add(nil, "process", p.Id, "no END event")
+	}
+
+	return problems
+}
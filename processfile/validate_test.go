@@ -0,0 +1,52 @@
+package processfile_test
+
+import (
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+)
+
+func TestValidate(t *testing.T) {
+	p := readA1()
+	if problems := processfile.Validate(p); len(problems) != 0 {
+		t.Fatalf("Expected no problems, but was %v", problems)
+	}
+
+	// Parameter ohne Richtung sind IN-Parameter
+	p.FormalParameters[0].Direction = ""
+	if problems := processfile.Validate(p); len(problems) != 0 {
+		t.Fatalf("Expected no problems for parameter without direction, but was %v", problems)
+	}
+	p.FormalParameters[0].Direction = "INPUT"
+	if problems := processfile.Validate(p); len(problems) != 1 || problems[0].Message != "invalid direction 'INPUT'" {
+		t.Errorf("Expected invalid direction, but was %v", problems)
+	}
+	p.FormalParameters[0].Direction = "IN"
+
+	// Transition ins Leere und ein zweites START-Event
+	p.Activities[1].Transitions[0].To = "unbekannt"
+	start := p.Activities[0]
+	start.Id = "start2"
+	p.Activities = append(p.Activities, start)
+
+	problems := processfile.Validate(p)
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, but was %v", problems)
+	}
+	if problems[0].Element != "transition" || problems[0].ActivityId != p.Activities[1].Id {
+		t.Errorf("Unexpected problem %s", problems[0])
+	}
+	if problems[1].Message != "expected exactly one START event, but found 2" {
+		t.Errorf("Unexpected problem %s", problems[1])
+	}
+}
+
+func TestParse(t *testing.T) {
+	_, err := processfile.Parse([]byte("<process id=\"A1\">\n<activities>\n</process>"))
+	if err == nil {
+		t.Fatal("Expected syntax error")
+	}
+	if problem := processfile.ParseProblem(err); problem.Line != 3 {
+		t.Errorf("Expected problem in line 3, but was %s", problem)
+	}
+}
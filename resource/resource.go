@@ -1,16 +1,16 @@
 package resource
 
 import (
-	"os"
+	"encoding/json"
 	"net/http"
 //	"fmt"
-	"io"
 	"io/ioutil"
 //	"bufio"
-//	"strings"
+	"strings"
 	"path/filepath"
 	"regexp"
 
+	"github.com/frericksm/pride/processfile"
 	"github.com/frericksm/pride/utils"
 	pcontext "github.com/frericksm/pride/context"	
 )
@@ -20,7 +20,7 @@ type Handler struct {}
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	//fmt.Printf("ServeHTTP: %s" , r.RequestURI)
-	slashed_path := filepath.ToSlash(r.RequestURI)
+	slashed_path := filepath.ToSlash(r.URL.Path)
 	re , _ := regexp.Compile("bundles/(.+?)/resources/(.*)")
 	groups := re.FindStringSubmatch(slashed_path)
 	if len(groups) != 3 {
@@ -32,38 +32,69 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	path := groups[2]
 
 	bundle_root_dir := pcontext.BundleRootDir(r.Context())
+	fs := pcontext.Storage(r.Context())
 
 	filename :=filepath.Join(bundle_root_dir, bundle_name, path)
 
 	if  r.Method == http.MethodGet {
-		content, err := ioutil.ReadFile(filename)
+		content, err := fs.ReadFile(filename)
 		utils.Check(err)
-		w.Header().Set("Content-Type", "application/octet-stream")
-		w.Write(content)
-	} else if  r.Method == http.MethodPut {
 
-		f, err := os.Create(filename)
-		utils.Check(err)
-		defer f.Close()
+		// Prozessdateien werden auf Wunsch als JSON geliefert (siehe
+		// processfile.JSONSchema)
+		if filepath.Ext(filename) == ".process" && strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			p, err := processfile.Decode(content, processfile.FORMAT_XML)
+			if err != nil {
+				body, err := json.Marshal([]processfile.Problem{processfile.ParseProblem(err)})
+				utils.Check(err)
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write(body)
+				return
+			}
+			body, err := processfile.Encode(p, processfile.FORMAT_JSON)
+			utils.Check(err)
+			w.Write(body)
+			return
+		}
 
-		//fw := bufio.NewWriter(f)
-		_, err = io.Copy(f, r.Body)
-		utils.Check(err)
-		f.Sync()
-		//fw.Flush()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(content)
+	} else if  r.Method == http.MethodPut || r.Method == http.MethodPost {
 
-	} else if  r.Method == http.MethodPost {
+		// Erst den ganzen Inhalt lesen: ein abgebrochener Upload lässt die
+		// Datei unverändert. Das Schreiben selbst ist atomar (storage.OS).
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("400 - Bad request!"))
+			return
+		}
 
-		f, err := os.Create(filename)
-		utils.Check(err)
-		defer f.Close()
+		// Prozessdateien werden vor dem Schreiben geprüft, außer mit ?force=true
+		if filepath.Ext(filename) == ".process" && r.URL.Query().Get("force") != "true" {
+			if problems := validate(content); len(problems) > 0 {
+				body, err := json.Marshal(problems)
+				utils.Check(err)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write(body)
+				return
+			}
+		}
 
-		//fw := bufio.NewWriter(f)
-		_, err = io.Copy(f, r.Body)
+		err = fs.WriteFile(filename, content, 0644)
 		utils.Check(err)
-		f.Sync()
-		//fw.Flush()
 
 	}
 
 }
+
+// validate liefert die Probleme der Prozessdefinition 'content'
+func validate(content []byte) []processfile.Problem {
+	p, err := processfile.Parse(content)
+	if err != nil {
+		return []processfile.Problem{processfile.ParseProblem(err)}
+	}
+	return processfile.Validate(p)
+}
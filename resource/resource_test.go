@@ -0,0 +1,84 @@
+package resource_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pcontext "github.com/frericksm/pride/context"
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/resource"
+	"github.com/frericksm/pride/storage"
+)
+
+func put(fs storage.Storage, uri string, body string) *httptest.ResponseRecorder {
+	h := &pcontext.Handler{BundleRootDir: "/bundles", Storage: fs, Handler: &resource.Handler{}}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, uri, strings.NewReader(body)))
+	return w
+}
+
+func TestPutProcess(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	valid, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := put(fs, "/bundles/b1/resources/de/michael/A1.process", string(valid)); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, but was %d", w.Code)
+	}
+
+	w := put(fs, "/bundles/b1/resources/de/michael/A1.process", `<process id="A1"><activities>`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422, but was %d", w.Code)
+	}
+	var problems []processfile.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problems); err != nil || len(problems) != 1 {
+		t.Errorf("Expected 1 problem, but was %s", w.Body)
+	}
+	if content, _ := fs.ReadFile("/bundles/b1/de/michael/A1.process"); string(content) != string(valid) {
+		t.Errorf("Expected A1.process to be unchanged")
+	}
+
+	if w := put(fs, "/bundles/b1/resources/de/michael/A1.process?force=true", `<process id="A1"/>`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with force, but was %d", w.Code)
+	}
+	if content, _ := fs.ReadFile("/bundles/b1/de/michael/A1.process"); string(content) != `<process id="A1"/>` {
+		t.Errorf("Expected forced content, but was %s", content)
+	}
+}
+
+func TestGetProcessAsJSON(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles/b1/de/michael", 0755)
+	content, err := ioutil.ReadFile("../processfile/testdata/A1.process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.WriteFile("/bundles/b1/de/michael/A1.process", content, 0644)
+	h := &pcontext.Handler{BundleRootDir: "/bundles", Storage: fs, Handler: &resource.Handler{}}
+
+	r := httptest.NewRequest(http.MethodGet, "/bundles/b1/resources/de/michael/A1.process", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected 200 with JSON, but was %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	p, err := processfile.Decode(w.Body.Bytes(), processfile.FORMAT_JSON)
+	if err != nil || p.Id != "de.michael.A1" || len(p.Activities) != 5 {
+		t.Errorf("Expected process de.michael.A1, but was %s", w.Body)
+	}
+
+	// Ohne Accept-Header wird die Datei unverändert geliefert
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bundles/b1/resources/de/michael/A1.process", nil))
+	if w.Body.String() != string(content) {
+		t.Errorf("Expected unchanged content, but was %s", w.Body)
+	}
+}
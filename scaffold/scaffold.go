@@ -0,0 +1,261 @@
+// Package scaffold erzeugt neue Bundles und Prozessdateien, wahlweise aus
+// benutzerdefinierten Templates.
+//
+// Ein Template-Verzeichnis kann enthalten:
+//
+//   bundle/           Dateien, die in jedes neue Bundle kopiert werden
+//   process.process   das Template neuer Prozessdateien
+//
+// Die Dateien sind Templates im Format von text/template. Verfügbar sind die
+// Felder von Data und die Funktion 'uuid', die bei jedem Aufruf eine neue
+// UUID liefert. Fehlt META-INF/MANIFEST.MF im Bundle-Template oder das
+// Prozess-Template, werden die eingebauten Vorlagen verwendet.
+package scaffold
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+)
+
+// Das Verzeichnis der Bundle-Templates im Template-Verzeichnis
+const BUNDLE_TEMPLATE_DIR = "bundle"
+
+// Das Prozess-Template im Template-Verzeichnis
+const PROCESS_TEMPLATE_FILE = "process.process"
+
+// Data sind die Werte, die in den Templates verfügbar sind
+type Data struct {
+	// Der Name des Bundles
+	Bundle string
+	// Die Id des Prozesses, z.B. de.michael.A1
+	Id string
+	// Der Name des Prozesses, der letzte Teil der Id
+	Name string
+	// Das Package des Prozesses, z.B. de.michael
+	Package string
+}
+
+var process_id = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// CheckProcessId prüft, ob 'id' eine gültige Prozess-Id ist: durch Punkte
+// getrennte Namen aus Buchstaben, Ziffern und '_'
+func CheckProcessId(id string) error {
+	if !process_id.MatchString(id) {
+		return errors.New(fmt.Sprintf("Invalid process id '%s'", id))
+	}
+	return nil
+}
+
+// NewUUID liefert eine zufällige UUID (Version 4)
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Manifest liefert das eingebaute META-INF/MANIFEST.MF des Bundles 'name'
+func Manifest(name string) []byte {
+	return []byte(fmt.Sprintf("Manifest-Version: 1.0\nBundle-ManifestVersion: 2\nBundle-SymbolicName: %s\nBundle-Version: 1.0.0\n", name))
+}
+
+func graphicsInfo(x, y int) processfile.NodeGraphicsInfo {
+	return processfile.NodeGraphicsInfo{
+		CoordinateX: x,
+		CoordinateY: y,
+		Width:       30,
+		Height:      30,
+	}
+}
+
+// NewProcess liefert den eingebauten Prozess mit der Id 'id': ein START- und
+// ein END-Event mit einer Transition dazwischen
+func NewProcess(id string) *processfile.Process {
+	start, end := NewUUID(), NewUUID()
+	return &processfile.Process{
+		Id:   id,
+		Name: id[strings.LastIndex(id, ".")+1:],
+		Activities: []processfile.Activity{
+			{
+				Id:   start,
+				Name: "<Start>",
+				Body: processfile.Body{
+					ActivityType:     "EVENT",
+					EventType:        "START",
+					NodeGraphicsInfo: graphicsInfo(100, 20),
+				},
+				Transitions: []processfile.Transition{{Id: NewUUID(), To: end}},
+			},
+			{
+				Id:   end,
+				Name: "<Ende>",
+				Body: processfile.Body{
+					ActivityType:     "EVENT",
+					EventType:        "END",
+					NodeGraphicsInfo: graphicsInfo(100, 120),
+				},
+			},
+		},
+	}
+}
+
+// render führt das Template 'content' mit 'data' aus
+func render(name string, content []byte, data Data) ([]byte, error) {
+	t, err := template.New(name).Funcs(template.FuncMap{"uuid": NewUUID}).Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ProcessContent liefert den Inhalt einer neuen Prozessdatei mit der Id
+// 'data.Id' aus dem Prozess-Template im Verzeichnis 'templates' oder, ohne
+// Template, aus NewProcess. Id und Name werden immer gesetzt. Ein Template,
+// das keine gültige Prozessdefinition ergibt, ist ein Fehler.
+func ProcessContent(fs storage.Storage, templates string, data Data) ([]byte, error) {
+	if templates == "" {
+		return processfile.ToBytes(NewProcess(data.Id)), nil
+	}
+	path := filepath.Join(templates, PROCESS_TEMPLATE_FILE)
+	content, err := fs.ReadFile(path)
+	if os.IsNotExist(err) {
+		return processfile.ToBytes(NewProcess(data.Id)), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	content, err = render(PROCESS_TEMPLATE_FILE, content, data)
+	if err != nil {
+		return nil, err
+	}
+	p, err := processfile.Parse(content)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", path, err))
+	}
+	p.Id, p.Name = data.Id, data.Name
+	if problems := processfile.Validate(p); len(problems) > 0 {
+		return nil, errors.New(fmt.Sprintf("%s: %s", path, problems[0]))
+	}
+	return processfile.ToBytes(p), nil
+}
+
+// ProcessData liefert die Template-Werte des Prozesses 'id' im Bundle 'bundle'
+func ProcessData(bundle string, id string) Data {
+	i := strings.LastIndex(id, ".")
+	data := Data{Bundle: bundle, Id: id, Name: id[i+1:]}
+	if i > 0 {
+		data.Package = id[:i]
+	}
+	return data
+}
+
+// CreateBundle legt das Bundle 'name' im Verzeichnis 'bundle_root_dir' an
+// und liefert sein Verzeichnis. Schlägt das Anlegen fehl, wird das Bundle
+// wieder entfernt.
+func CreateBundle(fs storage.Storage, bundle_root_dir string, name string, templates string) (string, error) {
+	bundle_dir := filepath.Join(bundle_root_dir, name)
+	if err := fs.Mkdir(bundle_dir, 0755); os.IsExist(err) {
+		return "", errors.New(fmt.Sprintf("Bundle '%s' already exists", name))
+	} else if err != nil {
+		return "", err
+	}
+
+	if err := createBundleFiles(fs, bundle_dir, name, templates); err != nil {
+		fs.RemoveAll(bundle_dir)
+		return "", err
+	}
+	return bundle_dir, nil
+}
+
+func createBundleFiles(fs storage.Storage, bundle_dir string, name string, templates string) error {
+	data := Data{Bundle: name}
+	if templates != "" {
+		template_dir := filepath.Join(templates, BUNDLE_TEMPLATE_DIR)
+		if _, err := fs.Stat(template_dir); err == nil {
+			err := storage.Walk(fs, template_dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(template_dir, path)
+				if err != nil || rel == "." {
+					return err
+				}
+				dest := filepath.Join(bundle_dir, rel)
+				if info.IsDir() {
+					return fs.Mkdir(dest, 0755)
+				}
+				content, err := fs.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				if content, err = render(rel, content, data); err != nil {
+					return err
+				}
+				return fs.WriteFile(dest, content, 0644)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	manifest := filepath.Join(bundle_dir, "META-INF", "MANIFEST.MF")
+	if _, err := fs.Stat(manifest); err == nil {
+		return nil
+	}
+	if err := storage.MkdirAll(fs, filepath.Dir(manifest), 0755); err != nil {
+		return err
+	}
+	return fs.WriteFile(manifest, Manifest(name), 0644)
+}
+
+// ProcessPath liefert den Pfad der Prozessdatei 'id' im Bundle-Verzeichnis
+// 'bundle_dir'
+func ProcessPath(bundle_dir string, id string) string {
+	return filepath.Join(bundle_dir, strings.Replace(id, ".", "/", -1)+".process")
+}
+
+// CreateProcess legt die Prozessdatei 'id' im Bundle 'bundle' an und liefert
+// ihren Pfad. Fehlende Verzeichnisse des Packages werden angelegt.
+func CreateProcess(fs storage.Storage, bundle_root_dir string, bundle string, id string, templates string) (string, error) {
+	if err := CheckProcessId(id); err != nil {
+		return "", err
+	}
+	bundle_dir := filepath.Join(bundle_root_dir, bundle)
+	if fi, err := fs.Stat(bundle_dir); err != nil || !fi.IsDir() {
+		return "", errors.New(fmt.Sprintf("Unknown bundle '%s'", bundle))
+	}
+	path := ProcessPath(bundle_dir, id)
+	if _, err := fs.Stat(path); err == nil {
+		return "", errors.New(fmt.Sprintf("A process '%s' already exists", id))
+	}
+
+	content, err := ProcessContent(fs, templates, ProcessData(bundle, id))
+	if err != nil {
+		return "", err
+	}
+	if err := storage.MkdirAll(fs, filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := fs.WriteFile(path, content, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,96 @@
+package scaffold_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/scaffold"
+	"github.com/frericksm/pride/storage"
+)
+
+func TestCreateProcess(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles", 0755)
+	if _, err := scaffold.CreateBundle(fs, "/bundles", "b1", ""); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := fs.ReadFile("/bundles/b1/META-INF/MANIFEST.MF")
+	if err != nil || !strings.Contains(string(manifest), "Bundle-SymbolicName: b1\n") {
+		t.Errorf("Expected manifest of b1, but was %s", manifest)
+	}
+	if _, err := scaffold.CreateBundle(fs, "/bundles", "b1", ""); err == nil {
+		t.Errorf("Expected error creating an existing bundle")
+	}
+
+	path, err := scaffold.CreateProcess(fs, "/bundles", "b1", "de.michael.A3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bundles/b1/de/michael/A3.process" {
+		t.Errorf("Expected /bundles/b1/de/michael/A3.process, but was %s", path)
+	}
+	content, _ := fs.ReadFile(path)
+	p, err := processfile.Parse(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := processfile.Validate(p); len(problems) != 0 {
+		t.Errorf("Expected a valid process, but was %v", problems)
+	}
+	if p.Id != "de.michael.A3" || p.Name != "A3" || len(p.Activities) != 2 || p.Activities[0].Transitions[0].To != p.Activities[1].Id {
+		t.Errorf("Expected process A3 with START and END, but was %s", content)
+	}
+
+	if _, err := scaffold.CreateProcess(fs, "/bundles", "b1", "de.michael.A3", ""); err == nil {
+		t.Errorf("Expected error creating an existing process")
+	}
+	for _, id := range []string{"de..A3", "de/michael", ".A3", "1de.A3"} {
+		if _, err := scaffold.CreateProcess(fs, "/bundles", "b1", id, ""); err == nil {
+			t.Errorf("Expected error for invalid id '%s'", id)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	fs := storage.NewMemory()
+	storage.MkdirAll(fs, "/bundles", 0755)
+	storage.MkdirAll(fs, "/templates/bundle/de", 0755)
+	fs.WriteFile("/templates/bundle/de/README.txt", []byte("Bundle {{.Bundle}}"), 0644)
+	fs.WriteFile("/templates/process.process", []byte(`<process>
+  <formal-parameters>
+    <formal-parameter id="{{uuid}}" name="protokollId" direction="IN"/>
+  </formal-parameters>
+  <activities>
+    <activity id="s" name="{{.Package}}"><body activity-type="EVENT" event-type="START"/>
+      <transitions><transition id="{{uuid}}" to="e"/></transitions></activity>
+    <activity id="e"><body activity-type="EVENT" event-type="END"/></activity>
+  </activities>
+</process>`), 0644)
+
+	if _, err := scaffold.CreateBundle(fs, "/bundles", "b1", "/templates"); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := fs.ReadFile("/bundles/b1/de/README.txt"); string(content) != "Bundle b1" {
+		t.Errorf("Expected rendered README.txt, but was %s", content)
+	}
+	if _, err := fs.Stat("/bundles/b1/META-INF/MANIFEST.MF"); err != nil {
+		t.Errorf("Expected generated manifest, but was %v", err)
+	}
+
+	path, err := scaffold.CreateProcess(fs, "/bundles", "b1", "de.michael.A3", "/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := fs.ReadFile(path)
+	p := processfile.FromBytes(content)
+	if p.Id != "de.michael.A3" || len(p.FormalParameters) != 1 || p.Activities[0].Name != "de.michael" {
+		t.Errorf("Expected process from template, but was %s", content)
+	}
+
+	// Ein Template ohne END-Event ergibt keinen gültigen Prozess
+	fs.WriteFile("/templates/process.process", []byte(`<process><activities/></process>`), 0644)
+	if _, err := scaffold.CreateProcess(fs, "/bundles", "b1", "de.michael.A4", "/templates"); err == nil {
+		t.Errorf("Expected error for an invalid template")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/bundle"
+	"github.com/frericksm/pride/expression"
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/simulate"
+	"github.com/frericksm/pride/storage"
+)
+
+// Simulate führt eine Prozessdatei mit gestubten Tasks aus und gibt das
+// Protokoll und den Endzustand der Variablen aus. Aufgerufene Prozesse werden
+// über den Index der Bundles im Verzeichnis 'dir' gefunden.
+func simulateProcess(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("simulate erwartet genau eine Prozessdatei")
+	}
+
+	fixture := &simulate.Fixture{}
+	if path := c.String("fixture"); path != "" {
+		var err error
+		if fixture, err = simulate.LoadFixture(path); err != nil {
+			return err
+		}
+	}
+
+	var index *bundle.Index
+	s := &simulate.Simulator{
+		Fixture:  fixture,
+		MaxSteps: c.Int("max-steps"),
+		Resolve: func(id string) (*processfile.Process, error) {
+			if index == nil {
+				index = bundle.CreateIndex(storage.OS{}, bundleRootDir(c))
+			}
+			path, found := index.ProcessFile(id)
+			if !found {
+				return nil, fmt.Errorf("process '%s' not found in index", id)
+			}
+			return readProcessFile(path)
+		},
+	}
+
+	p, err := readProcessFile(c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 2)
+	}
+	result, err := s.Run(p)
+
+	for _, step := range result.Trace {
+		fmt.Println(step)
+	}
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Println()
+	names := make([]string, 0, len(result.Variables))
+	for name := range result.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s = %s\n", name, expression.Format(result.Variables[name]))
+	}
+	return nil
+}
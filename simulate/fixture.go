@@ -0,0 +1,103 @@
+package simulate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/frericksm/pride/expression"
+)
+
+// Stub legt die Ausgaben einer simulierten TASK- oder SUB_FLOW-Aktivität fest.
+// Outputs bildet die Namen der formalen Parameter auf die gelieferten Werte ab.
+type Stub struct {
+	Outputs map[string]interface{} `json:"outputs" yaml:"outputs"`
+}
+
+// Fixture beschreibt die Eingaben und Stubs einer Simulation.
+//
+// Tasks enthält Stubs je ImplementationRefId einer TASK-Aktivität, Processes
+// je Id eines aufgerufenen Prozesses. Für Aufrufe ohne Stub wird der Prozess
+// über den Index gesucht und ebenfalls simuliert. Activities enthält Stubs je
+// Aktivitäts-Id und hat Vorrang vor Tasks und Processes.
+type Fixture struct {
+	Inputs     map[string]interface{} `json:"inputs" yaml:"inputs"`
+	Tasks      map[string]Stub        `json:"tasks" yaml:"tasks"`
+	Processes  map[string]Stub        `json:"processes" yaml:"processes"`
+	Activities map[string]Stub        `json:"activities" yaml:"activities"`
+}
+
+// LoadFixture liest ein Fixture im JSON- oder YAML-Format (nach Endung der Datei)
+func LoadFixture(path string) (*Fixture, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixture
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(content, &f)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &f)
+	default:
+		err = fmt.Errorf("unsupported fixture format '%s'", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.Inputs = convertMap(f.Inputs)
+	for _, stubs := range []map[string]Stub{f.Tasks, f.Processes, f.Activities} {
+		for k, s := range stubs {
+			s.Outputs = convertMap(s.Outputs)
+			stubs[k] = s
+		}
+	}
+	return &f, nil
+}
+
+func convertMap(m map[string]interface{}) map[string]interface{} {
+	r := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		r[k] = Value(v)
+	}
+	return r
+}
+
+// Value überführt einen Wert aus JSON oder YAML in einen Wert der Auswertung.
+// Schlüssel von Maps werden zu Keywords, ganzzahlige Zahlen zu int64.
+func Value(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return int64(x)
+		}
+		return x
+	case []interface{}:
+		l := make([]interface{}, len(x))
+		for i, e := range x {
+			l[i] = Value(e)
+		}
+		return l
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(x))
+		for k, e := range x {
+			m[expression.KeywordValue(k)] = Value(e)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(x))
+		for k, e := range x {
+			m[expression.KeywordValue(fmt.Sprint(k))] = Value(e)
+		}
+		return m
+	}
+	return v
+}
@@ -0,0 +1,248 @@
+// Package simulate führt Prozessdefinitionen ohne die Prozess-Engine der ISP
+// aus. TASK-Aktivitäten werden durch Stubs ersetzt, SUB_FLOW-Aktivitäten
+// rekursiv simuliert.
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/frericksm/pride/expression"
+	"github.com/frericksm/pride/processfile"
+)
+
+// Resolver liefert die Prozessdefinition zur Id eines aufgerufenen Prozesses
+type Resolver func(process_definition_id string) (*processfile.Process, error)
+
+// Step ist ein Schritt im Protokoll einer Simulation
+type Step struct {
+	Depth      int
+	ProcessId  string
+	ActivityId string
+	Activity   string
+	Kind       string
+	Inputs     map[string]interface{}
+	Outputs    map[string]interface{}
+}
+
+func (s Step) String() string {
+	line := fmt.Sprintf("%s%s %q (%s)", strings.Repeat("  ", s.Depth), s.Kind, s.Activity, s.ActivityId)
+	if len(s.Inputs) > 0 {
+		line = line + " in: " + formatValues(s.Inputs)
+	}
+	if len(s.Outputs) > 0 {
+		line = line + " out: " + formatValues(s.Outputs)
+	}
+	return line
+}
+
+func formatValues(values map[string]interface{}) string {
+	names := make([]string, 0, len(values))
+	for n := range values {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	l := make([]string, len(names))
+	for i, n := range names {
+		l[i] = n + "=" + expression.Format(values[n])
+	}
+	return strings.Join(l, ", ")
+}
+
+// Result ist das Ergebnis einer Simulation: das Protokoll aller ausgeführten
+// Aktivitäten und der Endzustand der Variablen und Parameter des Prozesses
+type Result struct {
+	Trace     []Step
+	Variables map[string]interface{}
+}
+
+// Simulator simuliert Prozessdefinitionen
+type Simulator struct {
+	Fixture *Fixture
+	Resolve Resolver
+	// MaxSteps begrenzt die Anzahl der ausgeführten Aktivitäten, damit
+	// Zyklen im Prozess terminieren. 0 bedeutet 10000.
+	MaxSteps int
+
+	trace []Step
+}
+
+// Run simuliert 'p' mit den Eingaben aus dem Fixture
+func (s *Simulator) Run(p *processfile.Process) (*Result, error) {
+	if s.Fixture == nil {
+		s.Fixture = &Fixture{}
+	}
+	if s.MaxSteps == 0 {
+		s.MaxSteps = 10000
+	}
+	s.trace = nil
+
+	env, err := s.run(p, s.Fixture.Inputs, 0)
+	if err != nil {
+		return &Result{Trace: s.trace}, err
+	}
+	return &Result{Trace: s.trace, Variables: map[string]interface{}(env)}, nil
+}
+
+func findStart(p *processfile.Process) (*processfile.Activity, error) {
+	for i, a := range p.Activities {
+		if a.Body.ActivityType == "EVENT" && a.Body.EventType == "START" {
+			return &p.Activities[i], nil
+		}
+	}
+	return nil, fmt.Errorf("process '%s' has no START event", p.Id)
+}
+
+func (s *Simulator) run(p *processfile.Process, inputs map[string]interface{}, depth int) (expression.Env, error) {
+	env := make(expression.Env)
+	for _, fp := range p.FormalParameters {
+		env[fp.Name] = nil
+		if fp.Direction != "OUT" {
+			if v, ok := inputs[fp.Name]; ok {
+				env[fp.Name] = v
+			}
+		}
+	}
+	for _, v := range p.Variables {
+		env[v.Name] = nil
+	}
+
+	activities := make(map[string]*processfile.Activity)
+	for i, a := range p.Activities {
+		activities[a.Id] = &p.Activities[i]
+	}
+
+	a, err := findStart(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if len(s.trace) >= s.MaxSteps {
+			return nil, fmt.Errorf("process '%s': more than %d steps, aborted", p.Id, s.MaxSteps)
+		}
+
+		step := Step{Depth: depth, ProcessId: p.Id, ActivityId: a.Id, Activity: a.Name}
+		if err := s.execute(a, env, &step, depth); err != nil {
+			return nil, fmt.Errorf("process '%s', activity '%s': %s", p.Id, a.Name, err)
+		}
+
+		if a.Body.ActivityType == "EVENT" && a.Body.EventType == "END" {
+			return env, nil
+		}
+
+		next, err := nextActivity(a, env)
+		if err != nil {
+			return nil, fmt.Errorf("process '%s', activity '%s': %s", p.Id, a.Name, err)
+		}
+		if a = activities[next]; a == nil {
+			return nil, fmt.Errorf("process '%s': unknown activity '%s'", p.Id, next)
+		}
+	}
+}
+
+// execute führt eine Aktivität aus und protokolliert sie
+func (s *Simulator) execute(a *processfile.Activity, env expression.Env, step *Step, depth int) error {
+	body := a.Body
+	switch {
+	case body.ActivityType == "EVENT":
+		step.Kind = body.EventType
+		s.trace = append(s.trace, *step)
+		return nil
+	case body.ImplementationType == "TASK" || body.ImplementationType == "SUB_FLOW":
+		step.Kind = body.ImplementationType
+	default:
+		return fmt.Errorf("unsupported activity type '%s'", body.ActivityType)
+	}
+
+	inputs := make(map[string]interface{})
+	for _, dm := range body.DataMappings {
+		v, err := expression.EvalString(dm.ActualParameter.Text(), env)
+		if err != nil {
+			return fmt.Errorf("data-mapping '%s': %s", dm.FormalParameter, err)
+		}
+		inputs[dm.FormalParameter] = v
+	}
+	step.Inputs = inputs
+
+	stub, found := s.Fixture.Activities[a.Id]
+	if !found && body.ImplementationType == "TASK" {
+		stub, found = s.Fixture.Tasks[body.ImplementationRefId]
+	}
+	if !found && body.ImplementationType == "SUB_FLOW" {
+		stub, found = s.Fixture.Processes[body.ImplementationRefId]
+	}
+
+	var outputs map[string]interface{}
+	if found || body.ImplementationType == "TASK" {
+		outputs = stub.Outputs
+		s.trace = append(s.trace, *step)
+	} else {
+		if s.Resolve == nil {
+			return fmt.Errorf("no stub and no resolver for process '%s'", body.ImplementationRefId)
+		}
+		callee, err := s.Resolve(body.ImplementationRefId)
+		if err != nil {
+			return err
+		}
+		s.trace = append(s.trace, *step)
+		index := len(s.trace) - 1
+
+		calleeEnv, err := s.run(callee, inputs, depth+1)
+		if err != nil {
+			return err
+		}
+		outputs = make(map[string]interface{})
+		for _, fp := range callee.FormalParameters {
+			if fp.Direction == "OUT" || fp.Direction == "INOUT" {
+				outputs[fp.Name] = calleeEnv[fp.Name]
+			}
+		}
+		s.trace[index].Outputs = outputs
+	}
+
+	return assignOutputs(body.DataMappings, outputs, env)
+}
+
+// assignOutputs weist die Ausgaben den Variablen zu, die in den Data-Mappings
+// als aktuelle Parameter der jeweiligen formalen Parameter stehen
+func assignOutputs(mappings []processfile.DataMapping, outputs map[string]interface{}, env expression.Env) error {
+	for _, dm := range mappings {
+		v, ok := outputs[dm.FormalParameter]
+		if !ok {
+			continue
+		}
+		target := strings.TrimSpace(dm.ActualParameter.Text())
+		if _, known := env[target]; !known {
+			return fmt.Errorf("data-mapping '%s': output cannot be assigned to '%s'", dm.FormalParameter, target)
+		}
+		env[target] = v
+	}
+	return nil
+}
+
+// nextActivity wählt die erste Transition, deren Bedingung erfüllt ist.
+// Transitionen ohne Bedingung werden nur gewählt, wenn keine Bedingung
+// erfüllt ist.
+func nextActivity(a *processfile.Activity, env expression.Env) (string, error) {
+	var otherwise []processfile.Transition
+	for _, t := range a.Transitions {
+		if strings.TrimSpace(t.Condition.Text()) == "" {
+			otherwise = append(otherwise, t)
+			continue
+		}
+		v, err := expression.EvalString(t.Condition.Text(), env)
+		if err != nil {
+			return "", fmt.Errorf("condition of transition '%s': %s", t.Id, err)
+		}
+		if expression.Truthy(v) {
+			return t.To, nil
+		}
+	}
+	if len(otherwise) > 0 {
+		return otherwise[0].To, nil
+	}
+	return "", errors.New("no transition can be taken")
+}
@@ -0,0 +1,54 @@
+package simulate_test
+
+import (
+	"testing"
+
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/simulate"
+)
+
+func TestRun(t *testing.T) {
+	fixture, err := simulate.LoadFixture("testdata/A1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := processfile.FromBytes(processfile.FileContent("../processfile/testdata/A1.process"))
+
+	// A2 wird nicht gestubt, sondern als minimaler Prozess simuliert
+	callee := &processfile.Process{
+		Id: "version400.epost_bsk20160726.epost_haupt_skb.EPost_Haupt_SKB",
+		Activities: []processfile.Activity{
+			{Id: "s", Body: processfile.Body{ActivityType: "EVENT", EventType: "START"},
+				Transitions: []processfile.Transition{{Id: "1", To: "e"}}},
+			{Id: "e", Body: processfile.Body{ActivityType: "EVENT", EventType: "END"}},
+		},
+	}
+
+	s := &simulate.Simulator{
+		Fixture: fixture,
+		Resolve: func(id string) (*processfile.Process, error) {
+			if id != callee.Id {
+				t.Errorf("Unexpected call of %s", id)
+			}
+			return callee, nil
+		},
+	}
+
+	result, err := s.Run(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(result.Trace); l != 7 {
+		t.Fatalf("Expected 7 steps, but was %d: %v", l, result.Trace)
+	}
+	if v := result.Trace[1].Inputs["eintrag"]; v != "Prozess: A1 - Meldung: Fehler X" {
+		t.Errorf("Unexpected input eintrag %v", v)
+	}
+	if d := result.Trace[4].Depth; d != 1 {
+		t.Errorf("Expected depth 1 in called process, but was %d", d)
+	}
+	if v := result.Variables["protokollId"]; v != "p-1" {
+		t.Errorf("Unexpected protokollId %v", v)
+	}
+}
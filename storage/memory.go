@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	errIsDir    = errors.New("is a directory")
+	errNotDir   = errors.New("not a directory")
+	errNotEmpty = errors.New("directory not empty")
+)
+
+// memFile ist eine Datei oder ein Verzeichnis im Memory-Storage
+type memFile struct {
+	content  []byte
+	mode     os.FileMode
+	mod_time time.Time
+	dir      bool
+}
+
+// memFileInfo ist die os.FileInfo eines memFile
+type memFileInfo struct {
+	name string
+	f    *memFile
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return int64(len(fi.f.content)) }
+func (fi *memFileInfo) Mode() os.FileMode  { return fi.f.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.f.mod_time }
+func (fi *memFileInfo) IsDir() bool        { return fi.f.dir }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
+
+// Memory ist ein Storage im Hauptspeicher, z.B. für Tests der Resolver.
+// Änderungen werden allen Watchern von Watch gemeldet.
+type Memory struct {
+	mu       sync.Mutex
+	files    map[string]*memFile
+	last     time.Time
+	watchers map[*memWatcher]struct{}
+}
+
+// NewMemory liefert einen leeren Memory-Storage, der nur das Wurzelverzeichnis
+// enthält
+func NewMemory() *Memory {
+	m := &Memory{
+		files:    make(map[string]*memFile),
+		watchers: make(map[*memWatcher]struct{}),
+	}
+	m.files[string(filepath.Separator)] = &memFile{mode: os.ModeDir | 0755, mod_time: m.now(), dir: true}
+	return m
+}
+
+// now liefert streng monoton steigende Zeiten, damit jede Änderung die
+// Änderungszeit der Datei ändert
+func (m *Memory) now() time.Time {
+	t := time.Now()
+	if !t.After(m.last) {
+		t = m.last.Add(time.Nanosecond)
+	}
+	m.last = t
+	return t
+}
+
+func pathError(op string, name string, err error) error {
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// dir liefert das Verzeichnis, in dem 'name' angelegt werden kann
+func (m *Memory) dir(op string, name string) error {
+	parent, present := m.files[filepath.Dir(name)]
+	if !present {
+		return pathError(op, name, os.ErrNotExist)
+	}
+	if !parent.dir {
+		return pathError(op, name, errNotDir)
+	}
+	return nil
+}
+
+// below liefert 'name' und alle Pfade darunter
+func (m *Memory) below(name string) []string {
+	var l []string
+	for path := range m.files {
+		if path == name || strings.HasPrefix(path, name+string(filepath.Separator)) {
+			l = append(l, path)
+		}
+	}
+	return l
+}
+
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	f, present := m.files[name]
+	if !present {
+		return nil, pathError("open", name, os.ErrNotExist)
+	}
+	if f.dir {
+		return nil, pathError("read", name, errIsDir)
+	}
+	return append([]byte(nil), f.content...), nil
+}
+
+func (m *Memory) WriteFile(name string, content []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if err := m.dir("open", name); err != nil {
+		return err
+	}
+	op := fsnotify.Write
+	f, present := m.files[name]
+	if !present {
+		f = &memFile{mode: perm}
+		m.files[name] = f
+		op = fsnotify.Create
+	} else if f.dir {
+		return pathError("open", name, errIsDir)
+	}
+	f.content = append([]byte(nil), content...)
+	f.mod_time = m.now()
+	m.notify(name, op)
+	return nil
+}
+
+func (m *Memory) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	f, present := m.files[name]
+	if !present {
+		return nil, pathError("stat", name, os.ErrNotExist)
+	}
+	return &memFileInfo{name: filepath.Base(name), f: f}, nil
+}
+
+func (m *Memory) ReadDir(name string) ([]os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	f, present := m.files[name]
+	if !present {
+		return nil, pathError("open", name, os.ErrNotExist)
+	}
+	if !f.dir {
+		return nil, pathError("readdirent", name, errNotDir)
+	}
+	var l []os.FileInfo
+	for path, f := range m.files {
+		if path != name && filepath.Dir(path) == name {
+			l = append(l, &memFileInfo{name: filepath.Base(path), f: f})
+		}
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Name() < l[j].Name() })
+	return l, nil
+}
+
+func (m *Memory) Mkdir(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, present := m.files[name]; present {
+		return pathError("mkdir", name, os.ErrExist)
+	}
+	if err := m.dir("mkdir", name); err != nil {
+		return err
+	}
+	m.files[name] = &memFile{mode: os.ModeDir | perm, mod_time: m.now(), dir: true}
+	m.notify(name, fsnotify.Create)
+	return nil
+}
+
+func (m *Memory) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	if _, present := m.files[oldpath]; !present {
+		return pathError("rename", oldpath, os.ErrNotExist)
+	}
+	if err := m.dir("rename", newpath); err != nil {
+		return err
+	}
+	if strings.HasPrefix(newpath, oldpath+string(filepath.Separator)) {
+		return pathError("rename", newpath, errors.New("invalid argument"))
+	}
+	if f, present := m.files[newpath]; present {
+		if f.dir && len(m.below(newpath)) > 1 {
+			return pathError("rename", newpath, errNotEmpty)
+		}
+		delete(m.files, newpath)
+	}
+	for _, path := range m.below(oldpath) {
+		m.files[newpath+path[len(oldpath):]] = m.files[path]
+		delete(m.files, path)
+	}
+	m.notify(oldpath, fsnotify.Rename)
+	m.notify(newpath, fsnotify.Create)
+	return nil
+}
+
+func (m *Memory) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, present := m.files[name]; !present {
+		return pathError("remove", name, os.ErrNotExist)
+	}
+	if len(m.below(name)) > 1 {
+		return pathError("remove", name, errNotEmpty)
+	}
+	delete(m.files, name)
+	m.notify(name, fsnotify.Remove)
+	return nil
+}
+
+func (m *Memory) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	paths := m.below(name)
+	if len(paths) == 0 {
+		return nil
+	}
+	for _, path := range paths {
+		delete(m.files, path)
+	}
+	m.notify(name, fsnotify.Remove)
+	return nil
+}
+
+func (m *Memory) Watch() (Watcher, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w := &memWatcher{
+		m:       m,
+		watches: make(map[string]struct{}),
+		events:  make(chan fsnotify.Event, 1024),
+		errors:  make(chan error, 1),
+	}
+	m.watchers[w] = struct{}{}
+	return w, nil
+}
+
+// notify meldet die Änderung von 'name' allen Watchern, die 'name' oder sein
+// Verzeichnis beobachten. Läuft der Puffer eines Watchers über, geht das Event
+// verloren und der Watcher meldet einen Fehler.
+func (m *Memory) notify(name string, op fsnotify.Op) {
+	for w := range m.watchers {
+		if !w.watching(name) {
+			continue
+		}
+		select {
+		case w.events <- fsnotify.Event{Name: name, Op: op}:
+		default:
+			select {
+			case w.errors <- errors.New("memory watcher: event queue overflow"):
+			default:
+			}
+		}
+	}
+}
+
+// memWatcher ist der Watcher eines Memory-Storage
+type memWatcher struct {
+	m       *Memory
+	mu      sync.Mutex
+	watches map[string]struct{}
+	events  chan fsnotify.Event
+	errors  chan error
+}
+
+func (w *memWatcher) watching(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, self := w.watches[name]
+	_, parent := w.watches[filepath.Dir(name)]
+	return self || parent
+}
+
+func (w *memWatcher) Add(name string) error {
+	if _, err := w.m.Stat(name); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watches[filepath.Clean(name)] = struct{}{}
+	return nil
+}
+
+func (w *memWatcher) Remove(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, present := w.watches[name]; !present {
+		return errNoWatch(name)
+	}
+	delete(w.watches, name)
+	return nil
+}
+
+func (w *memWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *memWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *memWatcher) Close() error {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	delete(w.m.watchers, w)
+	return nil
+}
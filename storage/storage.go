@@ -0,0 +1,164 @@
+// Package storage kapselt den Zugriff auf die Dateien der Bundles, damit
+// Resolver, Resource-Handler und Index nicht direkt vom Dateisystem abhängen.
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Storage ist ein Dateisystem. Namen sind Pfade im Format von 'filepath'; die
+// Fehler entsprechen denen des Package 'os', so dass os.IsNotExist und
+// os.IsExist auch für andere Implementierungen als OS funktionieren.
+type Storage interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, content []byte, perm os.FileMode) error
+	Stat(name string) (os.FileInfo, error)
+	// ReadDir liefert die Einträge des Verzeichnisses 'name' nach Namen sortiert
+	ReadDir(name string) ([]os.FileInfo, error)
+	Mkdir(name string, perm os.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	// Watch liefert einen neuen Watcher für Änderungen an diesem Storage
+	Watch() (Watcher, error)
+}
+
+// Walk entspricht filepath.Walk für die Dateien von 'fs'
+func Walk(fs Storage, root string, walkFn filepath.WalkFunc) error {
+	info, err := fs.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fs, root, info, walkFn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walk(fs Storage, path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+
+	fileinfos, err := fs.ReadDir(path)
+	err1 := walkFn(path, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, fi := range fileinfos {
+		err := walk(fs, filepath.Join(path, fi.Name()), fi, walkFn)
+		if err != nil && (!fi.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// MkdirAll entspricht os.MkdirAll für die Verzeichnisse von 'fs'
+func MkdirAll(fs Storage, path string, perm os.FileMode) error {
+	if fi, err := fs.Stat(path); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: path, Err: errNotDir}
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := MkdirAll(fs, parent, perm); err != nil {
+			return err
+		}
+	}
+	if err := fs.Mkdir(path, perm); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+// OS ist der Storage des Betriebssystems. Ist PollInterval gesetzt, liefert
+// Watch einen Watcher, der die Verzeichnisse in diesem Abstand liest, statt
+// der Events des Betriebssystems (die z.B. auf NFS- und SMB-Laufwerken
+// fehlen).
+type OS struct {
+	PollInterval time.Duration
+}
+
+func (o OS) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// WriteFile schreibt 'content' zunächst in eine versteckte temporäre Datei im
+// selben Verzeichnis, schreibt sie auf die Platte und benennt sie dann um.
+// Ein Abbruch hinterlässt so nie eine halb geschriebene Datei. Eine vorhandene
+// Datei behält ihre Rechte.
+func (o OS) WriteFile(name string, content []byte, perm os.FileMode) error {
+	if fi, err := os.Stat(name); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	dir := filepath.Dir(name)
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Die Umbenennung selbst auf die Platte schreiben
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func (o OS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (o OS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+func (o OS) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+func (o OS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (o OS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (o OS) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
+func (o OS) Watch() (Watcher, error) {
+	if o.PollInterval > 0 {
+		return NewPollWatcher(o, o.PollInterval), nil
+	}
+	return newFsnotifyWatcher()
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestMemory(t *testing.T) {
+	fs := NewMemory()
+	if err := MkdirAll(fs, "/b1/de/michael", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("/b1/de/michael/A1.process", []byte("<process/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("/b2/A1.process", nil, 0644); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, but was %v", err)
+	}
+	if err := fs.Mkdir("/b1", 0755); !os.IsExist(err) {
+		t.Errorf("Expected exist error, but was %v", err)
+	}
+
+	if err := fs.Rename("/b1/de/michael", "/b1/de/moved"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := fs.ReadFile("/b1/de/moved/A1.process")
+	if err != nil || string(content) != "<process/>" {
+		t.Errorf("Expected moved file, but was %s %v", content, err)
+	}
+	if _, err := fs.Stat("/b1/de/michael/A1.process"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, but was %v", err)
+	}
+
+	if err := fs.Remove("/b1/de"); err == nil {
+		t.Errorf("Expected error removing non-empty directory")
+	}
+	if err := fs.RemoveAll("/b1/de"); err != nil {
+		t.Fatal(err)
+	}
+	fileinfos, err := fs.ReadDir("/b1")
+	if err != nil || len(fileinfos) != 0 {
+		t.Errorf("Expected empty directory, but was %v %v", fileinfos, err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	fs := NewMemory()
+	MkdirAll(fs, "/b1/de/michael", 0755)
+	MkdirAll(fs, "/b1/.git", 0755)
+	fs.WriteFile("/b1/de/michael/A2.process", nil, 0644)
+	fs.WriteFile("/b1/de/michael/A1.process", nil, 0644)
+	fs.WriteFile("/b1/.git/HEAD", nil, 0644)
+
+	var paths []string
+	err := Walk(fs, "/b1", func(path string, info os.FileInfo, err error) error {
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/b1", "/b1/de", "/b1/de/michael", "/b1/de/michael/A1.process", "/b1/de/michael/A2.process"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, but was %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("Expected %v, but was %v", expected, paths)
+		}
+	}
+}
+
+func TestMemoryWatch(t *testing.T) {
+	fs := NewMemory()
+	MkdirAll(fs, "/b1/de", 0755)
+	watcher, _ := fs.Watch()
+	defer watcher.Close()
+	if err := watcher.Add("/b1"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.WriteFile("/b1/A1.process", nil, 0644)
+	fs.WriteFile("/b1/de/A2.process", nil, 0644)
+	fs.Rename("/b1/A1.process", "/b1/A3.process")
+
+	expected := []fsnotify.Event{
+		{Name: "/b1/A1.process", Op: fsnotify.Create},
+		{Name: "/b1/A1.process", Op: fsnotify.Rename},
+		{Name: "/b1/A3.process", Op: fsnotify.Create},
+	}
+	for _, e := range expected {
+		if event := <-watcher.Events(); event != e {
+			t.Errorf("Expected %v, but was %v", e, event)
+		}
+	}
+	select {
+	case event := <-watcher.Events():
+		t.Errorf("Unexpected event %v", event)
+	default:
+	}
+}
+
+func TestPollWatcher(t *testing.T) {
+	fs := NewMemory()
+	MkdirAll(fs, "/b1", 0755)
+	fs.WriteFile("/b1/A1.process", []byte("<process/>"), 0644)
+
+	watcher := NewPollWatcher(fs, time.Hour).(*pollWatcher)
+	defer watcher.Close()
+	if err := watcher.Add("/b1"); err != nil {
+		t.Fatal(err)
+	}
+	if events := watcher.poll(); len(events) != 0 {
+		t.Errorf("Expected no events, but was %v", events)
+	}
+
+	fs.WriteFile("/b1/A1.process", []byte("<process></process>"), 0644)
+	fs.Mkdir("/b1/de", 0755)
+	events := watcher.poll()
+	if len(events) != 2 || events[0].Op != fsnotify.Create || events[1].Op != fsnotify.Write {
+		t.Errorf("Expected create and write, but was %v", events)
+	}
+
+	fs.Remove("/b1/A1.process")
+	events = watcher.poll()
+	if len(events) != 1 || events[0] != (fsnotify.Event{Name: "/b1/A1.process", Op: fsnotify.Remove}) {
+		t.Errorf("Expected remove, but was %v", events)
+	}
+}
+
+func TestOSWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pride-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := OS{}
+	name := filepath.Join(dir, "A1.process")
+	if err := fs.WriteFile(name, []byte("<process/>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile(name, []byte("<process></process>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := fs.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be kept, but was %v", fi.Mode())
+	}
+	if content, _ := fs.ReadFile(name); string(content) != "<process></process>" {
+		t.Errorf("Unexpected content %s", content)
+	}
+	if fileinfos, _ := fs.ReadDir(dir); len(fileinfos) != 1 {
+		t.Errorf("Expected no temporary files, but was %d files", len(fileinfos))
+	}
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher beobachtet Dateien und Verzeichnisse und liefert Änderungen als
+// fsnotify-Events. Wie bei fsnotify wird für ein Verzeichnis nur das
+// Verzeichnis selbst und seine direkten Einträge beobachtet.
+type Watcher interface {
+	Add(name string) error
+	Remove(name string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+func errNoWatch(name string) error {
+	return errors.New(fmt.Sprintf("can't remove non-existent watch for: %s", name))
+}
+
+// fsnotifyWatcher ist ein Watcher auf Basis der Events des Betriebssystems
+type fsnotifyWatcher struct {
+	*fsnotify.Watcher
+}
+
+// newFsnotifyWatcher liefert einen Watcher auf Basis von fsnotify. Auf
+// Netzlaufwerken (NFS, SMB) liefert er keine Events, siehe NewPollWatcher.
+func newFsnotifyWatcher() (Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &fsnotifyWatcher{w}, nil
+}
+
+func (w *fsnotifyWatcher) Events() <-chan fsnotify.Event {
+	return w.Watcher.Events
+}
+
+func (w *fsnotifyWatcher) Errors() <-chan error {
+	return w.Watcher.Errors
+}
+
+// pollWatcher vergleicht in festen Abständen Änderungszeit und Größe der
+// beobachteten Einträge mit dem Stand der letzten Abfrage
+type pollWatcher struct {
+	fs      Storage
+	mu      sync.Mutex
+	watches map[string]map[string]pollState
+	events  chan fsnotify.Event
+	errors  chan error
+	done    chan struct{}
+}
+
+// pollState ist der Zustand eines Eintrags bei der letzten Abfrage
+type pollState struct {
+	mod_time int64
+	size     int64
+	dir      bool
+}
+
+// NewPollWatcher liefert einen Watcher, der die beobachteten Verzeichnisse
+// von 'fs' alle 'interval' liest. Er erzeugt Create-, Write- und
+// Remove-Events; eine Umbenennung erscheint als Remove des alten und Create
+// des neuen Namens.
+func NewPollWatcher(fs Storage, interval time.Duration) Watcher {
+	w := &pollWatcher{
+		fs:      fs,
+		watches: make(map[string]map[string]pollState),
+		events:  make(chan fsnotify.Event),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+	}
+	go w.run(interval)
+	return w
+}
+
+// snapshot liest den Zustand von 'name' und, falls es ein Verzeichnis ist,
+// seiner direkten Einträge
+func snapshot(fs Storage, name string) (map[string]pollState, error) {
+	fi, err := fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	states := map[string]pollState{name: pollStateOf(fi)}
+	if !fi.IsDir() {
+		return states, nil
+	}
+	fileinfos, err := fs.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fileinfos {
+		states[filepath.Join(name, fi.Name())] = pollStateOf(fi)
+	}
+	return states, nil
+}
+
+func pollStateOf(fi os.FileInfo) pollState {
+	return pollState{mod_time: fi.ModTime().UnixNano(), size: fi.Size(), dir: fi.IsDir()}
+}
+
+func (w *pollWatcher) Add(name string) error {
+	name = filepath.Clean(name)
+	states, err := snapshot(w.fs, name)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, present := w.watches[name]; !present {
+		w.watches[name] = states
+	}
+	return nil
+}
+
+func (w *pollWatcher) Remove(name string) error {
+	name = filepath.Clean(name)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, present := w.watches[name]; !present {
+		return errNoWatch(name)
+	}
+	delete(w.watches, name)
+	return nil
+}
+
+func (w *pollWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *pollWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *pollWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+func (w *pollWatcher) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			for _, event := range w.poll() {
+				select {
+				case w.events <- event:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// poll vergleicht alle beobachteten Einträge mit ihrem letzten Stand und
+// liefert die Events in der Reihenfolge Remove, Create, Write
+func (w *pollWatcher) poll() []fsnotify.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var removed, created, written []fsnotify.Event
+	seen := make(map[string]struct{})
+	add := func(events *[]fsnotify.Event, name string, op fsnotify.Op) {
+		key := fmt.Sprintf("%s:%d", name, op)
+		if _, present := seen[key]; present {
+			return
+		}
+		seen[key] = struct{}{}
+		*events = append(*events, fsnotify.Event{Name: name, Op: op})
+	}
+
+	for name, old := range w.watches {
+		current, err := snapshot(w.fs, name)
+		if err != nil {
+			// Das beobachtete Verzeichnis ist verschwunden. Wie bei fsnotify
+			// endet die Beobachtung.
+			add(&removed, name, fsnotify.Remove)
+			delete(w.watches, name)
+			continue
+		}
+		for path, old_state := range old {
+			if path == name {
+				continue
+			}
+			if _, present := current[path]; !present {
+				add(&removed, path, fsnotify.Remove)
+			} else if !old_state.dir && old_state != current[path] {
+				add(&written, path, fsnotify.Write)
+			}
+		}
+		for path := range current {
+			if _, present := old[path]; !present {
+				add(&created, path, fsnotify.Create)
+			}
+		}
+		if old_state, state := old[name], current[name]; !state.dir && old_state != state {
+			add(&written, name, fsnotify.Write)
+		}
+		w.watches[name] = current
+	}
+
+	return append(append(removed, created...), written...)
+}
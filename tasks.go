@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/bundle"
+	"github.com/frericksm/pride/storage"
+)
+
+// Tasks gibt für jeden Task der Bundles im Verzeichnis 'dir' aus, wie oft und
+// von welchen Prozessen er mit welchen formalen Parametern aufgerufen wird.
+// Ist ein Task angegeben, werden nur seine Aufrufe ausgegeben.
+func tasks(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return cli.NewExitError("tasks erwartet höchstens eine Task-Id", 1)
+	}
+
+	index := bundle.CreateIndex(storage.OS{}, bundleRootDir(c))
+	usages := index.TaskUsages(c.Args().Get(0))
+
+	if c.Bool("json") {
+		report, err := json.MarshalIndent(usages, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(report))
+		return nil
+	}
+
+	// Die Aufrufe sind nach Task sortiert
+	for i := 0; i < len(usages); {
+		task := usages[i].Task
+		j := i
+		for j < len(usages) && usages[j].Task == task {
+			j++
+		}
+		fmt.Printf("%s (%d)\n", task, j-i)
+		for _, u := range usages[i:j] {
+			fmt.Printf("  %s %s '%s' [%s]\n", u.Bundle, u.Process, u.Activity, strings.Join(u.Parameters, ", "))
+		}
+		i = j
+	}
+	return nil
+}
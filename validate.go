@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+
+	"github.com/frericksm/pride/bundle"
+	"github.com/frericksm/pride/expression"
+	"github.com/frericksm/pride/processfile"
+	"github.com/frericksm/pride/storage"
+)
+
+// loadTaskCatalog liest den Task-Katalog 'path'
+func loadTaskCatalog(path string) (*processfile.TaskCatalog, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := processfile.ParseTaskCatalog(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return catalog, nil
+}
+
+// bundleTaskCatalog sucht den Task-Katalog (META-INF/tasks.json oder
+// tasks.yaml) des Bundles, in dem 'file' liegt, in den übergeordneten
+// Verzeichnissen. Ohne Katalog ist das Ergebnis nil. Die gelesenen Kataloge
+// werden je Verzeichnis in 'catalogs' gemerkt.
+func bundleTaskCatalog(file string, catalogs map[string]*processfile.TaskCatalog) (*processfile.TaskCatalog, error) {
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	var visited []string
+	var catalog *processfile.TaskCatalog
+	for {
+		if c, present := catalogs[dir]; present {
+			catalog = c
+			break
+		}
+		visited = append(visited, dir)
+		found := false
+		for _, name := range processfile.TaskCatalogFiles {
+			path := filepath.Join(dir, "META-INF", name)
+			if _, err := os.Stat(path); err == nil {
+				if catalog, err = loadTaskCatalog(path); err != nil {
+					return nil, err
+				}
+				found = true
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if found || parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for _, d := range visited {
+		catalogs[d] = catalog
+	}
+	return catalog, nil
+}
+
+// Validate prüft Prozessdateien vor dem Deployment und gibt alle gefundenen
+// Probleme aus. Gibt es Probleme, ist der Exit-Code 1.
+func validate(c *cli.Context) error {
+	paths := []string(c.Args())
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := processFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	// Mit --catalog gilt der angegebene Katalog für alle Dateien
+	var catalog *processfile.TaskCatalog
+	if path := c.String("catalog"); path != "" {
+		if catalog, err = loadTaskCatalog(path); err != nil {
+			return err
+		}
+	}
+	catalogs := make(map[string]*processfile.TaskCatalog)
+
+	// Aufgerufene Prozesse werden über den Index der Bundles im Verzeichnis
+	// 'dir' gesucht. Er wird erst beim ersten SUB_FLOW gebaut.
+	var index *bundle.Index
+	resolve := func(id string) (*processfile.Process, bool) {
+		if index == nil {
+			index = bundle.CreateIndex(storage.OS{}, bundleRootDir(c))
+		}
+		return index.Process(id)
+	}
+
+	count := 0
+	for _, file := range files {
+		// Eine nicht lesbare Datei ist ein Problem, die übrigen Dateien werden
+		// trotzdem geprüft
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			count++
+			fmt.Printf("%s: %s\n", file, err)
+			continue
+		}
+		if len(content) == 0 {
+			continue
+		}
+		var problems []processfile.Problem
+		if p, err := processfile.Parse(content); err != nil {
+			problems = append(problems, processfile.ParseProblem(err))
+		} else {
+			problems = append(processfile.Validate(p), expression.LintProcess(p)...)
+			tasks := catalog
+			if tasks == nil {
+				if tasks, err = bundleTaskCatalog(file, catalogs); err != nil {
+					return err
+				}
+			}
+			problems = append(problems, processfile.ValidateTasks(p, tasks)...)
+			problems = append(problems, processfile.ValidateSubFlows(p, resolve)...)
+		}
+		for _, problem := range problems {
+			count++
+			fmt.Printf("%s: %s\n", file, problem)
+		}
+	}
+
+	if count > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d Problem(e) gefunden", count), 1)
+	}
+	return nil
+}